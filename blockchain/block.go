@@ -26,6 +26,7 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/gob"
+	"time"
 
 	"github.com/tchiunam/axolgo-lib/util"
 )
@@ -35,6 +36,8 @@ type Block struct {
 	Transactions []*Transaction
 	PrevHash     []byte
 	Nonce        int
+	Difficulty   int
+	Timestamp    int64
 }
 
 // HashTransactions returns a hash of the transactions in the block
@@ -51,9 +54,16 @@ func (b *Block) HashTransactions() []byte {
 	return txHash[:]
 }
 
-// CreateBlock creates a new block using the data and the previous block's hash
-func CreateBlock(txs []*Transaction, prevHash []byte) *Block {
-	block := &Block{[]byte{}, txs, prevHash, 0}
+// CreateBlock creates a new block using the data and the previous block's hash.
+// The block is mined with the given difficulty.
+func CreateBlock(txs []*Transaction, prevHash []byte, difficulty int) *Block {
+	return CreateBlockAt(txs, prevHash, difficulty, time.Now().Unix())
+}
+
+// CreateBlockAt creates a new block like CreateBlock with the given
+// Unix timestamp instead of the current time.
+func CreateBlockAt(txs []*Transaction, prevHash []byte, difficulty int, timestamp int64) *Block {
+	block := &Block{[]byte{}, txs, prevHash, 0, difficulty, timestamp}
 	pow := NewProof(block)
 	nonce, hash := pow.Run()
 
@@ -64,8 +74,8 @@ func CreateBlock(txs []*Transaction, prevHash []byte) *Block {
 }
 
 // Genesis creates the first block in the chain
-func Genesis(tx *Transaction) *Block {
-	return CreateBlock([]*Transaction{tx}, []byte{})
+func Genesis(tx *Transaction, difficulty int) *Block {
+	return CreateBlock([]*Transaction{tx}, []byte{}, difficulty)
 }
 
 // Serialize the block into bytes
@@ -23,7 +23,12 @@ THE SOFTWARE.
 package blockchain
 
 import (
+	"bytes"
+	"encoding/gob"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
 	"os"
 
 	"github.com/dgraph-io/badger"
@@ -33,10 +38,73 @@ import (
 // Data in the genesis block
 const genesisData = "First block in the chain - Genesis"
 
+// Ratio of stale data in a value log file that makes it worth rewriting
+const gcDiscardRatio = 0.5
+
+// Key of the last hash in the database
+var lastHashKey = []byte("lh")
+
+// Key of the chain parameters in the database
+var chainParamsKey = []byte("params")
+
+// Key of the genesis block hash in the database
+var genesisHashKey = []byte("gh")
+
 // BlockChain structure
 type BlockChain struct {
 	LastHash []byte
 	Database *badger.DB
+	Params   *ChainParams
+}
+
+// BlockChainOptionsFunc is a type alias for BlockChainOptions functional option
+type BlockChainOptionsFunc func(*BlockChainOptions) error
+
+// BlockChainOptions are discrete set of options that are valid for
+// creating or continuing a blockchain.
+type BlockChainOptions struct {
+	Params      *ChainParams
+	GenesisSpec *GenesisSpec
+	GenesisHash []byte
+}
+
+// WithChainParams is a helper function to construct functional options
+// that sets the network parameters of the blockchain.
+func WithChainParams(params *ChainParams) BlockChainOptionsFunc {
+	return func(o *BlockChainOptions) error {
+		o.Params = params
+		return nil
+	}
+}
+
+// WithGenesisSpec is a helper function to construct functional options
+// that creates the genesis block from a spec. When continuing a chain,
+// the genesis block in the database must match the spec.
+func WithGenesisSpec(spec *GenesisSpec) BlockChainOptionsFunc {
+	return func(o *BlockChainOptions) error {
+		o.GenesisSpec = spec
+		return nil
+	}
+}
+
+// WithGenesisHash is a helper function to construct functional options
+// that sets the expected hash of the genesis block of a continued chain.
+func WithGenesisHash(hash []byte) BlockChainOptionsFunc {
+	return func(o *BlockChainOptions) error {
+		o.GenesisHash = hash
+		return nil
+	}
+}
+
+// Evaluate the functional options and set the options in the BlockChainOptions struct
+func (options *BlockChainOptions) Merge(optFns ...BlockChainOptionsFunc) error {
+	for _, optFn := range optFns {
+		if err := optFn(options); err != nil {
+			return fmt.Errorf("Fail to read blockchain options: %v", err)
+		}
+	}
+
+	return nil
 }
 
 // An iterator for iterating the blockchain in database
@@ -54,10 +122,18 @@ func DBExists(dbPath string) bool {
 	return true
 }
 
-// InitBlockChain creates a new blockchain with a genesis block
-func InitBlockChain(dbPath string, address string) *BlockChain {
+// InitBlockChain creates a new blockchain with a genesis block.
+// The chain is created on the main network unless other parameters
+// are given with WithChainParams. The genesis block rewards the address
+// unless a genesis spec is given with WithGenesisSpec, in which case the
+// allocations of the spec are used and the address is ignored.
+func InitBlockChain(dbPath string, address string, optFns ...BlockChainOptionsFunc) *BlockChain {
 	var lastHash []byte
 
+	options := BlockChainOptions{Params: &MainNetParams}
+	util.PanicOnError(options.Merge(optFns...))
+	params := options.Params
+
 	if DBExists(dbPath) {
 		panic("Blockchain already exists.")
 	}
@@ -66,16 +142,30 @@ func InitBlockChain(dbPath string, address string) *BlockChain {
 	opts.Dir = dbPath
 	opts.ValueDir = dbPath
 
+	var genesis *Block
+	if options.GenesisSpec != nil {
+		var err error
+		genesis, err = options.GenesisSpec.Block(params)
+		util.PanicOnError(err)
+	} else {
+		abTx := CoinbaseTx(address, params.GenesisData, params.CoinbaseReward)
+		genesis = Genesis(abTx, params.Difficulty)
+	}
+
 	db, err := badger.Open(opts)
 	util.PanicOnError(err)
 
 	err = db.Update(func(txn *badger.Txn) error {
-		abTx := CoinbaseTx(address, genesisData)
-		genesis := Genesis(abTx)
 		err = txn.Set(genesis.Hash, genesis.Serialize())
 		util.PanicOnError(err)
 
-		err = txn.Set([]byte("lh"), genesis.Hash)
+		err = txn.Set(genesisHashKey, genesis.Hash)
+		util.PanicOnError(err)
+
+		err = txn.Set(chainParamsKey, params.Serialize())
+		util.PanicOnError(err)
+
+		err = txn.Set(lastHashKey, genesis.Hash)
 		lastHash = genesis.Hash
 
 		return err
@@ -83,17 +173,25 @@ func InitBlockChain(dbPath string, address string) *BlockChain {
 
 	util.PanicOnError(err)
 
-	chain := BlockChain{lastHash, db}
+	chain := BlockChain{lastHash, db, params}
 	return &chain
 }
 
-// Continue a blockchain by pulling the last hash
-func ContinueBlockChain(dbPath string) *BlockChain {
+// Continue a blockchain by pulling the last hash. The network parameters
+// are read from the database. If parameters are given with WithChainParams,
+// they must match the network the chain was created for. Likewise, the
+// genesis block must match the one given with WithGenesisSpec or
+// WithGenesisHash.
+func ContinueBlockChain(dbPath string, optFns ...BlockChainOptionsFunc) *BlockChain {
+	var options BlockChainOptions
+	util.PanicOnError(options.Merge(optFns...))
+
 	if DBExists(dbPath) == false {
 		panic("No existing blockchain found. Create one first.")
 	}
 
 	var lastHash []byte
+	var params *ChainParams
 
 	opts := badger.DefaultOptions(dbPath)
 	opts.Dir = dbPath
@@ -102,31 +200,85 @@ func ContinueBlockChain(dbPath string) *BlockChain {
 	db, err := badger.Open(opts)
 	util.PanicOnError(err)
 
-	err = db.Update(func(txn *badger.Txn) error {
-		item, err := txn.Get([]byte("lh"))
+	err = db.View(func(txn *badger.Txn) error {
+		params, err = _readChainParams(txn)
 		util.PanicOnError(err)
 
-		err = item.Value(func(val []byte) error {
-			lastHash = val
+		item, err := txn.Get(lastHashKey)
+		if err == badger.ErrKeyNotFound {
 			return nil
-		})
+		}
+		util.PanicOnError(err)
+
+		lastHash, err = item.ValueCopy(nil)
 
 		return err
 	})
 
 	util.PanicOnError(err)
 
-	chain := BlockChain{lastHash, db}
+	if options.Params != nil && *options.Params != *params {
+		db.Close()
+		panic(fmt.Sprintf("Blockchain was created for the %s network, not %s.", params.Name, options.Params.Name))
+	}
+
+	chain := BlockChain{lastHash, db, params}
+
+	// The last hash can be left dangling by a crash in the middle of a write
+	_, err = chain.RecoverLastHash()
+	util.PanicOnError(err)
+
+	expectedGenesisHash := options.GenesisHash
+	if options.GenesisSpec != nil {
+		genesis, err := options.GenesisSpec.Block(params)
+		util.PanicOnError(err)
+		expectedGenesisHash = genesis.Hash
+	}
+	if expectedGenesisHash != nil && !bytes.Equal(expectedGenesisHash, chain.GenesisHash()) {
+		db.Close()
+		panic(fmt.Sprintf("Genesis block %x does not match the expected %x.", chain.GenesisHash(), expectedGenesisHash))
+	}
+
 	return &chain
 }
 
+// GenesisHash returns the hash of the first block in the chain
+func (chain *BlockChain) GenesisHash() []byte {
+	var genesisHash []byte
+
+	err := chain.Database.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(genesisHashKey)
+		if err != nil {
+			return err
+		}
+		genesisHash, err = item.ValueCopy(nil)
+
+		return err
+	})
+
+	// Chains created before the genesis hash was stored are walked instead
+	if err == badger.ErrKeyNotFound {
+		iter := chain.Iterator()
+		for {
+			block := iter.Next()
+			if len(block.PrevHash) == 0 {
+				return block.Hash
+			}
+		}
+	}
+	util.PanicOnError(err)
+
+	return genesisHash
+}
+
 // AddBlock is a helper function that adds a new block to the chain using
-// the previous block's hash
-func (chain *BlockChain) AddBlock(transactions []*Transaction) {
+// the previous block's hash. The UTXO index is updated with the block if
+// it has been built. The new block is returned.
+func (chain *BlockChain) AddBlock(transactions []*Transaction) *Block {
 	var lastHash []byte
 
 	err := chain.Database.View(func(txn *badger.Txn) error {
-		item, err := txn.Get([]byte("lh"))
+		item, err := txn.Get(lastHashKey)
 		util.PanicOnError(err)
 
 		err = item.Value(func(val []byte) error {
@@ -139,17 +291,163 @@ func (chain *BlockChain) AddBlock(transactions []*Transaction) {
 
 	util.PanicOnError(err)
 
-	newBlock := CreateBlock(transactions, lastHash)
+	newBlock := CreateBlock(transactions, lastHash, chain.Params.Difficulty)
 
 	err = chain.Database.Update(func(txn *badger.Txn) error {
 		err := txn.Set(newBlock.Hash, newBlock.Serialize())
 		util.PanicOnError(err)
-		err = txn.Set([]byte("lh"), newBlock.Hash)
+		err = txn.Set(lastHashKey, newBlock.Hash)
+		if err != nil {
+			return err
+		}
+
+		indexed, err := _utxoIndexed(txn)
+		if err != nil || !indexed {
+			return err
+		}
+
+		return _updateUTXOIndex(txn, newBlock)
+	})
 
-		chain.LastHash = newBlock.Hash
+	util.PanicOnError(err)
+
+	chain.LastHash = newBlock.Hash
+
+	return newBlock
+}
+
+// Close the database of the blockchain. The blockchain cannot be
+// used after it is closed.
+func (chain *BlockChain) Close() error {
+	return chain.Database.Close()
+}
 
+// Compact flattens the LSM tree and runs value log garbage collection
+// until there are no more log files to rewrite. Long-running nodes
+// should call it periodically to reclaim disk space.
+func (chain *BlockChain) Compact() error {
+	if err := chain.Database.Flatten(1); err != nil {
 		return err
+	}
+
+	for {
+		err := chain.Database.RunValueLogGC(gcDiscardRatio)
+		if err == badger.ErrNoRewrite {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// Verify walks the chain from the last hash down to the genesis block
+// and checks that every block is present, intact and has a valid proof
+// of work.
+func (chain *BlockChain) Verify() error {
+	return chain.Database.View(func(txn *badger.Txn) error {
+		_, err := _validateChain(txn, chain.LastHash, math.MaxInt, chain.Params.Difficulty)
+		return err
+	})
+}
+
+// RecoverLastHash checks that the last hash points to a block that can
+// be decoded, which a crash in the middle of a write can break. Otherwise
+// the last hash is rolled back to the tip of the longest complete chain
+// in the database. Only the tip is checked so that opening a chain does
+// not take longer as it grows; use Verify to check every block. A
+// rollback invalidates the UTXO index, which UTXOSet.Reindex rebuilds.
+// Returns true if the last hash was changed.
+func (chain *BlockChain) RecoverLastHash() (bool, error) {
+	if chain.LastHash != nil {
+		intact := false
+		err := chain.Database.View(func(txn *badger.Txn) error {
+			intact = _blockIntact(txn, chain.LastHash)
+			return nil
+		})
+		if err != nil || intact {
+			return false, err
+		}
+	}
+
+	var recovered []byte
+	err := chain.Database.Update(func(txn *badger.Txn) error {
+
+		// Collect every intact block so that the chains can be measured
+		blocks := make(map[string]*Block)
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		for it.Rewind(); it.Valid(); it.Next() {
+			var block Block
+			err := it.Item().Value(func(val []byte) error {
+				return gob.NewDecoder(bytes.NewReader(val)).Decode(&block)
+			})
+			// Keys that are not blocks are skipped
+			if err != nil || !bytes.Equal(block.Hash, it.Item().Key()) || !NewProof(&block).Validate() {
+				continue
+			}
+			blocks[string(block.Hash)] = &block
+		}
+		it.Close()
+
+		heights := make(map[string]int)
+		var height func(hash string) int
+		height = func(hash string) int {
+			if h, ok := heights[hash]; ok {
+				return h
+			}
+			// Guard against cycles while the height is being computed
+			heights[hash] = -1
+			block, ok := blocks[hash]
+			h := -1
+			if ok && len(block.PrevHash) == 0 {
+				h = 0
+			} else if ok {
+				if prev := height(string(block.PrevHash)); prev >= 0 {
+					h = prev + 1
+				}
+			}
+			heights[hash] = h
+			return h
+		}
+
+		best := -1
+		for hash := range blocks {
+			if h := height(hash); h > best || (h == best && h >= 0 && hash < string(recovered)) {
+				best = h
+				recovered = []byte(hash)
+			}
+		}
+		if best < 0 {
+			return fmt.Errorf("No complete chain found in the database")
+		}
+
+		// The index may contain outputs of the blocks that were dropped
+		if err := txn.Delete(utxoIndexKey); err != nil {
+			return err
+		}
+
+		return txn.Set(lastHashKey, recovered)
+	})
+	if err != nil || recovered == nil {
+		return false, err
+	}
+
+	chain.LastHash = recovered
+	return true, nil
+}
+
+// Tell whether the block with the hash is present and can be decoded
+func _blockIntact(txn *badger.Txn, hash []byte) bool {
+	item, err := txn.Get(hash)
+	if err != nil {
+		return false
+	}
+
+	var block Block
+	err = item.Value(func(val []byte) error {
+		return gob.NewDecoder(bytes.NewReader(val)).Decode(&block)
 	})
+
+	return err == nil && bytes.Equal(block.Hash, hash)
 }
 
 // Iterator returns a BlockChainIterator that can be used to iterate over
@@ -182,6 +480,64 @@ func (iter *BlockChainIterator) Next() *Block {
 	return block
 }
 
+// ErrBlockNotFound is returned when a block is not in the chain
+var ErrBlockNotFound = errors.New("Block not found")
+
+// ErrTransactionNotFound is returned when a transaction is not in the chain
+var ErrTransactionNotFound = errors.New("Transaction not found")
+
+// GetBlock returns the block with the given hash
+func (chain *BlockChain) GetBlock(hash []byte) (*Block, error) {
+	var block Block
+
+	err := chain.Database.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(hash)
+		if err == badger.ErrKeyNotFound {
+			return ErrBlockNotFound
+		} else if err != nil {
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			// Keys that are not blocks cannot be decoded as blocks
+			if gob.NewDecoder(bytes.NewReader(val)).Decode(&block) != nil {
+				return ErrBlockNotFound
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(block.Hash, hash) {
+		return nil, ErrBlockNotFound
+	}
+
+	return &block, nil
+}
+
+// FindTransaction returns the transaction with the given ID and the
+// block it is in
+func (chain *BlockChain) FindTransaction(id []byte) (*Transaction, *Block, error) {
+	iter := chain.Iterator()
+
+	for {
+		block := iter.Next()
+
+		for _, tx := range block.Transactions {
+			if bytes.Equal(tx.ID, id) {
+				return tx, block, nil
+			}
+		}
+
+		if len(block.PrevHash) == 0 {
+			break
+		}
+	}
+
+	return nil, nil, ErrTransactionNotFound
+}
+
 // Find all transactions for a given address that is unspent
 func (chain *BlockChain) FindUnspentTransactions(address string) []Transaction {
 	var unspentTxs []Transaction
@@ -266,3 +622,91 @@ Work:
 
 	return accumulated, unspentOutputs
 }
+
+// Find all unspent transaction outputs for a given address together
+// with the transaction ID and index needed to spend them
+func (chain *BlockChain) FindUnspentOutputs(address string) []UnspentOutput {
+	var unspentOutputs []UnspentOutput
+	spentTxOutputs := make(map[string]map[int]bool)
+	iter := chain.Iterator()
+
+	for {
+		block := iter.Next()
+
+		for _, tx := range block.Transactions {
+			txID := hex.EncodeToString(tx.ID)
+
+			for outIdx, out := range tx.Outputs {
+				if spentTxOutputs[txID][outIdx] {
+					continue
+				}
+				if out.CanBeUnlocked(address) {
+					unspentOutputs = append(unspentOutputs, UnspentOutput{tx.ID, outIdx, out})
+				}
+			}
+			if tx.IsCoinbase() == false {
+				for _, in := range tx.Inputs {
+					if in.CanUnlock(address) {
+						inTxID := hex.EncodeToString(in.ID)
+						if spentTxOutputs[inTxID] == nil {
+							spentTxOutputs[inTxID] = make(map[int]bool)
+						}
+						spentTxOutputs[inTxID][in.Out] = true
+					}
+				}
+			}
+		}
+
+		if len(block.PrevHash) == 0 {
+			break
+		}
+	}
+
+	return unspentOutputs
+}
+
+// Balance returns the total value of the unspent outputs of an address
+func (chain *BlockChain) Balance(address string) int {
+	return sumUnspentOutputs(chain.FindUnspentOutputs(address))
+}
+
+// Select transaction outputs for a given address with the coin selector
+// so that they can be used as inputs for a new transaction
+func (chain *BlockChain) SelectSpendableOutputs(
+	address string,
+	amount int,
+	selector CoinSelector) (int, map[string][]int, error) {
+	selected, err := selector.Select(chain.FindUnspentOutputs(address), amount)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	unspentOutputs := make(map[string][]int)
+	for _, utxo := range selected {
+		txID := hex.EncodeToString(utxo.TxID)
+		unspentOutputs[txID] = append(unspentOutputs[txID], utxo.Index)
+	}
+
+	return sumUnspentOutputs(selected), unspentOutputs, nil
+}
+
+// Read the chain parameters from the database. Chains created before
+// the parameters were stored belong to the main network.
+func _readChainParams(txn *badger.Txn) (*ChainParams, error) {
+	item, err := txn.Get(chainParamsKey)
+	if err == badger.ErrKeyNotFound {
+		return &MainNetParams, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var params ChainParams
+	err = item.Value(func(val []byte) error {
+		return gob.NewDecoder(bytes.NewReader(val)).Decode(&params)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &params, nil
+}
@@ -25,8 +25,10 @@ package blockchain
 import (
 	"os"
 	"path/filepath"
+	"sort"
 	"testing"
 
+	"github.com/dgraph-io/badger"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -72,9 +74,17 @@ func TestBlockChain(t *testing.T) {
 	os.MkdirAll(dbPath, 0755)
 	defer _cleanTestBadgerDatabase(dbPath)
 
+	// Transactions depend on each other so they are added in order
+	var names []string
+	for name := range cases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
 	chain := InitBlockChain(dbPath, "John")
 	defer chain.Database.Close()
-	for name, c := range cases {
+	for _, name := range names {
+		c := cases[name]
 		t.Run(name, func(t *testing.T) {
 			tx, _ := NewTransaction(c.from, c.to, c.amount, chain)
 			assert.NotPanics(t, func() { chain.AddBlock([]*Transaction{tx}) }, "AddBlock should not panic")
@@ -113,3 +123,80 @@ func TestBlockChain(t *testing.T) {
 		}
 	})
 }
+
+// TestContinueBlockChainRecovery points the last hash to a missing
+// block and checks that it is rolled back when the chain is continued.
+func TestContinueBlockChainRecovery(t *testing.T) {
+	dbPath := filepath.Join("testdata", "db", "recovery")
+	os.MkdirAll(dbPath, 0755)
+	defer _cleanTestBadgerDatabase(dbPath)
+
+	chain := InitBlockChain(dbPath, "John")
+	tx, _ := NewTransaction("John", "Jane", 20, chain)
+	chain.AddBlock([]*Transaction{tx})
+	lastHash := chain.LastHash
+
+	// Simulate a crash that left the last hash without its block
+	err := chain.Database.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte("lh"), []byte("missing block"))
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, chain.Close(), "Close() should not return error")
+
+	chain = ContinueBlockChain(dbPath)
+	defer chain.Close()
+
+	assert.Equal(t, lastHash, chain.LastHash, "Last hash should be rolled back to the last complete block")
+	assert.Equal(t, 20, chain.Balance("Jane"), "Balance of Jane should be 20")
+
+	recovered, err := chain.RecoverLastHash()
+	assert.NoError(t, err)
+	assert.False(t, recovered, "Intact chain should not be recovered")
+	assert.NoError(t, chain.Verify())
+
+	assert.NoError(t, chain.Compact(), "Compact() should not return error")
+}
+
+// TestRecoverLastHashTipOnly checks that only the tip is checked when
+// the chain is opened, and that Verify finds a broken block below it.
+func TestRecoverLastHashTipOnly(t *testing.T) {
+	dbPath := filepath.Join("testdata", "db", "recovertip")
+	os.MkdirAll(dbPath, 0755)
+	defer _cleanTestBadgerDatabase(dbPath)
+
+	chain := InitBlockChain(dbPath, "John", WithChainParams(&RegTestParams))
+	genesisHash := chain.LastHash
+	tx, _ := NewTransaction("John", "Jane", 20, chain)
+	chain.AddBlock([]*Transaction{tx})
+	lastHash := chain.LastHash
+
+	err := chain.Database.Update(func(txn *badger.Txn) error {
+		return txn.Delete(genesisHash)
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, chain.Close())
+
+	chain = ContinueBlockChain(dbPath)
+	defer chain.Close()
+	assert.Equal(t, lastHash, chain.LastHash, "Last hash with an intact tip should be kept")
+	assert.ErrorContains(t, chain.Verify(), "is missing")
+}
+
+// TestContinueBlockChainParams checks that the network parameters are
+// restored from the database and that other networks are rejected.
+func TestContinueBlockChainParams(t *testing.T) {
+	dbPath := filepath.Join("testdata", "db", "params")
+	os.MkdirAll(dbPath, 0755)
+	defer _cleanTestBadgerDatabase(dbPath)
+
+	chain := InitBlockChain(dbPath, "John", WithChainParams(&RegTestParams))
+	assert.Equal(t, RegTestParams.Difficulty, chain.Iterator().Next().Difficulty)
+	chain.Close()
+
+	assert.Panics(t, func() { ContinueBlockChain(dbPath, WithChainParams(&MainNetParams)) }, "Continuing on another network should panic")
+
+	chain = ContinueBlockChain(dbPath)
+	defer chain.Close()
+	assert.Equal(t, RegTestParams, *chain.Params)
+	assert.Equal(t, RegTestParams.CoinbaseReward, chain.Balance("John"))
+}
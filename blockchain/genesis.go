@@ -0,0 +1,129 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package blockchain
+
+import (
+	"fmt"
+
+	"github.com/tchiunam/axolgo-lib/io/ioutil"
+)
+
+// A premine allocation of coins to an address in the genesis block
+type GenesisAllocation struct {
+	Address string `mapstructure:"address"`
+	Value   int    `mapstructure:"value"`
+}
+
+// GenesisSpec describes the genesis block of a new chain. Difficulty
+// and ExtraData default to the chain parameters when they are not set.
+//
+// For example, in YAML:
+//
+//	timestamp: 1664582400
+//	extra_data: Axolotl genesis
+//	difficulty: 8
+//	allocations:
+//	  - address: 1BoatSLRHtKNngkdXEeobR76b53LETtpyT
+//	    value: 500
+type GenesisSpec struct {
+	Timestamp   int64               `mapstructure:"timestamp"`
+	ExtraData   string              `mapstructure:"extra_data"`
+	Difficulty  int                 `mapstructure:"difficulty"`
+	Allocations []GenesisAllocation `mapstructure:"allocations"`
+}
+
+// Read a genesis spec from a YAML or JSON file. The format is
+// decided by the file extension.
+func ReadGenesisSpec(filepath string) (*GenesisSpec, error) {
+	var spec GenesisSpec
+	if _, err := ioutil.ReadConfigFile(filepath, ioutil.WithCFOClass(&spec)); err != nil {
+		return nil, err
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &spec, nil
+}
+
+// Validate checks that the genesis spec can create a genesis block.
+// The addresses are checked by ValidateFor since they depend on the network.
+func (spec *GenesisSpec) Validate() error {
+	if len(spec.Allocations) == 0 {
+		return fmt.Errorf("Genesis spec has no allocations")
+	}
+	if spec.Difficulty < 0 || spec.Difficulty > MaxDifficulty {
+		return fmt.Errorf("Invalid genesis difficulty: %d", spec.Difficulty)
+	}
+	for _, allocation := range spec.Allocations {
+		if allocation.Address == "" {
+			return fmt.Errorf("Genesis allocation has no address")
+		}
+		if allocation.Value <= 0 {
+			return fmt.Errorf("Invalid genesis allocation value for %s: %d", allocation.Address, allocation.Value)
+		}
+	}
+
+	return nil
+}
+
+// ValidateFor checks that the genesis spec can create a genesis block
+// on the network given by params, including its addresses
+func (spec *GenesisSpec) ValidateFor(params *ChainParams) error {
+	if err := spec.Validate(); err != nil {
+		return err
+	}
+	for _, allocation := range spec.Allocations {
+		if !ValidateAddress(allocation.Address, params) {
+			return fmt.Errorf("Invalid genesis allocation address for the %s network: %s", params.Name, allocation.Address)
+		}
+	}
+
+	return nil
+}
+
+// Block mines the genesis block described by the spec. The same spec
+// and parameters always produce the same block.
+func (spec *GenesisSpec) Block(params *ChainParams) (*Block, error) {
+	if err := spec.ValidateFor(params); err != nil {
+		return nil, err
+	}
+
+	data := spec.ExtraData
+	if data == "" {
+		data = params.GenesisData
+	}
+	difficulty := spec.Difficulty
+	if difficulty == 0 {
+		difficulty = params.Difficulty
+	}
+
+	var outputs []TXOutput
+	for _, allocation := range spec.Allocations {
+		outputs = append(outputs, TXOutput{allocation.Value, allocation.Address})
+	}
+	tx := Transaction{nil, []TXInput{{[]byte{}, -1, data}}, outputs}
+	tx.SetID()
+
+	return CreateBlockAt([]*Transaction{&tx}, []byte{}, difficulty, spec.Timestamp), nil
+}
@@ -0,0 +1,185 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package blockchain
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Main network addresses of the allocations in testdata/genesis.yaml
+const (
+	testJohnAddress = "1Lnk8fKL85oyt4ZvYnfch2Th6hSt3sbNxw"
+	testJaneAddress = "1EaA7jRuSp9Xkt6QACxw4bQhAbkgveFJpC"
+)
+
+// TestReadGenesisSpec calls ReadGenesisSpec to check for reading
+// of YAML and JSON genesis specs.
+func TestReadGenesisSpec(t *testing.T) {
+	expectSpec := GenesisSpec{
+		Timestamp:  1664582400,
+		ExtraData:  "Axolotl genesis",
+		Difficulty: 4,
+		Allocations: []GenesisAllocation{
+			{Address: testJohnAddress, Value: 500},
+			{Address: testJaneAddress, Value: 250},
+		},
+	}
+
+	cases := map[string]struct {
+		filepath string
+	}{
+		"yaml file": {
+			filepath: filepath.Join("testdata", "genesis.yaml"),
+		},
+		"json file": {
+			filepath: filepath.Join("testdata", "genesis.json"),
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			spec, err := ReadGenesisSpec(c.filepath)
+			assert.NoError(t, err, "ReadGenesisSpec(%v) = %v", c.filepath, err)
+			assert.Equal(t, expectSpec, *spec)
+		})
+	}
+}
+
+// TestReadGenesisSpecInvalid calls ReadGenesisSpec to check for error cases
+func TestReadGenesisSpecInvalid(t *testing.T) {
+	cases := map[string]struct {
+		filepath            string
+		expectStringInError string
+	}{
+		"file does not exist": {
+			filepath:            filepath.Join("testdata", "non-exist-genesis.yaml"),
+			expectStringInError: "no such file or directory",
+		},
+		"invalid allocation": {
+			filepath:            filepath.Join("testdata", "genesis_invalid.yaml"),
+			expectStringInError: "Invalid genesis allocation value for John: 0",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ReadGenesisSpec(c.filepath)
+			assert.ErrorContains(t, err, c.expectStringInError, "ReadGenesisSpec(%v) = %v", c.filepath, err)
+		})
+	}
+}
+
+// TestGenesisSpecValidate checks the difficulty and the addresses of
+// genesis specs
+func TestGenesisSpecValidate(t *testing.T) {
+	allocations := []GenesisAllocation{{Address: testJohnAddress, Value: 500}}
+	cases := map[string]struct {
+		spec                GenesisSpec
+		params              *ChainParams
+		expectStringInError string
+	}{
+		"valid": {
+			spec:   GenesisSpec{Difficulty: MaxDifficulty, Allocations: allocations},
+			params: &MainNetParams,
+		},
+		"negative difficulty": {
+			spec:                GenesisSpec{Difficulty: -1, Allocations: allocations},
+			params:              &MainNetParams,
+			expectStringInError: "Invalid genesis difficulty: -1",
+		},
+		"difficulty too high": {
+			spec:                GenesisSpec{Difficulty: 250, Allocations: allocations},
+			params:              &MainNetParams,
+			expectStringInError: "Invalid genesis difficulty: 250",
+		},
+		"invalid address": {
+			spec:                GenesisSpec{Allocations: []GenesisAllocation{{Address: "John", Value: 500}}},
+			params:              &MainNetParams,
+			expectStringInError: "Invalid genesis allocation address for the main network: John",
+		},
+		"address of another network": {
+			spec:                GenesisSpec{Allocations: allocations},
+			params:              &TestNetParams,
+			expectStringInError: "Invalid genesis allocation address for the test network",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := c.spec.ValidateFor(c.params)
+			if c.expectStringInError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, c.expectStringInError)
+			_, err = c.spec.Block(c.params)
+			assert.ErrorContains(t, err, c.expectStringInError)
+		})
+	}
+}
+
+// TestGenesisSpecBlock checks that a spec always mines the same block
+func TestGenesisSpecBlock(t *testing.T) {
+	spec, err := ReadGenesisSpec(filepath.Join("testdata", "genesis.yaml"))
+	assert.NoError(t, err)
+
+	block, err := spec.Block(&MainNetParams)
+	assert.NoError(t, err)
+	again, _ := spec.Block(&MainNetParams)
+
+	assert.Equal(t, block.Hash, again.Hash, "Genesis block should be deterministic")
+	assert.Equal(t, 4, block.Difficulty)
+	assert.Equal(t, int64(1664582400), block.Timestamp)
+	assert.True(t, NewProof(block).Validate(), "Proof of work is not valid")
+	assert.True(t, block.Transactions[0].IsCoinbase(), "Genesis transaction should be a coinbase")
+}
+
+// TestInitBlockChainWithGenesisSpec creates a chain from a genesis spec
+// and continues it with matching and mismatching genesis blocks.
+func TestInitBlockChainWithGenesisSpec(t *testing.T) {
+	dbPath := filepath.Join("testdata", "db", "genesisspec")
+	os.MkdirAll(dbPath, 0755)
+	defer _cleanTestBadgerDatabase(dbPath)
+
+	spec, err := ReadGenesisSpec(filepath.Join("testdata", "genesis.json"))
+	assert.NoError(t, err)
+
+	chain := InitBlockChain(dbPath, "", WithGenesisSpec(spec))
+	assert.Equal(t, 500, chain.Balance(testJohnAddress), "Balance of John should be 500")
+	assert.Equal(t, 250, chain.Balance(testJaneAddress), "Balance of Jane should be 250")
+	genesisHash := chain.GenesisHash()
+	chain.Close()
+
+	other := *spec
+	other.ExtraData = "Another genesis"
+	assert.Panics(t, func() { ContinueBlockChain(dbPath, WithGenesisSpec(&other)) }, "Mismatching genesis should panic")
+	assert.Panics(t, func() { ContinueBlockChain(dbPath, WithGenesisHash([]byte("foo"))) }, "Mismatching genesis hash should panic")
+
+	chain = ContinueBlockChain(dbPath, WithGenesisSpec(spec), WithGenesisHash(genesisHash))
+	defer chain.Close()
+	assert.Equal(t, genesisHash, chain.GenesisHash())
+}
@@ -0,0 +1,100 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package blockchain
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+
+	"github.com/tchiunam/axolgo-lib/util"
+)
+
+// ChainParams defines the parameters that distinguish one network from
+// another. Chains and addresses of different networks are incompatible.
+type ChainParams struct {
+	// Name of the network
+	Name string
+
+	// Version byte prepended to the public key hash of an address
+	AddressVersion byte
+
+	// Difficulty of mining a new block
+	Difficulty int
+
+	// Value of the output of a coinbase transaction
+	CoinbaseReward int
+
+	// Data in the genesis block
+	GenesisData string
+}
+
+// Parameters of the main network
+var MainNetParams = ChainParams{
+	Name:           "main",
+	AddressVersion: 0x00,
+	Difficulty:     Difficulty,
+	CoinbaseReward: 100,
+	GenesisData:    genesisData,
+}
+
+// Parameters of the test network
+var TestNetParams = ChainParams{
+	Name:           "test",
+	AddressVersion: 0x6f,
+	Difficulty:     8,
+	CoinbaseReward: 100,
+	GenesisData:    "First block in the test chain - Genesis",
+}
+
+// Parameters of the regression test network. Blocks are mined almost
+// instantly so it is suitable for local development and tests.
+var RegTestParams = ChainParams{
+	Name:           "regtest",
+	AddressVersion: 0x7a,
+	Difficulty:     1,
+	CoinbaseReward: 100,
+	GenesisData:    "First block in the regtest chain - Genesis",
+}
+
+// Get the parameters of a named network
+func ChainParamsByName(name string) (*ChainParams, error) {
+	for _, params := range []*ChainParams{&MainNetParams, &TestNetParams, &RegTestParams} {
+		if params.Name == name {
+			return params, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Unknown network: %s", name)
+}
+
+// Serialize the chain parameters into bytes
+func (params *ChainParams) Serialize() []byte {
+	var res bytes.Buffer
+	encoder := gob.NewEncoder(&res)
+
+	err := encoder.Encode(params)
+	util.PanicOnError(err)
+
+	return res.Bytes()
+}
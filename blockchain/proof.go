@@ -31,19 +31,33 @@ import (
 	"github.com/tchiunam/axolgo-lib/util"
 )
 
-// Difficulty of mining a new block
+// Difficulty of mining a new block on the main network. It also applies
+// to blocks created before the difficulty was recorded in the block.
 const Difficulty = 12
 
+// Highest difficulty that can be set. Every step doubles the time it
+// takes to mine a block, so a block of this difficulty takes hours.
+const MaxDifficulty = 32
+
 // Proof of Work structure
 type ProofOfWork struct {
 	Block  *Block
 	Target *big.Int
 }
 
+// Get the difficulty the block is mined with
+func _blockDifficulty(b *Block) int {
+	if b.Difficulty == 0 {
+		return Difficulty
+	}
+
+	return b.Difficulty
+}
+
 // Create a new ProofOfWork
 func NewProof(b *Block) *ProofOfWork {
 	target := big.NewInt(1)
-	target.Lsh(target, uint(256-Difficulty))
+	target.Lsh(target, uint(256-_blockDifficulty(b)))
 
 	pow := &ProofOfWork{b, target}
 
@@ -52,17 +66,22 @@ func NewProof(b *Block) *ProofOfWork {
 
 // Prepare data to run the proof of work algorithm
 func (pow *ProofOfWork) InitData(nonce int) []byte {
-	hexDifficulty, _ := util.IntToHex(int64(Difficulty))
+	hexDifficulty, _ := util.IntToHex(int64(_blockDifficulty(pow.Block)))
 	hexNonce, _ := util.IntToHex(int64(nonce))
-	data := bytes.Join(
-		[][]byte{
-			pow.Block.PrevHash,
-			pow.Block.HashTransactions(),
-			hexNonce,
-			hexDifficulty,
-		},
-		[]byte{},
-	)
+	fields := [][]byte{
+		pow.Block.PrevHash,
+		pow.Block.HashTransactions(),
+		hexNonce,
+		hexDifficulty,
+	}
+
+	// Blocks created before timestamps were recorded have no timestamp
+	if pow.Block.Timestamp != 0 {
+		hexTimestamp, _ := util.IntToHex(pow.Block.Timestamp)
+		fields = append(fields, hexTimestamp)
+	}
+
+	data := bytes.Join(fields, []byte{})
 
 	return data
 }
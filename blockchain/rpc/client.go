@@ -0,0 +1,158 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+// Client calls the methods of a JSON-RPC server over HTTP
+type Client struct {
+	URL        string
+	HTTPClient *http.Client
+	// Sent as a bearer token when the server requires one
+	AuthToken string
+
+	nextID uint64
+}
+
+// Create a new client for the server at the URL
+func NewClient(url string) *Client {
+	return &Client{URL: url, HTTPClient: http.DefaultClient}
+}
+
+// Call a method with the parameters and decode the result into result.
+// A JSON-RPC error returned by the server is returned as *Error.
+func (c *Client) Call(method string, params interface{}, result interface{}) error {
+	id := strconv.FormatUint(atomic.AddUint64(&c.nextID, 1), 10)
+	request := Request{JSONRPC: jsonRPCVersion, Method: method, ID: json.RawMessage(id)}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		request.Params = data
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	httpRequest, err := http.NewRequest(http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	if c.AuthToken != "" {
+		httpRequest.Header.Set("Authorization", "Bearer "+c.AuthToken)
+	}
+
+	httpResponse, err := c.HTTPClient.Do(httpRequest)
+	if err != nil {
+		return err
+	}
+	defer httpResponse.Body.Close()
+
+	if httpResponse.StatusCode != http.StatusOK {
+		return fmt.Errorf("Unexpected HTTP status: %s", httpResponse.Status)
+	}
+
+	var response Response
+	if err = json.NewDecoder(httpResponse.Body).Decode(&response); err != nil {
+		return err
+	}
+	if response.Error != nil {
+		return response.Error
+	}
+	if result != nil {
+		return json.Unmarshal(response.Result, result)
+	}
+
+	return nil
+}
+
+// GetBalance returns the balance of an address
+func (c *Client) GetBalance(address string) (int, error) {
+	var result GetBalanceResult
+	if err := c.Call(MethodGetBalance, GetBalanceParams{address}, &result); err != nil {
+		return 0, err
+	}
+
+	return result.Balance, nil
+}
+
+// Send coins from a wallet of the server to an address
+func (c *Client) Send(from string, to string, amount int) (*SendResult, error) {
+	var result SendResult
+	if err := c.Call(MethodSend, SendParams{from, to, amount}, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// GetBlock returns the block with the hex encoded hash
+func (c *Client) GetBlock(hash string) (*BlockResult, error) {
+	var result BlockResult
+	if err := c.Call(MethodGetBlock, GetBlockParams{hash}, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// GetTransaction returns the transaction with the hex encoded ID
+func (c *Client) GetTransaction(id string) (*GetTransactionResult, error) {
+	var result GetTransactionResult
+	if err := c.Call(MethodGetTransaction, GetTransactionParams{id}, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// ListAddresses returns the addresses of all wallets of the server
+func (c *Client) ListAddresses() ([]string, error) {
+	var result ListAddressesResult
+	if err := c.Call(MethodListAddresses, nil, &result); err != nil {
+		return nil, err
+	}
+
+	return result.Addresses, nil
+}
+
+// CreateWallet creates a new wallet on the server and returns its address
+func (c *Client) CreateWallet() (string, error) {
+	var result CreateWalletResult
+	if err := c.Call(MethodCreateWallet, nil, &result); err != nil {
+		return "", err
+	}
+
+	return result.Address, nil
+}
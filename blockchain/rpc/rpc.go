@@ -0,0 +1,200 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+// Package rpc exposes a BlockChain and its Wallets over JSON-RPC 2.0
+// on HTTP, so that other processes can use a chain without opening
+// its database.
+package rpc
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/tchiunam/axolgo-lib/blockchain"
+)
+
+// Version of the JSON-RPC protocol
+const jsonRPCVersion = "2.0"
+
+// Error codes defined by the JSON-RPC 2.0 specification
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Error code for errors returned by the blockchain or wallets
+const CodeServerError = -32000
+
+// Names of the supported methods
+const (
+	MethodGetBalance     = "getbalance"
+	MethodSend           = "send"
+	MethodGetBlock       = "getblock"
+	MethodGetTransaction = "gettransaction"
+	MethodListAddresses  = "listaddresses"
+	MethodCreateWallet   = "createwallet"
+)
+
+// A JSON-RPC request
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// A JSON-RPC response. Either Result or Error is set.
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// A JSON-RPC error
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error returns the code and message of the error
+func (e *Error) Error() string {
+	return fmt.Sprintf("JSON-RPC error %d: %s", e.Code, e.Message)
+}
+
+// Parameters of getbalance
+type GetBalanceParams struct {
+	Address string `json:"address"`
+}
+
+// Result of getbalance
+type GetBalanceResult struct {
+	Address string `json:"address"`
+	Balance int    `json:"balance"`
+}
+
+// Parameters of send
+type SendParams struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Amount int    `json:"amount"`
+}
+
+// Result of send
+type SendResult struct {
+	TxID      string `json:"txid"`
+	BlockHash string `json:"blockhash"`
+}
+
+// Parameters of getblock
+type GetBlockParams struct {
+	Hash string `json:"hash"`
+}
+
+// Parameters of gettransaction
+type GetTransactionParams struct {
+	ID string `json:"id"`
+}
+
+// Result of gettransaction
+type GetTransactionResult struct {
+	Transaction TransactionResult `json:"transaction"`
+	BlockHash   string            `json:"blockhash"`
+}
+
+// Result of listaddresses
+type ListAddressesResult struct {
+	Addresses []string `json:"addresses"`
+}
+
+// Result of createwallet
+type CreateWalletResult struct {
+	Address string `json:"address"`
+}
+
+// A block with its hashes encoded as hex strings
+type BlockResult struct {
+	Hash         string              `json:"hash"`
+	PrevHash     string              `json:"prevhash"`
+	Nonce        int                 `json:"nonce"`
+	Difficulty   int                 `json:"difficulty"`
+	Timestamp    int64               `json:"timestamp"`
+	Transactions []TransactionResult `json:"transactions"`
+}
+
+// A transaction with its IDs encoded as hex strings
+type TransactionResult struct {
+	ID      string         `json:"id"`
+	Inputs  []InputResult  `json:"inputs"`
+	Outputs []OutputResult `json:"outputs"`
+}
+
+// A transaction input
+type InputResult struct {
+	TxID string `json:"txid"`
+	Out  int    `json:"out"`
+	Sig  string `json:"sig"`
+}
+
+// A transaction output
+type OutputResult struct {
+	Value  int    `json:"value"`
+	PubKey string `json:"pubkey"`
+}
+
+// Convert a block into its JSON representation
+func NewBlockResult(block *blockchain.Block) BlockResult {
+	result := BlockResult{
+		Hash:         hex.EncodeToString(block.Hash),
+		PrevHash:     hex.EncodeToString(block.PrevHash),
+		Nonce:        block.Nonce,
+		Difficulty:   block.Difficulty,
+		Timestamp:    block.Timestamp,
+		Transactions: []TransactionResult{},
+	}
+	for _, tx := range block.Transactions {
+		result.Transactions = append(result.Transactions, NewTransactionResult(tx))
+	}
+
+	return result
+}
+
+// Convert a transaction into its JSON representation
+func NewTransactionResult(tx *blockchain.Transaction) TransactionResult {
+	result := TransactionResult{
+		ID:      hex.EncodeToString(tx.ID),
+		Inputs:  []InputResult{},
+		Outputs: []OutputResult{},
+	}
+	for _, in := range tx.Inputs {
+		result.Inputs = append(result.Inputs, InputResult{hex.EncodeToString(in.ID), in.Out, in.Sig})
+	}
+	for _, out := range tx.Outputs {
+		result.Outputs = append(result.Outputs, OutputResult{out.Value, out.PubKey})
+	}
+
+	return result
+}
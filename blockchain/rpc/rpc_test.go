@@ -0,0 +1,290 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package rpc
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tchiunam/axolgo-lib/blockchain"
+)
+
+// Start a test server with a regtest chain funding the first wallet.
+// The returned function shuts everything down.
+func _startTestServer(t *testing.T, dbPath string) (*httptest.Server, *blockchain.Wallets, string, func()) {
+	os.MkdirAll(dbPath, 0755)
+
+	wallets := &blockchain.Wallets{Wallets: make(map[string]*blockchain.Wallet)}
+	miner := wallets.AddWallet(&blockchain.RegTestParams)
+	chain := blockchain.InitBlockChain(dbPath, miner, blockchain.WithChainParams(&blockchain.RegTestParams))
+
+	server, err := NewServer(chain, wallets)
+	assert.NoError(t, err, "NewServer() = %v", err)
+	httpServer := httptest.NewServer(server)
+
+	return httpServer, wallets, miner, func() {
+		httpServer.Close()
+		chain.Close()
+		os.RemoveAll(dbPath)
+	}
+}
+
+// TestClientServer calls every method through the client
+func TestClientServer(t *testing.T) {
+	httpServer, _, miner, shutdown := _startTestServer(t, filepath.Join("testdata", "db", "rpc"))
+	defer shutdown()
+	client := NewClient(httpServer.URL)
+
+	addresses, err := client.ListAddresses()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{miner}, addresses)
+
+	receiver, err := client.CreateWallet()
+	assert.NoError(t, err)
+	assert.True(t, blockchain.ValidateAddress(receiver, &blockchain.RegTestParams), "%v should be a regtest address", receiver)
+
+	addresses, err = client.ListAddresses()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{miner, receiver}, addresses)
+
+	sent, err := client.Send(miner, receiver, 30)
+	assert.NoError(t, err, "Send(%v, %v, 30) = %v", miner, receiver, err)
+
+	balance, err := client.GetBalance(miner)
+	assert.NoError(t, err)
+	assert.Equal(t, 70, balance, "Balance of miner should be 70")
+	balance, err = client.GetBalance(receiver)
+	assert.NoError(t, err)
+	assert.Equal(t, 30, balance, "Balance of receiver should be 30")
+
+	block, err := client.GetBlock(sent.BlockHash)
+	assert.NoError(t, err, "GetBlock(%v) = %v", sent.BlockHash, err)
+	assert.Equal(t, sent.BlockHash, block.Hash)
+	assert.Equal(t, sent.TxID, block.Transactions[0].ID)
+
+	tx, err := client.GetTransaction(sent.TxID)
+	assert.NoError(t, err, "GetTransaction(%v) = %v", sent.TxID, err)
+	assert.Equal(t, sent.TxID, tx.Transaction.ID)
+	assert.Equal(t, sent.BlockHash, tx.BlockHash)
+	assert.Equal(t, OutputResult{30, receiver}, tx.Transaction.Outputs[0])
+}
+
+// TestClientServerInvalid calls the server with invalid requests and
+// checks the JSON-RPC error codes.
+func TestClientServerInvalid(t *testing.T) {
+	httpServer, wallets, miner, shutdown := _startTestServer(t, filepath.Join("testdata", "db", "rpcinvalid"))
+	defer shutdown()
+	client := NewClient(httpServer.URL)
+	receiver := wallets.AddWallet(&blockchain.RegTestParams)
+
+	cases := map[string]struct {
+		method     string
+		params     interface{}
+		expectCode int
+	}{
+		"method not found": {
+			method:     "foo",
+			expectCode: CodeMethodNotFound,
+		},
+		"missing params": {
+			method:     MethodGetBalance,
+			expectCode: CodeInvalidParams,
+		},
+		"invalid block hash": {
+			method:     MethodGetBlock,
+			params:     GetBlockParams{"not hex"},
+			expectCode: CodeInvalidParams,
+		},
+		"block not found": {
+			method:     MethodGetBlock,
+			params:     GetBlockParams{"abcdef"},
+			expectCode: CodeServerError,
+		},
+		"transaction not found": {
+			method:     MethodGetTransaction,
+			params:     GetTransactionParams{"abcdef"},
+			expectCode: CodeServerError,
+		},
+		"insufficient funds": {
+			method:     MethodSend,
+			params:     SendParams{miner, receiver, 1000},
+			expectCode: CodeServerError,
+		},
+		"wallet not found": {
+			method:     MethodSend,
+			params:     SendParams{"John", receiver, 10},
+			expectCode: CodeInvalidParams,
+		},
+		"address of another network": {
+			method:     MethodSend,
+			params:     SendParams{miner, wallets.AddWallet(&blockchain.MainNetParams), 10},
+			expectCode: CodeInvalidParams,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := client.Call(c.method, c.params, nil)
+			assert.Error(t, err, "Call(%v, %v) should return error", c.method, c.params)
+			rpcErr, ok := err.(*Error)
+			assert.True(t, ok, "Call(%v, %v) = %v, want *Error", c.method, c.params, err)
+			if ok {
+				assert.Equal(t, c.expectCode, rpcErr.Code, "Call(%v, %v) = %v", c.method, c.params, err)
+			}
+		})
+	}
+}
+
+// TestServerRawRequests posts raw bodies to check parse errors,
+// batches and notifications.
+func TestServerRawRequests(t *testing.T) {
+	httpServer, _, _, shutdown := _startTestServer(t, filepath.Join("testdata", "db", "rpcraw"))
+	defer shutdown()
+
+	cases := map[string]struct {
+		body           string
+		expectStatus   int
+		expectContains []string
+	}{
+		"parse error": {
+			body:           `{"jsonrpc": "2.0", "method": `,
+			expectStatus:   http.StatusOK,
+			expectContains: []string{`"code":-32700`},
+		},
+		"wrong version": {
+			body:           `{"jsonrpc": "1.0", "method": "listaddresses", "id": 1}`,
+			expectStatus:   http.StatusOK,
+			expectContains: []string{`"code":-32600`, `"id":1`},
+		},
+		"batch": {
+			body:           `[{"jsonrpc": "2.0", "method": "listaddresses", "id": 1}, {"jsonrpc": "2.0", "method": "foo", "id": "two"}]`,
+			expectStatus:   http.StatusOK,
+			expectContains: []string{`"addresses":`, `"code":-32601`, `"id":"two"`},
+		},
+		"empty batch": {
+			body:           `[]`,
+			expectStatus:   http.StatusOK,
+			expectContains: []string{`"code":-32600`},
+		},
+		"notification": {
+			body:         `{"jsonrpc": "2.0", "method": "listaddresses"}`,
+			expectStatus: http.StatusNoContent,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			response, err := http.Post(httpServer.URL, "application/json", strings.NewReader(c.body))
+			assert.NoError(t, err)
+			defer response.Body.Close()
+			body, _ := io.ReadAll(response.Body)
+
+			assert.Equal(t, c.expectStatus, response.StatusCode, "Unexpected status for %v", c.body)
+			for _, s := range c.expectContains {
+				assert.Contains(t, string(body), s)
+			}
+		})
+	}
+
+	response, err := http.Get(httpServer.URL)
+	assert.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, response.StatusCode)
+}
+
+// TestServerAuthToken checks that a server with a token rejects
+// requests without it
+func TestServerAuthToken(t *testing.T) {
+	dbPath := filepath.Join("testdata", "db", "rpcauth")
+	os.MkdirAll(dbPath, 0755)
+	defer os.RemoveAll(dbPath)
+
+	wallets := &blockchain.Wallets{Wallets: make(map[string]*blockchain.Wallet)}
+	miner := wallets.AddWallet(&blockchain.RegTestParams)
+	chain := blockchain.InitBlockChain(dbPath, miner, blockchain.WithChainParams(&blockchain.RegTestParams))
+	defer chain.Close()
+
+	_, err := NewServer(chain, wallets, WithAuthToken(""))
+	assert.Error(t, err, "NewServer() with an empty token should return error")
+
+	server, err := NewServer(chain, wallets, WithAuthToken("iamthebest"))
+	assert.NoError(t, err, "NewServer() = %v", err)
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	cases := map[string]struct {
+		token     string
+		expectErr bool
+	}{
+		"no token": {
+			expectErr: true,
+		},
+		"wrong token": {
+			token:     "iamtheworst",
+			expectErr: true,
+		},
+		"valid token": {
+			token: "iamthebest",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			client := NewClient(httpServer.URL)
+			client.AuthToken = c.token
+			balance, err := client.GetBalance(miner)
+			if c.expectErr {
+				assert.Error(t, err, "GetBalance() with token %q should return error", c.token)
+				assert.Contains(t, err.Error(), "401")
+			} else {
+				assert.NoError(t, err, "GetBalance() = %v", err)
+				assert.Equal(t, 100, balance)
+			}
+		})
+	}
+	// The scheme is required and matched without regard to case
+	headers := map[string]int{
+		"iamthebest":        http.StatusUnauthorized,
+		"Basic iamthebest":  http.StatusUnauthorized,
+		"Bearer":            http.StatusUnauthorized,
+		"Bearer iamthebest": http.StatusOK,
+		"bearer iamthebest": http.StatusOK,
+	}
+	for header, expectStatus := range headers {
+		t.Run(header, func(t *testing.T) {
+			request, err := http.NewRequest(http.MethodPost, httpServer.URL, strings.NewReader(`{"jsonrpc": "2.0", "method": "listaddresses", "id": 1}`))
+			assert.NoError(t, err)
+			request.Header.Set("Authorization", header)
+			response, err := http.DefaultClient.Do(request)
+			assert.NoError(t, err, "Do() = %v", err)
+			defer response.Body.Close()
+			assert.Equal(t, expectStatus, response.StatusCode, "Authorization %q", header)
+		})
+	}
+}
@@ -0,0 +1,378 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package rpc
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/tchiunam/axolgo-lib/blockchain"
+)
+
+// Maximum size of a request body
+const maxRequestSize = 1 << 20
+
+// ServerOptionsFunc is a type alias for ServerOptions functional option
+type ServerOptionsFunc func(*ServerOptions) error
+
+// ServerOptions are discrete set of options that are valid for
+// creating a JSON-RPC server.
+type ServerOptions struct {
+	WalletsFilePath string
+	AuthToken       string
+}
+
+// WithWalletsFilePath is a helper function to construct functional options
+// that persists the wallets to a file whenever a wallet is created.
+func WithWalletsFilePath(v string) ServerOptionsFunc {
+	return func(o *ServerOptions) error {
+		o.WalletsFilePath = v
+		return nil
+	}
+}
+
+// WithAuthToken is a helper function to construct functional options
+// that require every request to carry the token as a bearer token.
+func WithAuthToken(v string) ServerOptionsFunc {
+	return func(o *ServerOptions) error {
+		if v == "" {
+			return fmt.Errorf("Auth token must not be empty")
+		}
+		o.AuthToken = v
+		return nil
+	}
+}
+
+// Evaluate the functional options and set the options in the ServerOptions struct
+func (options *ServerOptions) Merge(optFns ...ServerOptionsFunc) error {
+	for _, optFn := range optFns {
+		if err := optFn(options); err != nil {
+			return fmt.Errorf("Fail to read server options: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// A method handler takes the raw parameters and returns the result
+type methodFunc func(params json.RawMessage) (interface{}, error)
+
+// Server serves JSON-RPC requests over HTTP for a blockchain and
+// its wallets. It implements http.Handler.
+//
+// Any caller that reaches the server can send coins from the wallets
+// it holds. Configure WithAuthToken or bind the server to localhost.
+type Server struct {
+	chain   *blockchain.BlockChain
+	wallets *blockchain.Wallets
+	options ServerOptions
+	methods map[string]methodFunc
+
+	// Writes to the chain and the wallets are serialized and
+	// reads of the chain tip are guarded against them
+	mu sync.RWMutex
+}
+
+// Create a new JSON-RPC server
+func NewServer(
+	chain *blockchain.BlockChain,
+	wallets *blockchain.Wallets,
+	optFns ...ServerOptionsFunc) (*Server, error) {
+	var options ServerOptions
+	if err := options.Merge(optFns...); err != nil {
+		return nil, err
+	}
+
+	s := &Server{chain: chain, wallets: wallets, options: options}
+	s.methods = map[string]methodFunc{
+		MethodGetBalance:     s.getBalance,
+		MethodSend:           s.send,
+		MethodGetBlock:       s.getBlock,
+		MethodGetTransaction: s.getTransaction,
+		MethodListAddresses:  s.listAddresses,
+		MethodCreateWallet:   s.createWallet,
+	}
+
+	return s, nil
+}
+
+// ServeHTTP handles a single request or a batch of requests
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var out interface{}
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(body, &batch); err != nil || len(batch) == 0 {
+			out = _errorResponse(nil, CodeInvalidRequest, "Invalid batch request")
+		} else {
+			responses := []*Response{}
+			for _, raw := range batch {
+				if response := s.handle(raw); response != nil {
+					responses = append(responses, response)
+				}
+			}
+			if len(responses) > 0 {
+				out = responses
+			}
+		}
+	} else if response := s.handle(body); response != nil {
+		out = response
+	}
+
+	// Notifications do not get a response
+	if out == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+// Check the bearer token of a request if the server requires one. The
+// scheme is case-insensitive, the token is compared in constant time.
+func (s *Server) authorized(r *http.Request) bool {
+	if s.options.AuthToken == "" {
+		return true
+	}
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.options.AuthToken)) == 1
+}
+
+// Handle a single request. Returns nil for notifications.
+func (s *Server) handle(raw json.RawMessage) *Response {
+	var request Request
+	if err := json.Unmarshal(raw, &request); err != nil {
+		return _errorResponse(nil, CodeParseError, "Parse error")
+	}
+	if request.JSONRPC != jsonRPCVersion || request.Method == "" {
+		return _errorResponse(request.ID, CodeInvalidRequest, "Invalid request")
+	}
+
+	result, err := s.call(request)
+	if request.ID == nil {
+		return nil
+	}
+	if err != nil {
+		if rpcErr, ok := err.(*Error); ok {
+			return &Response{JSONRPC: jsonRPCVersion, Error: rpcErr, ID: request.ID}
+		}
+		return _errorResponse(request.ID, CodeServerError, err.Error())
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return _errorResponse(request.ID, CodeInternalError, err.Error())
+	}
+
+	return &Response{JSONRPC: jsonRPCVersion, Result: data, ID: request.ID}
+}
+
+// Call the method of the request. The blockchain panics on database
+// errors so panics are turned into internal errors.
+func (s *Server) call(request Request) (result interface{}, err error) {
+	method, ok := s.methods[request.Method]
+	if !ok {
+		return nil, &Error{CodeMethodNotFound, fmt.Sprintf("Method not found: %s", request.Method)}
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			result = nil
+			err = &Error{CodeInternalError, fmt.Sprintf("%v", r)}
+		}
+	}()
+
+	return method(request.Params)
+}
+
+// Make an error response
+func _errorResponse(id json.RawMessage, code int, message string) *Response {
+	return &Response{JSONRPC: jsonRPCVersion, Error: &Error{code, message}, ID: id}
+}
+
+// Decode the parameters of a request
+func _decodeParams(params json.RawMessage, v interface{}) error {
+	if len(params) == 0 {
+		return &Error{CodeInvalidParams, "Missing params"}
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &Error{CodeInvalidParams, fmt.Sprintf("Invalid params: %v", err)}
+	}
+
+	return nil
+}
+
+// Decode a hex string parameter
+func _decodeHexParam(name string, value string) ([]byte, error) {
+	decoded, err := hex.DecodeString(value)
+	if err != nil || len(decoded) == 0 {
+		return nil, &Error{CodeInvalidParams, fmt.Sprintf("Invalid %s: %s", name, value)}
+	}
+
+	return decoded, nil
+}
+
+// Get the balance of an address
+func (s *Server) getBalance(raw json.RawMessage) (interface{}, error) {
+	var params GetBalanceParams
+	if err := _decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return GetBalanceResult{params.Address, s.chain.Balance(params.Address)}, nil
+}
+
+// Send coins from a wallet to an address and mine the transaction
+// into a new block
+func (s *Server) send(raw json.RawMessage) (interface{}, error) {
+	var params SendParams
+	if err := _decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	if params.Amount <= 0 {
+		return nil, &Error{CodeInvalidParams, fmt.Sprintf("Invalid amount: %d", params.Amount)}
+	}
+	if !blockchain.ValidateAddress(params.To, s.chain.Params) {
+		return nil, &Error{CodeInvalidParams, fmt.Sprintf("Invalid address: %s", params.To)}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.wallets.Wallets[params.From]; !ok {
+		return nil, &Error{CodeInvalidParams, fmt.Sprintf("Wallet not found: %s", params.From)}
+	}
+
+	tx, err := blockchain.NewTransaction(params.From, params.To, params.Amount, s.chain)
+	if err != nil {
+		return nil, err
+	}
+	block := s.chain.AddBlock([]*blockchain.Transaction{tx})
+
+	return SendResult{hex.EncodeToString(tx.ID), hex.EncodeToString(block.Hash)}, nil
+}
+
+// Get a block by its hash
+func (s *Server) getBlock(raw json.RawMessage) (interface{}, error) {
+	var params GetBlockParams
+	if err := _decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	hash, err := _decodeHexParam("hash", params.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	block, err := s.chain.GetBlock(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewBlockResult(block), nil
+}
+
+// Get a transaction by its ID
+func (s *Server) getTransaction(raw json.RawMessage) (interface{}, error) {
+	var params GetTransactionParams
+	if err := _decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	id, err := _decodeHexParam("id", params.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tx, block, err := s.chain.FindTransaction(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetTransactionResult{NewTransactionResult(tx), hex.EncodeToString(block.Hash)}, nil
+}
+
+// List the addresses of all wallets
+func (s *Server) listAddresses(raw json.RawMessage) (interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	addresses := s.wallets.GetAllAddresses()
+	if addresses == nil {
+		addresses = []string{}
+	}
+
+	return ListAddressesResult{addresses}, nil
+}
+
+// Create a new wallet and persist the wallets if a file is configured
+func (s *Server) createWallet(raw json.RawMessage) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	address := s.wallets.AddWallet(s.chain.Params)
+	if s.options.WalletsFilePath != "" {
+		if err := s.wallets.Persist(s.options.WalletsFilePath); err != nil {
+			delete(s.wallets.Wallets, address)
+			return nil, err
+		}
+	}
+
+	return CreateWalletResult{address}, nil
+}
@@ -0,0 +1,206 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package blockchain
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"sort"
+)
+
+// Default number of branches explored by BranchAndBoundSelector
+const defaultBranchAndBoundTries = 100000
+
+// ErrInsufficientFunds is returned when the unspent outputs of an
+// address cannot cover the requested amount.
+var ErrInsufficientFunds = errors.New("Not enough funds to make a transaction")
+
+// ErrNoExactMatch is returned by BranchAndBoundSelector when no
+// combination of outputs adds up to the exact amount.
+var ErrNoExactMatch = errors.New("No combination of outputs matches the amount exactly")
+
+// An unspent transaction output and its location in the chain
+type UnspentOutput struct {
+	TxID   []byte
+	Index  int
+	Output TXOutput
+}
+
+// CoinSelector chooses which unspent outputs are spent to cover
+// an amount. The selected outputs must add up to at least the amount.
+type CoinSelector interface {
+	Select(utxos []UnspentOutput, amount int) ([]UnspentOutput, error)
+}
+
+// LargestFirstSelector spends the largest outputs first, which keeps
+// the number of inputs low.
+type LargestFirstSelector struct{}
+
+// SmallestFirstSelector spends the smallest outputs first, which
+// consolidates dust into the change output.
+type SmallestFirstSelector struct{}
+
+// BranchAndBoundSelector searches for a set of outputs adding up to
+// the exact amount so that no change output is needed. Fallback is
+// used when no exact match is found within MaxTries branches. If
+// Fallback is nil, ErrNoExactMatch is returned instead.
+type BranchAndBoundSelector struct {
+	MaxTries int
+	Fallback CoinSelector
+}
+
+// RandomSelector spends outputs in a random order so that the
+// selection does not reveal anything about the wallet.
+type RandomSelector struct{}
+
+// Sum the values of the unspent outputs
+func sumUnspentOutputs(utxos []UnspentOutput) int {
+	total := 0
+	for _, utxo := range utxos {
+		total += utxo.Output.Value
+	}
+
+	return total
+}
+
+// Take outputs in the given order until the amount is covered
+func accumulateUnspentOutputs(utxos []UnspentOutput, amount int) ([]UnspentOutput, error) {
+	var selected []UnspentOutput
+	accumulated := 0
+
+	for _, utxo := range utxos {
+		if accumulated >= amount {
+			break
+		}
+		selected = append(selected, utxo)
+		accumulated += utxo.Output.Value
+	}
+
+	if accumulated < amount {
+		return nil, ErrInsufficientFunds
+	}
+
+	return selected, nil
+}
+
+// Copy the unspent outputs and sort them by value
+func sortUnspentOutputs(utxos []UnspentOutput, descending bool) []UnspentOutput {
+	sorted := make([]UnspentOutput, len(utxos))
+	copy(sorted, utxos)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if descending {
+			return sorted[i].Output.Value > sorted[j].Output.Value
+		}
+		return sorted[i].Output.Value < sorted[j].Output.Value
+	})
+
+	return sorted
+}
+
+// Select the largest outputs until the amount is covered
+func (s LargestFirstSelector) Select(utxos []UnspentOutput, amount int) ([]UnspentOutput, error) {
+	return accumulateUnspentOutputs(sortUnspentOutputs(utxos, true), amount)
+}
+
+// Select the smallest outputs until the amount is covered
+func (s SmallestFirstSelector) Select(utxos []UnspentOutput, amount int) ([]UnspentOutput, error) {
+	return accumulateUnspentOutputs(sortUnspentOutputs(utxos, false), amount)
+}
+
+// Select a set of outputs adding up to exactly the amount
+func (s BranchAndBoundSelector) Select(utxos []UnspentOutput, amount int) ([]UnspentOutput, error) {
+	if sumUnspentOutputs(utxos) < amount {
+		return nil, ErrInsufficientFunds
+	}
+
+	maxTries := s.MaxTries
+	if maxTries <= 0 {
+		maxTries = defaultBranchAndBoundTries
+	}
+
+	// Exploring the largest outputs first finds a match with fewer inputs
+	sorted := sortUnspentOutputs(utxos, true)
+
+	// remaining[i] is the total value of the outputs from i onwards
+	remaining := make([]int, len(sorted)+1)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		remaining[i] = remaining[i+1] + sorted[i].Output.Value
+	}
+
+	var selected []int
+	tries := 0
+
+	var search func(index int, total int) bool
+	search = func(index int, total int) bool {
+		tries++
+		if total == amount {
+			return true
+		}
+		// Prune branches that overshoot or can no longer reach the amount
+		if total > amount || index >= len(sorted) || total+remaining[index] < amount || tries > maxTries {
+			return false
+		}
+
+		selected = append(selected, index)
+		if search(index+1, total+sorted[index].Output.Value) {
+			return true
+		}
+		selected = selected[:len(selected)-1]
+
+		return search(index+1, total)
+	}
+
+	if search(0, 0) {
+		result := make([]UnspentOutput, 0, len(selected))
+		for _, i := range selected {
+			result = append(result, sorted[i])
+		}
+		return result, nil
+	}
+
+	if s.Fallback != nil {
+		return s.Fallback.Select(utxos, amount)
+	}
+
+	return nil, ErrNoExactMatch
+}
+
+// Select outputs in a random order until the amount is covered
+func (s RandomSelector) Select(utxos []UnspentOutput, amount int) ([]UnspentOutput, error) {
+	shuffled := make([]UnspentOutput, len(utxos))
+	copy(shuffled, utxos)
+
+	// Fisher-Yates shuffle using a cryptographically secure source
+	for i := len(shuffled) - 1; i > 0; i-- {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return nil, err
+		}
+		j := int(n.Int64())
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	}
+
+	return accumulateUnspentOutputs(shuffled, amount)
+}
@@ -0,0 +1,154 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package blockchain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Make unspent outputs with the given values
+func _makeUnspentOutputs(values ...int) []UnspentOutput {
+	var utxos []UnspentOutput
+	for i, value := range values {
+		utxos = append(utxos, UnspentOutput{[]byte{byte(i)}, 0, TXOutput{value, "John"}})
+	}
+
+	return utxos
+}
+
+// Collect the values of the selected outputs
+func _unspentOutputValues(utxos []UnspentOutput) []int {
+	var values []int
+	for _, utxo := range utxos {
+		values = append(values, utxo.Output.Value)
+	}
+
+	return values
+}
+
+// TestCoinSelectors calls Select of every CoinSelector and checks
+// the chosen outputs.
+func TestCoinSelectors(t *testing.T) {
+	cases := map[string]struct {
+		selector     CoinSelector
+		values       []int
+		amount       int
+		expectValues []int
+	}{
+		"largest first": {
+			selector:     LargestFirstSelector{},
+			values:       []int{5, 50, 20, 1},
+			amount:       60,
+			expectValues: []int{50, 20},
+		},
+		"smallest first": {
+			selector:     SmallestFirstSelector{},
+			values:       []int{5, 50, 20, 1},
+			amount:       25,
+			expectValues: []int{1, 5, 20},
+		},
+		"branch and bound exact match": {
+			selector:     BranchAndBoundSelector{},
+			values:       []int{5, 50, 20, 1, 7},
+			amount:       26,
+			expectValues: []int{20, 5, 1},
+		},
+		"branch and bound fallback": {
+			selector:     BranchAndBoundSelector{Fallback: LargestFirstSelector{}},
+			values:       []int{10, 10, 10},
+			amount:       15,
+			expectValues: []int{10, 10},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			selected, err := c.selector.Select(_makeUnspentOutputs(c.values...), c.amount)
+			assert.NoError(t, err, "Select(%v, %v) = %v", c.values, c.amount, err)
+			assert.Equal(t, c.expectValues, _unspentOutputValues(selected))
+		})
+	}
+}
+
+// TestRandomSelector calls Select of RandomSelector and checks that
+// the amount is covered.
+func TestRandomSelector(t *testing.T) {
+	values := []int{5, 50, 20, 1, 7}
+
+	for i := 0; i < 10; i++ {
+		selected, err := RandomSelector{}.Select(_makeUnspentOutputs(values...), 30)
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, sumUnspentOutputs(selected), 30)
+	}
+}
+
+// TestCoinSelectorsInvalid calls Select of every CoinSelector to make
+// sure errors are returned when the amount cannot be covered.
+func TestCoinSelectorsInvalid(t *testing.T) {
+	cases := map[string]struct {
+		selector    CoinSelector
+		values      []int
+		amount      int
+		expectError error
+	}{
+		"largest first": {
+			selector:    LargestFirstSelector{},
+			values:      []int{5, 10},
+			amount:      16,
+			expectError: ErrInsufficientFunds,
+		},
+		"smallest first": {
+			selector:    SmallestFirstSelector{},
+			values:      []int{5, 10},
+			amount:      16,
+			expectError: ErrInsufficientFunds,
+		},
+		"random": {
+			selector:    RandomSelector{},
+			values:      []int{5, 10},
+			amount:      16,
+			expectError: ErrInsufficientFunds,
+		},
+		"branch and bound insufficient funds": {
+			selector:    BranchAndBoundSelector{},
+			values:      []int{5, 10},
+			amount:      16,
+			expectError: ErrInsufficientFunds,
+		},
+		"branch and bound no exact match": {
+			selector:    BranchAndBoundSelector{},
+			values:      []int{5, 10},
+			amount:      12,
+			expectError: ErrNoExactMatch,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := c.selector.Select(_makeUnspentOutputs(c.values...), c.amount)
+			assert.ErrorIs(t, err, c.expectError, "Select(%v, %v) = %v", c.values, c.amount, err)
+		})
+	}
+}
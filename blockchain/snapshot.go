@@ -0,0 +1,397 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package blockchain
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/dgraph-io/badger"
+	"github.com/tchiunam/axolgo-lib/cryptography"
+)
+
+// Magic bytes at the beginning of every snapshot
+const snapshotMagic = "AXOLSNAP"
+
+// Version of the snapshot format
+const snapshotVersion = 1
+
+// SnapshotOptionsFunc is a type alias for SnapshotOptions functional option
+type SnapshotOptionsFunc func(*SnapshotOptions) error
+
+// SnapshotOptions are discrete set of options that are valid for
+// backing up and restoring a blockchain.
+type SnapshotOptions struct {
+	Passphrase  string
+	Params      *ChainParams
+	GenesisHash []byte
+}
+
+// WithSnapshotPassphrase is a helper function to construct functional options
+// that encrypts or decrypts the snapshot with a passphrase.
+func WithSnapshotPassphrase(passphrase string) SnapshotOptionsFunc {
+	return func(o *SnapshotOptions) error {
+		o.Passphrase = passphrase
+		return nil
+	}
+}
+
+// WithSnapshotChainParams is a helper function to construct functional options
+// that sets the network a restored snapshot must be of. Otherwise the
+// network recorded in the snapshot is trusted.
+func WithSnapshotChainParams(params *ChainParams) SnapshotOptionsFunc {
+	return func(o *SnapshotOptions) error {
+		o.Params = params
+		return nil
+	}
+}
+
+// WithSnapshotGenesisHash is a helper function to construct functional
+// options that sets the hash of the genesis block a restored snapshot
+// must start with. Otherwise any valid chain of the network is accepted.
+func WithSnapshotGenesisHash(hash []byte) SnapshotOptionsFunc {
+	return func(o *SnapshotOptions) error {
+		if len(hash) == 0 {
+			return fmt.Errorf("Genesis hash is empty")
+		}
+		o.GenesisHash = hash
+		return nil
+	}
+}
+
+// Evaluate the functional options and set the options in the SnapshotOptions struct
+func (options *SnapshotOptions) Merge(optFns ...SnapshotOptionsFunc) error {
+	for _, optFn := range optFns {
+		if err := optFn(options); err != nil {
+			return fmt.Errorf("Fail to read snapshot options: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// Header written before the entries of a snapshot
+type snapshotHeader struct {
+	Version  int
+	LastHash []byte
+}
+
+// A key and value pair in the database
+type snapshotEntry struct {
+	Key   []byte
+	Value []byte
+}
+
+// Backup writes a compressed snapshot of every key in the database,
+// including the blocks, the indexes and the tip. The snapshot is taken
+// in a single read transaction so it is consistent even when blocks
+// are being added at the same time. With a passphrase, the snapshot is
+// encrypted as a stream, see cryptography.NewEncryptWriter.
+func (chain *BlockChain) Backup(w io.Writer, optFns ...SnapshotOptionsFunc) error {
+	var options SnapshotOptions
+	if err := options.Merge(optFns...); err != nil {
+		return err
+	}
+
+	out := w
+	var ew io.WriteCloser
+	if options.Passphrase != "" {
+		var err error
+		if ew, err = cryptography.NewEncryptWriter(w, options.Passphrase); err != nil {
+			return err
+		}
+		out = ew
+	}
+
+	if _, err := io.WriteString(out, snapshotMagic); err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+
+	err := chain.Database.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(lastHashKey)
+		if err != nil {
+			return err
+		}
+		lastHash, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+
+		encoder := gob.NewEncoder(zw)
+		if err = encoder.Encode(snapshotHeader{snapshotVersion, lastHash}); err != nil {
+			return err
+		}
+
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			value, err := it.Item().ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			if err = encoder.Encode(snapshotEntry{it.Item().KeyCopy(nil), value}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err = zw.Close(); err != nil {
+		return err
+	}
+	// Closing the encryption writer seals the last chunk
+	if ew != nil {
+		return ew.Close()
+	}
+
+	return nil
+}
+
+// RestoreBlockChain loads a snapshot created by Backup into a new
+// database at dbPath. The snapshot is loaded into a temporary directory
+// next to dbPath and the chain is validated from the tip down to the
+// genesis block against the difficulty of its network. The network and
+// the genesis block can be pinned with WithSnapshotChainParams and
+// WithSnapshotGenesisHash. Only then is the directory renamed to dbPath,
+// which must not exist or be empty, so nothing at dbPath is touched if
+// the snapshot is not valid.
+func RestoreBlockChain(dbPath string, r io.Reader, optFns ...SnapshotOptionsFunc) (*BlockChain, error) {
+	var options SnapshotOptions
+	if err := options.Merge(optFns...); err != nil {
+		return nil, err
+	}
+
+	if DBExists(dbPath) {
+		return nil, fmt.Errorf("Blockchain already exists.")
+	}
+	if entries, err := os.ReadDir(dbPath); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("Directory %s is not empty", dbPath)
+	}
+
+	in := r
+	if options.Passphrase != "" {
+		dr, err := cryptography.NewDecryptReader(r, options.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+		in = dr
+	}
+
+	magic := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(in, magic); err != nil || string(magic) != snapshotMagic {
+		return nil, fmt.Errorf("Not a blockchain snapshot")
+	}
+	zr, err := gzip.NewReader(in)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	decoder := gob.NewDecoder(zr)
+	var header snapshotHeader
+	if err = decoder.Decode(&header); err != nil {
+		return nil, err
+	}
+	if header.Version != snapshotVersion {
+		return nil, fmt.Errorf("Unsupported snapshot version: %d", header.Version)
+	}
+
+	tempPath, err := os.MkdirTemp(filepath.Dir(filepath.Clean(dbPath)), "."+filepath.Base(dbPath)+".restore*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tempPath)
+
+	if err = _loadSnapshot(tempPath, decoder, header.LastHash, &options); err != nil {
+		return nil, err
+	}
+	if err = os.Rename(tempPath, dbPath); err != nil {
+		return nil, err
+	}
+
+	return _openRestoredBlockChain(dbPath)
+}
+
+// Write the snapshot entries into a new database at dbPath and
+// validate the chain. The database is closed when it returns.
+func _loadSnapshot(dbPath string, decoder *gob.Decoder, lastHash []byte, options *SnapshotOptions) error {
+	opts := badger.DefaultOptions(dbPath)
+	opts.Dir = dbPath
+	opts.ValueDir = dbPath
+
+	db, err := badger.Open(opts)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	count, err := _loadSnapshotEntries(db, decoder)
+	if err != nil {
+		return err
+	}
+
+	return db.View(func(txn *badger.Txn) error {
+		params, err := _readChainParams(txn)
+		if err != nil {
+			return err
+		}
+		if options.Params != nil && *options.Params != *params {
+			return fmt.Errorf("Snapshot is of the %s network, not %s", params.Name, options.Params.Name)
+		}
+
+		item, err := txn.Get(lastHashKey)
+		if err != nil {
+			return fmt.Errorf("Snapshot has no last hash: %v", err)
+		}
+		tip, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(tip, lastHash) {
+			return fmt.Errorf("Snapshot last hash does not match its header")
+		}
+
+		genesisHash, err := _validateChain(txn, lastHash, count, params.Difficulty)
+		if err != nil {
+			return err
+		}
+		if item, err = txn.Get(genesisHashKey); err == nil {
+			stored, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(stored, genesisHash) {
+				return fmt.Errorf("Snapshot genesis hash %x does not match its genesis block %x", stored, genesisHash)
+			}
+		} else if err != badger.ErrKeyNotFound {
+			return err
+		}
+		if options.GenesisHash != nil && !bytes.Equal(options.GenesisHash, genesisHash) {
+			return fmt.Errorf("Genesis block %x does not match the expected %x", genesisHash, options.GenesisHash)
+		}
+
+		return nil
+	})
+}
+
+// Write the snapshot entries into the database. Returns the number of
+// entries.
+func _loadSnapshotEntries(db *badger.DB, decoder *gob.Decoder) (int, error) {
+	wb := db.NewWriteBatch()
+	defer wb.Cancel()
+
+	count := 0
+	for {
+		var entry snapshotEntry
+		if err := decoder.Decode(&entry); err == io.EOF {
+			break
+		} else if err != nil {
+			return 0, err
+		}
+		if err := wb.Set(entry.Key, entry.Value); err != nil {
+			return 0, err
+		}
+		count++
+	}
+
+	return count, wb.Flush()
+}
+
+// Open a database restored from a snapshot
+func _openRestoredBlockChain(dbPath string) (*BlockChain, error) {
+	opts := badger.DefaultOptions(dbPath)
+	opts.Dir = dbPath
+	opts.ValueDir = dbPath
+
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	chain := BlockChain{Database: db}
+	err = db.View(func(txn *badger.Txn) error {
+		if chain.Params, err = _readChainParams(txn); err != nil {
+			return err
+		}
+		item, err := txn.Get(lastHashKey)
+		if err != nil {
+			return err
+		}
+		chain.LastHash, err = item.ValueCopy(nil)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &chain, nil
+}
+
+// Walk the chain from the given hash down to the genesis block and
+// check that every block is present, intact and has a valid proof of
+// work of at least the difficulty of the network. The genesis block may
+// be mined with the difficulty of its spec, it is identified by its hash
+// instead, see WithSnapshotGenesisHash. Returns the hash of the genesis
+// block. maxBlocks guards against cycles.
+func _validateChain(txn *badger.Txn, lastHash []byte, maxBlocks int, difficulty int) ([]byte, error) {
+	hash := lastHash
+
+	for i := 0; i < maxBlocks; i++ {
+		item, err := txn.Get(hash)
+		if err != nil {
+			return nil, fmt.Errorf("Block %x is missing: %v", hash, err)
+		}
+
+		var block Block
+		err = item.Value(func(val []byte) error {
+			return gob.NewDecoder(bytes.NewReader(val)).Decode(&block)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Block %x cannot be decoded: %v", hash, err)
+		}
+		if !bytes.Equal(block.Hash, hash) || !NewProof(&block).Validate() {
+			return nil, fmt.Errorf("Block %x is not valid", hash)
+		}
+
+		if len(block.PrevHash) == 0 {
+			return hash, nil
+		}
+		if _blockDifficulty(&block) < difficulty {
+			return nil, fmt.Errorf("Block %x is mined with difficulty %d, below %d", hash, _blockDifficulty(&block), difficulty)
+		}
+		hash = block.PrevHash
+	}
+
+	return nil, fmt.Errorf("Chain does not end with a genesis block")
+}
@@ -0,0 +1,213 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package blockchain
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dgraph-io/badger"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestBackupRestoreBlockChain backs up a chain and restores it
+// into a new database.
+func TestBackupRestoreBlockChain(t *testing.T) {
+	cases := map[string]struct {
+		optFns []SnapshotOptionsFunc
+	}{
+		"plain snapshot": {},
+		"encrypted snapshot": {
+			optFns: []SnapshotOptionsFunc{WithSnapshotPassphrase("iamthebest")},
+		},
+	}
+
+	dbPath := filepath.Join("testdata", "db", "backup")
+	os.MkdirAll(dbPath, 0755)
+	defer _cleanTestBadgerDatabase(dbPath)
+
+	chain := InitBlockChain(dbPath, "John")
+	defer chain.Database.Close()
+	tx, _ := NewTransaction("John", "Jane", 20, chain)
+	chain.AddBlock([]*Transaction{tx})
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			var snapshot bytes.Buffer
+			err := chain.Backup(&snapshot, c.optFns...)
+			assert.NoError(t, err, "Backup() = %v", err)
+
+			restorePath := filepath.Join("testdata", "db", "restore")
+			defer _cleanTestBadgerDatabase(restorePath)
+
+			restored, err := RestoreBlockChain(restorePath, &snapshot, c.optFns...)
+			assert.NoError(t, err, "RestoreBlockChain(%v) = %v", restorePath, err)
+			defer restored.Database.Close()
+
+			assert.Equal(t, chain.LastHash, restored.LastHash)
+			assert.Equal(t, 80, restored.Balance("John"), "Balance of John should be 80")
+			assert.Equal(t, 20, restored.Balance("Jane"), "Balance of Jane should be 20")
+		})
+	}
+}
+
+// TestRestoreBlockChainInvalid calls RestoreBlockChain to make sure
+// errors are returned for snapshots that cannot be restored.
+func TestRestoreBlockChainInvalid(t *testing.T) {
+	dbPath := filepath.Join("testdata", "db", "backup")
+	os.MkdirAll(dbPath, 0755)
+	defer _cleanTestBadgerDatabase(dbPath)
+
+	chain := InitBlockChain(dbPath, "John")
+	defer chain.Database.Close()
+
+	var plain, encrypted bytes.Buffer
+	assert.NoError(t, chain.Backup(&plain))
+	assert.NoError(t, chain.Backup(&encrypted, WithSnapshotPassphrase("iamthebest")))
+
+	cases := map[string]struct {
+		dbPath   string
+		snapshot []byte
+		optFns   []SnapshotOptionsFunc
+	}{
+		"existing database": {
+			dbPath:   dbPath,
+			snapshot: plain.Bytes(),
+		},
+		"not a snapshot": {
+			dbPath:   filepath.Join("testdata", "db", "restore"),
+			snapshot: []byte("The quick brown fox jumps over the lazy dog"),
+		},
+		"truncated snapshot": {
+			dbPath:   filepath.Join("testdata", "db", "restore"),
+			snapshot: plain.Bytes()[:plain.Len()/2],
+		},
+		"truncated encrypted snapshot": {
+			dbPath:   filepath.Join("testdata", "db", "restore"),
+			snapshot: encrypted.Bytes()[:encrypted.Len()-1],
+			optFns:   []SnapshotOptionsFunc{WithSnapshotPassphrase("iamthebest")},
+		},
+		"wrong passphrase": {
+			dbPath:   filepath.Join("testdata", "db", "restore"),
+			snapshot: encrypted.Bytes(),
+			optFns:   []SnapshotOptionsFunc{WithSnapshotPassphrase("notthebest")},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := RestoreBlockChain(c.dbPath, bytes.NewReader(c.snapshot), c.optFns...)
+			assert.Error(t, err, "RestoreBlockChain(%v) = %v, want error", c.dbPath, err)
+		})
+	}
+	assert.False(t, DBExists(filepath.Join("testdata", "db", "restore")), "Invalid snapshot should be removed")
+}
+
+// TestRestoreBlockChainForged checks that blocks mined below the
+// difficulty of the network are rejected and that nothing at the
+// target path is removed.
+func TestRestoreBlockChainForged(t *testing.T) {
+	dbPath := filepath.Join("testdata", "db", "backup")
+	os.MkdirAll(dbPath, 0755)
+	defer _cleanTestBadgerDatabase(dbPath)
+
+	chain := InitBlockChain(dbPath, "John")
+	defer chain.Database.Close()
+	var valid bytes.Buffer
+	assert.NoError(t, chain.Backup(&valid))
+
+	// A block that claims the lowest difficulty is cheap to forge
+	forged := CreateBlock([]*Transaction{CoinbaseTx("Mallory", "", 1000)}, chain.LastHash, 1)
+	err := chain.Database.Update(func(txn *badger.Txn) error {
+		if err := txn.Set(forged.Hash, forged.Serialize()); err != nil {
+			return err
+		}
+		return txn.Set(lastHashKey, forged.Hash)
+	})
+	assert.NoError(t, err)
+	var snapshot bytes.Buffer
+	assert.NoError(t, chain.Backup(&snapshot))
+
+	restorePath := filepath.Join("testdata", "db", "restore")
+	defer _cleanTestBadgerDatabase(restorePath)
+	_, err = RestoreBlockChain(restorePath, &snapshot)
+	assert.ErrorContains(t, err, "below 12")
+	assert.False(t, DBExists(restorePath))
+
+	_, err = RestoreBlockChain(restorePath, bytes.NewReader(valid.Bytes()), WithSnapshotChainParams(&TestNetParams))
+	assert.ErrorContains(t, err, "not test")
+
+	// A directory that is not empty is left as it is
+	os.MkdirAll(restorePath, 0755)
+	keep := filepath.Join(restorePath, "keep.txt")
+	assert.NoError(t, os.WriteFile(keep, []byte("keep"), 0600))
+	_, err = RestoreBlockChain(restorePath, bytes.NewReader(valid.Bytes()))
+	assert.Error(t, err)
+	_, err = os.Stat(keep)
+	assert.NoError(t, err)
+
+	entries, err := os.ReadDir(filepath.Join("testdata", "db"))
+	assert.NoError(t, err)
+	for _, entry := range entries {
+		assert.NotContains(t, entry.Name(), ".restore", "Temporary directory should be removed")
+	}
+}
+
+// TestRestoreBlockChainGenesis checks that a snapshot of another chain
+// of the same network is rejected when the genesis hash is pinned.
+func TestRestoreBlockChainGenesis(t *testing.T) {
+	dbPath := filepath.Join("testdata", "db", "backup")
+	os.MkdirAll(dbPath, 0755)
+	defer _cleanTestBadgerDatabase(dbPath)
+
+	chain := InitBlockChain(dbPath, "John")
+	genesisHash := chain.GenesisHash()
+	var snapshot bytes.Buffer
+	assert.NoError(t, chain.Backup(&snapshot))
+	chain.Database.Close()
+	_cleanTestBadgerDatabase(dbPath)
+
+	// A chain that is valid on its own but starts with another genesis block
+	os.MkdirAll(dbPath, 0755)
+	other := InitBlockChain(dbPath, "Mallory")
+	var otherSnapshot bytes.Buffer
+	assert.NoError(t, other.Backup(&otherSnapshot))
+	other.Database.Close()
+
+	restorePath := filepath.Join("testdata", "db", "restore")
+	defer _cleanTestBadgerDatabase(restorePath)
+
+	_, err := RestoreBlockChain(restorePath, &otherSnapshot, WithSnapshotGenesisHash(genesisHash))
+	assert.ErrorContains(t, err, "does not match the expected")
+	assert.False(t, DBExists(restorePath))
+
+	restored, err := RestoreBlockChain(restorePath, &snapshot, WithSnapshotGenesisHash(genesisHash))
+	assert.NoError(t, err, "RestoreBlockChain(%v) = %v", restorePath, err)
+	defer restored.Database.Close()
+	assert.Equal(t, genesisHash, restored.GenesisHash())
+
+	_, err = RestoreBlockChain(restorePath, &snapshot, WithSnapshotGenesisHash(nil))
+	assert.ErrorContains(t, err, "Genesis hash is empty")
+}
@@ -32,6 +32,35 @@ import (
 	"github.com/tchiunam/axolgo-lib/util"
 )
 
+// TransactionOptionsFunc is a type alias for TransactionOptions functional option
+type TransactionOptionsFunc func(*TransactionOptions) error
+
+// TransactionOptions are discrete set of options that are valid for
+// creating a new transaction.
+type TransactionOptions struct {
+	CoinSelector CoinSelector
+}
+
+// WithCoinSelector is a helper function to construct functional options
+// that sets the strategy for choosing the outputs to spend.
+func WithCoinSelector(selector CoinSelector) TransactionOptionsFunc {
+	return func(o *TransactionOptions) error {
+		o.CoinSelector = selector
+		return nil
+	}
+}
+
+// Evaluate the functional options and set the options in the TransactionOptions struct
+func (options *TransactionOptions) Merge(optFns ...TransactionOptionsFunc) error {
+	for _, optFn := range optFns {
+		if err := optFn(options); err != nil {
+			return fmt.Errorf("Fail to read transaction options: %v", err)
+		}
+	}
+
+	return nil
+}
+
 // A blockchain transaction
 type Transaction struct {
 	ID      []byte
@@ -65,28 +94,40 @@ func (tx *Transaction) SetID() {
 	tx.ID = hash[:]
 }
 
-// Make a transaction to the given address
-func CoinbaseTx(to, data string) *Transaction {
+// Make a transaction that rewards the given address
+func CoinbaseTx(to, data string, reward int) *Transaction {
 	if data == "" {
 		data = fmt.Sprintf("Coinbase transaction to %s", to)
 	}
 
 	txin := TXInput{[]byte{}, -1, data}
-	txout := TXOutput{100, to}
+	txout := TXOutput{reward, to}
 	tx := Transaction{nil, []TXInput{txin}, []TXOutput{txout}}
 	tx.SetID()
 
 	return &tx
 }
 
-func NewTransaction(from string, to string, amount int, chain *BlockChain) (*Transaction, error) {
+// Make a transaction that sends the amount from one address to another.
+// Outputs to spend are chosen by LargestFirstSelector unless another
+// CoinSelector is given with WithCoinSelector.
+func NewTransaction(
+	from string,
+	to string,
+	amount int,
+	chain *BlockChain,
+	optFns ...TransactionOptionsFunc) (*Transaction, error) {
 	var inputs []TXInput
 	var outputs []TXOutput
 
-	acc, validOutputs := chain.FindSpendableOutputs(from, amount)
+	options := TransactionOptions{CoinSelector: LargestFirstSelector{}}
+	if err := options.Merge(optFns...); err != nil {
+		return nil, err
+	}
 
-	if acc < amount {
-		return nil, fmt.Errorf("Not enough funds to make a transaction")
+	acc, validOutputs, err := chain.SelectSpendableOutputs(from, amount, options.CoinSelector)
+	if err != nil {
+		return nil, err
 	}
 
 	for txid, outs := range validOutputs {
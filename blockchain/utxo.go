@@ -0,0 +1,286 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package blockchain
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+
+	"github.com/dgraph-io/badger"
+	"github.com/tchiunam/axolgo-lib/util"
+)
+
+// Prefix of the keys of the unspent transaction output index
+var utxoPrefix = []byte("utxo-")
+
+// Key that marks the unspent transaction output index as current
+var utxoIndexKey = []byte("utxoindex")
+
+// ErrNoUTXOIndex is returned when the index has not been built or was
+// invalidated by RecoverLastHash
+var ErrNoUTXOIndex = errors.New("The UTXO index is not built")
+
+// UTXOSet is an index of the unspent transaction outputs of a chain.
+// It answers balance queries without walking every block. The index
+// is built by Reindex. After that AddBlock updates it in the same
+// transaction as the block.
+type UTXOSet struct {
+	Chain *BlockChain
+}
+
+// Find the unspent outputs of every address, grouped by the hex
+// encoded ID of their transaction
+func (chain *BlockChain) FindAllUnspentOutputs() map[string][]UnspentOutput {
+	unspentOutputs := make(map[string][]UnspentOutput)
+	spentTxOutputs := make(map[string]map[int]bool)
+	iter := chain.Iterator()
+
+	for {
+		block := iter.Next()
+
+		for _, tx := range block.Transactions {
+			txID := hex.EncodeToString(tx.ID)
+
+			for outIdx, out := range tx.Outputs {
+				if !spentTxOutputs[txID][outIdx] {
+					unspentOutputs[txID] = append(unspentOutputs[txID], UnspentOutput{tx.ID, outIdx, out})
+				}
+			}
+			if tx.IsCoinbase() == false {
+				for _, in := range tx.Inputs {
+					inTxID := hex.EncodeToString(in.ID)
+					if spentTxOutputs[inTxID] == nil {
+						spentTxOutputs[inTxID] = make(map[int]bool)
+					}
+					spentTxOutputs[inTxID][in.Out] = true
+				}
+			}
+		}
+
+		if len(block.PrevHash) == 0 {
+			break
+		}
+	}
+
+	return unspentOutputs
+}
+
+// Serialize unspent outputs into bytes
+func _serializeUnspentOutputs(utxos []UnspentOutput) []byte {
+	var res bytes.Buffer
+	encoder := gob.NewEncoder(&res)
+
+	err := encoder.Encode(utxos)
+	util.PanicOnError(err)
+
+	return res.Bytes()
+}
+
+// Deserialize unspent outputs from bytes
+func _deserializeUnspentOutputs(data []byte) ([]UnspentOutput, error) {
+	var utxos []UnspentOutput
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&utxos)
+
+	return utxos, err
+}
+
+// Reindex rebuilds the index from the blocks of the chain
+func (u UTXOSet) Reindex() error {
+	var keys [][]byte
+
+	err := u.Chain.Database.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(utxoPrefix); it.ValidForPrefix(utxoPrefix); it.Next() {
+			keys = append(keys, it.Item().KeyCopy(nil))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	wb := u.Chain.Database.NewWriteBatch()
+	defer wb.Cancel()
+
+	for _, key := range keys {
+		if err = wb.Delete(key); err != nil {
+			return err
+		}
+	}
+	for _, utxos := range u.Chain.FindAllUnspentOutputs() {
+		key := append(append([]byte{}, utxoPrefix...), utxos[0].TxID...)
+		if err = wb.Set(key, _serializeUnspentOutputs(utxos)); err != nil {
+			return err
+		}
+	}
+	if err = wb.Set(utxoIndexKey, []byte{1}); err != nil {
+		return err
+	}
+
+	return wb.Flush()
+}
+
+// Tell whether the index has been built and is current
+func (u UTXOSet) Indexed() (bool, error) {
+	indexed := false
+	err := u.Chain.Database.View(func(txn *badger.Txn) (err error) {
+		indexed, err = _utxoIndexed(txn)
+		return err
+	})
+
+	return indexed, err
+}
+
+// Tell whether the index is current within a transaction
+func _utxoIndexed(txn *badger.Txn) (bool, error) {
+	_, err := txn.Get(utxoIndexKey)
+	if err == badger.ErrKeyNotFound {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// Update the index with a block that is added to the chain in the same
+// transaction. Outputs spent by the block are removed and its new
+// outputs are added.
+func _updateUTXOIndex(txn *badger.Txn, block *Block) error {
+	for _, tx := range block.Transactions {
+		if tx.IsCoinbase() == false {
+			for _, in := range tx.Inputs {
+				key := append(append([]byte{}, utxoPrefix...), in.ID...)
+				item, err := txn.Get(key)
+				if err == badger.ErrKeyNotFound {
+					continue
+				} else if err != nil {
+					return err
+				}
+				value, err := item.ValueCopy(nil)
+				if err != nil {
+					return err
+				}
+				utxos, err := _deserializeUnspentOutputs(value)
+				if err != nil {
+					return err
+				}
+
+				var remaining []UnspentOutput
+				for _, utxo := range utxos {
+					if utxo.Index != in.Out {
+						remaining = append(remaining, utxo)
+					}
+				}
+
+				if len(remaining) == 0 {
+					err = txn.Delete(key)
+				} else {
+					err = txn.Set(key, _serializeUnspentOutputs(remaining))
+				}
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		var utxos []UnspentOutput
+		for outIdx, out := range tx.Outputs {
+			utxos = append(utxos, UnspentOutput{tx.ID, outIdx, out})
+		}
+		key := append(append([]byte{}, utxoPrefix...), tx.ID...)
+		if err := txn.Set(key, _serializeUnspentOutputs(utxos)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Iterate over the unspent outputs in the index. Returns ErrNoUTXOIndex
+// if the index is not current.
+func (u UTXOSet) forEach(fn func(utxos []UnspentOutput)) error {
+	return u.Chain.Database.View(func(txn *badger.Txn) error {
+		indexed, err := _utxoIndexed(txn)
+		if err != nil {
+			return err
+		}
+		if !indexed {
+			return ErrNoUTXOIndex
+		}
+
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for it.Seek(utxoPrefix); it.ValidForPrefix(utxoPrefix); it.Next() {
+			value, err := it.Item().ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			utxos, err := _deserializeUnspentOutputs(value)
+			if err != nil {
+				return err
+			}
+			fn(utxos)
+		}
+
+		return nil
+	})
+}
+
+// FindUnspentOutputs returns the unspent outputs of an address
+func (u UTXOSet) FindUnspentOutputs(address string) ([]UnspentOutput, error) {
+	var unspentOutputs []UnspentOutput
+
+	err := u.forEach(func(utxos []UnspentOutput) {
+		for _, utxo := range utxos {
+			if utxo.Output.CanBeUnlocked(address) {
+				unspentOutputs = append(unspentOutputs, utxo)
+			}
+		}
+	})
+
+	return unspentOutputs, err
+}
+
+// Balance returns the total value of the unspent outputs of an address
+func (u UTXOSet) Balance(address string) (int, error) {
+	utxos, err := u.FindUnspentOutputs(address)
+
+	return sumUnspentOutputs(utxos), err
+}
+
+// CountTransactions returns the number of transactions with unspent outputs
+func (u UTXOSet) CountTransactions() (int, error) {
+	count := 0
+	err := u.forEach(func(utxos []UnspentOutput) {
+		count++
+	})
+
+	return count, err
+}
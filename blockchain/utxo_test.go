@@ -0,0 +1,113 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package blockchain
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dgraph-io/badger"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestUTXOSet reindexes a chain and checks that AddBlock keeps the
+// index current.
+func TestUTXOSet(t *testing.T) {
+	dbPath := filepath.Join("testdata", "db", "utxo")
+	os.MkdirAll(dbPath, 0755)
+	defer _cleanTestBadgerDatabase(dbPath)
+
+	chain := InitBlockChain(dbPath, "John", WithChainParams(&RegTestParams))
+	defer chain.Close()
+	tx, _ := NewTransaction("John", "Jane", 20, chain)
+	chain.AddBlock([]*Transaction{tx})
+
+	utxoSet := UTXOSet{chain}
+	_, err := utxoSet.Balance("John")
+	assert.ErrorIs(t, err, ErrNoUTXOIndex, "Balance() before Reindex() should return ErrNoUTXOIndex")
+
+	assert.NoError(t, utxoSet.Reindex(), "Reindex() should not return error")
+	// Reindexing twice must not leave stale entries behind
+	assert.NoError(t, utxoSet.Reindex(), "Reindex() should not return error")
+
+	count, err := utxoSet.CountTransactions()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count, "Only the last transaction has unspent outputs")
+
+	tx, _ = NewTransaction("Jane", "Mary", 5, chain)
+	chain.AddBlock([]*Transaction{tx})
+
+	for address, expect := range map[string]int{"John": 80, "Jane": 15, "Mary": 5, "Nancy": 0} {
+		balance, err := utxoSet.Balance(address)
+		assert.NoError(t, err)
+		assert.Equal(t, expect, balance, "Balance of %v should be %v", address, expect)
+		assert.Equal(t, chain.Balance(address), balance, "Index and chain should agree on %v", address)
+	}
+
+	count, err = utxoSet.CountTransactions()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+// TestUTXOSetRecoverLastHash checks that a rollback of the last hash
+// invalidates the index until it is rebuilt
+func TestUTXOSetRecoverLastHash(t *testing.T) {
+	dbPath := filepath.Join("testdata", "db", "utxorecover")
+	os.MkdirAll(dbPath, 0755)
+	defer _cleanTestBadgerDatabase(dbPath)
+
+	chain := InitBlockChain(dbPath, "John", WithChainParams(&RegTestParams))
+	defer chain.Close()
+	utxoSet := UTXOSet{chain}
+	assert.NoError(t, utxoSet.Reindex(), "Reindex() should not return error")
+
+	tx, _ := NewTransaction("John", "Jane", 20, chain)
+	block := chain.AddBlock([]*Transaction{tx})
+	balance, err := utxoSet.Balance("Jane")
+	assert.NoError(t, err)
+	assert.Equal(t, 20, balance)
+
+	// Break the tip so that it is rolled back to the genesis block
+	err = chain.Database.Update(func(txn *badger.Txn) error {
+		return txn.Delete(block.Hash)
+	})
+	assert.NoError(t, err)
+	changed, err := chain.RecoverLastHash()
+	assert.NoError(t, err)
+	assert.True(t, changed, "RecoverLastHash() should roll back the last hash")
+
+	indexed, err := utxoSet.Indexed()
+	assert.NoError(t, err)
+	assert.False(t, indexed, "The index should be invalidated by the rollback")
+	_, err = utxoSet.Balance("Jane")
+	assert.ErrorIs(t, err, ErrNoUTXOIndex)
+
+	assert.NoError(t, utxoSet.Reindex(), "Reindex() should not return error")
+	for address, expect := range map[string]int{"John": 100, "Jane": 0} {
+		balance, err := utxoSet.Balance(address)
+		assert.NoError(t, err)
+		assert.Equal(t, expect, balance, "Balance of %v should be %v", address, expect)
+		assert.Equal(t, chain.Balance(address), balance, "Index and chain should agree on %v", address)
+	}
+}
@@ -24,31 +24,34 @@ package blockchain
 
 import (
 	"bytes"
-	"crypto/ecdsa"
+	cecdsa "crypto/ecdsa"
 	"crypto/elliptic"
-	"crypto/rand"
 	"crypto/sha256"
+	"encoding/gob"
 
+	"github.com/tchiunam/axolgo-lib/cryptography"
+	"github.com/tchiunam/axolgo-lib/cryptography/ecdsa"
 	"github.com/tchiunam/axolgo-lib/util"
 	"golang.org/x/crypto/ripemd160"
 )
 
 const (
-	checksumLength = 4          // 4 bytes
-	version        = byte(0x00) // 1 byte
+	checksumLength = 4 // 4 bytes
+	versionLength  = 1 // 1 byte
 )
 
 // Wallet represents a wallet in the blockchain
 type Wallet struct {
-	PrivateKey ecdsa.PrivateKey
+	PrivateKey cecdsa.PrivateKey
 	PublicKey  []byte
 }
 
-// Address gets the address of the wallet
-func (w Wallet) Address() []byte {
+// Address gets the address of the wallet on the network
+// given by params
+func (w Wallet) Address(params *ChainParams) []byte {
 	pubKeyHash := PublicKeyHash(w.PublicKey)
 
-	versionedHash := append([]byte{version}, pubKeyHash...)
+	versionedHash := append([]byte{params.AddressVersion}, pubKeyHash...)
 	checksum := Checksum(versionedHash)
 
 	fullHash := append(versionedHash, checksum...)
@@ -57,26 +60,67 @@ func (w Wallet) Address() []byte {
 	return address
 }
 
-// ValidateAddress checks if the address is valid
-func ValidateAddress(address string) bool {
+// ValidateAddress checks if the address is valid on the network
+// given by params. Addresses of other networks are rejected.
+func ValidateAddress(address string, params *ChainParams) bool {
 	pubKeyHash, err := util.Base58Decode([]byte(address))
-	if err != nil {
+	if err != nil || len(pubKeyHash) <= versionLength+checksumLength {
 		return false
 	}
 
 	actualChecksum := pubKeyHash[len(pubKeyHash)-checksumLength:]
 	version := pubKeyHash[0]
-	pubKeyHash = pubKeyHash[1 : len(pubKeyHash)-checksumLength]
+	if version != params.AddressVersion {
+		return false
+	}
+	pubKeyHash = pubKeyHash[versionLength : len(pubKeyHash)-checksumLength]
 	targetChecksum := Checksum(append([]byte{version}, pubKeyHash...))
 
 	return bytes.Compare(actualChecksum, targetChecksum) == 0
 }
 
-// NewKeyPair generates a public and private key pair
-func NewKeyPair() (ecdsa.PrivateKey, []byte) {
-	curve := elliptic.P256()
+// Serialized form of a wallet. The private key is stored as SEC 1 DER
+// because the curve of an ecdsa.PrivateKey cannot be encoded by gob.
+type walletData struct {
+	PrivateKey []byte
+	PublicKey  []byte
+}
+
+// GobEncode serializes the wallet for gob
+func (w Wallet) GobEncode() ([]byte, error) {
+	der, err := ecdsa.MarshalPrivateKeyDER(&w.PrivateKey, cryptography.WithPrivateKeyFormat(cryptography.KeyFormatSEC1))
+	if err != nil {
+		return nil, err
+	}
+
+	var content bytes.Buffer
+	if err = gob.NewEncoder(&content).Encode(walletData{der, w.PublicKey}); err != nil {
+		return nil, err
+	}
 
-	private, err := ecdsa.GenerateKey(curve, rand.Reader)
+	return content.Bytes(), nil
+}
+
+// GobDecode deserializes a wallet encoded by GobEncode
+func (w *Wallet) GobDecode(data []byte) error {
+	var wd walletData
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&wd); err != nil {
+		return err
+	}
+
+	privateKey, err := ecdsa.ParsePrivateKey(wd.PrivateKey)
+	if err != nil {
+		return err
+	}
+	w.PrivateKey = *privateKey
+	w.PublicKey = wd.PublicKey
+
+	return nil
+}
+
+// NewKeyPair generates a public and private key pair
+func NewKeyPair() (cecdsa.PrivateKey, []byte) {
+	private, _, err := ecdsa.GenerateECDSAKeyPair(elliptic.P256())
 	if err != nil {
 		util.PanicOnError(err)
 	}
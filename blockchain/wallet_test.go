@@ -36,8 +36,77 @@ func TestWallet(t *testing.T) {
 	assert.NotNil(t, wallet.PrivateKey)
 	assert.NotNil(t, wallet.PublicKey)
 
-	address := wallet.Address()
+	address := wallet.Address(&MainNetParams)
 
 	assert.NotNil(t, address)
-	assert.True(t, ValidateAddress(string(address)))
+	assert.True(t, ValidateAddress(string(address), &MainNetParams))
+}
+
+// TestValidateAddressNetworks checks that an address is only valid on
+// the network it is created for.
+func TestValidateAddressNetworks(t *testing.T) {
+	wallet := MakeWallet()
+
+	cases := map[string]struct {
+		addressParams  *ChainParams
+		validateParams *ChainParams
+		expectValid    bool
+	}{
+		"main on main": {
+			addressParams:  &MainNetParams,
+			validateParams: &MainNetParams,
+			expectValid:    true,
+		},
+		"test on test": {
+			addressParams:  &TestNetParams,
+			validateParams: &TestNetParams,
+			expectValid:    true,
+		},
+		"regtest on regtest": {
+			addressParams:  &RegTestParams,
+			validateParams: &RegTestParams,
+			expectValid:    true,
+		},
+		"test on main": {
+			addressParams:  &TestNetParams,
+			validateParams: &MainNetParams,
+			expectValid:    false,
+		},
+		"main on regtest": {
+			addressParams:  &MainNetParams,
+			validateParams: &RegTestParams,
+			expectValid:    false,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			address := string(wallet.Address(c.addressParams))
+			actual := ValidateAddress(address, c.validateParams)
+			assert.Equal(t, c.expectValid, actual, "ValidateAddress(%v, %v) = %v, want %v", address, c.validateParams.Name, actual, c.expectValid)
+		})
+	}
+}
+
+// TestValidateAddressInvalid calls ValidateAddress with malformed addresses
+func TestValidateAddressInvalid(t *testing.T) {
+	cases := map[string]struct {
+		address string
+	}{
+		"empty address": {
+			address: "",
+		},
+		"too short": {
+			address: "1a",
+		},
+		"not base58": {
+			address: "0OIl",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.False(t, ValidateAddress(c.address, &MainNetParams), "ValidateAddress(%v) should be false", c.address)
+		})
+	}
 }
@@ -24,11 +24,11 @@ package blockchain
 
 import (
 	"bytes"
-	"crypto/elliptic"
 	"encoding/gob"
 	"fmt"
-	"io/ioutil"
 	"os"
+
+	"github.com/tchiunam/axolgo-lib/util"
 )
 
 // Wallets represents a collection of wallets
@@ -46,24 +46,18 @@ func CreateWallets(filePath string) (*Wallets, error) {
 	return &wallets, err
 }
 
-// Save the wallets to a file
+// Save the wallets to a file. The file holds private keys so only the
+// owner can read it, even if it existed with a wider mode before.
 func (ws *Wallets) Persist(filePath string) error {
 	var content bytes.Buffer
 
-	gob.Register(elliptic.P256())
-
 	encoder := gob.NewEncoder(&content)
 	err := encoder.Encode(ws)
 	if err != nil {
 		return err
 	}
 
-	err = ioutil.WriteFile(filePath, content.Bytes(), 0644)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return util.WriteFileAtomic(filePath, content.Bytes(), 0600)
 }
 
 // Load the wallets from a file
@@ -75,7 +69,9 @@ func (ws *Wallets) LoadFromFile(filePath string) error {
 	var wallets Wallets
 
 	content, err := os.ReadFile(filePath)
-	gob.Register(elliptic.P256())
+	if err != nil {
+		return err
+	}
 	decoder := gob.NewDecoder(bytes.NewReader(content))
 	err = decoder.Decode(&wallets)
 	if err != nil {
@@ -87,10 +83,11 @@ func (ws *Wallets) LoadFromFile(filePath string) error {
 	return nil
 }
 
-// Add a wallet to the collection
-func (ws *Wallets) AddWallet() string {
+// Add a wallet to the collection. The wallet is indexed by its
+// address on the network given by params.
+func (ws *Wallets) AddWallet(params *ChainParams) string {
 	wallet := MakeWallet()
-	address := fmt.Sprintf("%s", wallet.Address())
+	address := fmt.Sprintf("%s", wallet.Address(params))
 
 	ws.Wallets[address] = wallet
 
@@ -112,3 +109,22 @@ func (ws *Wallets) GetAllAddresses() []string {
 
 	return addresses
 }
+
+// Balance summary of a collection of wallets
+type WalletsBalance struct {
+	Total     int
+	Addresses map[string]int
+}
+
+// Get the balance of every wallet address and the total across them
+func (ws *Wallets) Balance(chain *BlockChain) WalletsBalance {
+	balance := WalletsBalance{Addresses: make(map[string]int)}
+
+	for address := range ws.Wallets {
+		amount := chain.Balance(address)
+		balance.Addresses[address] = amount
+		balance.Total += amount
+	}
+
+	return balance
+}
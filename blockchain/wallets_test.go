@@ -36,7 +36,7 @@ func TestWallets(t *testing.T) {
 	wallets.Wallets = make(map[string]*Wallet)
 
 	for i := 0; i < 5; i++ {
-		address := wallets.AddWallet()
+		address := wallets.AddWallet(&MainNetParams)
 		assert.NotNil(t, address)
 	}
 
@@ -44,14 +44,51 @@ func TestWallets(t *testing.T) {
 	wallet := wallets.GetWallet(addresses[0])
 	assert.NotNil(t, wallet)
 
+	// An existing file readable by everyone is replaced
 	walletFilePath := filepath.Join("testdata", "wallets.dat")
-	wallets.Persist(walletFilePath)
+	assert.NoError(t, os.WriteFile(walletFilePath, nil, 0644))
+	err := wallets.Persist(walletFilePath)
 	defer os.Remove(walletFilePath)
+	assert.NoError(t, err)
+	info, err := os.Stat(walletFilePath)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "Wallets file should only be readable by the owner")
 
-	// Having a bug to be fixed here
-	// assert.Error(t, err)
+	walletsLoaded, err := CreateWallets(walletFilePath)
+	assert.NoError(t, err)
+	assert.NotNil(t, walletsLoaded)
+	assert.ElementsMatch(t, addresses, walletsLoaded.GetAllAddresses())
+	for _, address := range addresses {
+		loaded := walletsLoaded.GetWallet(address)
+		assert.Equal(t, address, string(loaded.Address(&MainNetParams)))
+	}
+	loaded := walletsLoaded.GetWallet(addresses[0])
+	assert.True(t, wallet.PrivateKey.Equal(&loaded.PrivateKey), "Private key should be restored")
+}
+
+// TestWalletsBalance sends coins between wallets and checks the
+// balance summary.
+func TestWalletsBalance(t *testing.T) {
+	dbPath := filepath.Join("testdata", "db", "wallets")
+	os.MkdirAll(dbPath, 0755)
+	defer _cleanTestBadgerDatabase(dbPath)
+
+	wallets := Wallets{Wallets: make(map[string]*Wallet)}
+	john := wallets.AddWallet(&RegTestParams)
+	jane := wallets.AddWallet(&RegTestParams)
+
+	chain := InitBlockChain(dbPath, john, WithChainParams(&RegTestParams))
+	defer chain.Database.Close()
+
+	tx, err := NewTransaction(john, jane, 30, chain, WithCoinSelector(BranchAndBoundSelector{Fallback: SmallestFirstSelector{}}))
+	assert.NoError(t, err, "NewTransaction(%v, %v, 30) = %v", john, jane, err)
+	chain.AddBlock([]*Transaction{tx})
+
+	balance := wallets.Balance(chain)
+	assert.Equal(t, 100, balance.Total, "Total balance should be 100")
+	assert.Equal(t, 70, balance.Addresses[john], "Balance of %v should be 70", john)
+	assert.Equal(t, 30, balance.Addresses[jane], "Balance of %v should be 30", jane)
 
-	CreateWallets(walletFilePath)
-	// assert.NoError(t, err)
-	// assert.NotNil(t, walletsLoaded)
+	_, err = NewTransaction(jane, john, 31, chain)
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
 }
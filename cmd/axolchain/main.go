@@ -0,0 +1,541 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+// Command axolchain manages a blockchain and its wallets from the
+// command line.
+//
+// Usage:
+//
+//	axolchain [-config file] [-db path] [-wallets file] [-network name] <command> [flags]
+//
+// Run "axolchain help" for the list of commands.
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/tchiunam/axolgo-lib/blockchain"
+	"github.com/tchiunam/axolgo-lib/io/ioutil"
+	"github.com/tchiunam/axolgo-lib/types"
+	"github.com/tchiunam/axolgo-lib/util"
+)
+
+// Default location of the blockchain database
+const defaultDBPath = "axolchain-data/blocks"
+
+// Default location of the wallets file
+const defaultWalletsFile = "axolchain-data/wallets.dat"
+
+const usage = `Usage: axolchain [global flags] <command> [flags]
+
+Global flags:
+  -config file     YAML or JSON config file with db_path, wallets_file, network and genesis
+  -db path         path of the blockchain database (default %s)
+  -wallets file    path of the wallets file (default %s)
+  -network name    network of the chain and addresses: main, test or regtest
+
+Commands:
+  createblockchain -address ADDRESS | -genesis SPEC   create a new blockchain
+  createwallet                                        create a new wallet
+  listaddresses                                       list the addresses of all wallets
+  getbalance [-address ADDRESS]...                    print balances, of all wallets by default
+  send -from FROM -to TO -amount AMOUNT [-selector S] send coins and mine a block
+                                                      S is largest, smallest, bnb or random
+  printchain                                          print every block of the chain
+  reindexutxo                                         rebuild the unspent transaction output index
+  verify [-genesis SPEC]                              verify every block of the chain
+`
+
+// Configuration shared by all commands
+type config struct {
+	DBPath      string `mapstructure:"db_path"`
+	WalletsFile string `mapstructure:"wallets_file"`
+	Network     string `mapstructure:"network"`
+	Genesis     string `mapstructure:"genesis"`
+}
+
+// A command of the command line tool
+type command struct {
+	cfg    *config
+	stdout io.Writer
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// Run the command line tool and return the exit code
+func run(args []string, stdout io.Writer, stderr io.Writer) (code int) {
+	// The blockchain package panics on fatal errors
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", r)
+			code = 1
+		}
+	}()
+
+	cfg, args, err := parseGlobalFlags(args, stderr)
+	if err != nil {
+		if err != flag.ErrHelp {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+		}
+		return 2
+	}
+	if len(args) == 0 || args[0] == "help" {
+		fmt.Fprintf(stderr, usage, defaultDBPath, defaultWalletsFile)
+		return 2
+	}
+
+	cmd := command{cfg, stdout}
+	commands := map[string]func([]string) error{
+		"createblockchain": cmd.createBlockChain,
+		"createwallet":     cmd.createWallet,
+		"listaddresses":    cmd.listAddresses,
+		"getbalance":       cmd.getBalance,
+		"send":             cmd.send,
+		"printchain":       cmd.printChain,
+		"reindexutxo":      cmd.reindexUTXO,
+		"verify":           cmd.verify,
+	}
+
+	fn, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "Error: unknown command %q\n", args[0])
+		fmt.Fprintf(stderr, usage, defaultDBPath, defaultWalletsFile)
+		return 2
+	}
+	if err = fn(args[1:]); err != nil {
+		if err != flag.ErrHelp {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+		}
+		return 1
+	}
+
+	return 0
+}
+
+// Parse the global flags and the config file. Flags take precedence
+// over the config file. Returns the config and the remaining arguments.
+func parseGlobalFlags(args []string, stderr io.Writer) (*config, []string, error) {
+	fs := flag.NewFlagSet("axolchain", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprintf(stderr, usage, defaultDBPath, defaultWalletsFile) }
+	configFile := fs.String("config", "", "config file")
+	dbPath := fs.String("db", "", "path of the blockchain database")
+	walletsFile := fs.String("wallets", "", "path of the wallets file")
+	network := fs.String("network", "", "network of the chain and addresses")
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	cfg := config{}
+	if *configFile != "" {
+		if _, err := ioutil.ReadConfigFile(util.ExpandPath(*configFile), ioutil.WithCFOClass(&cfg)); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, o := range []struct {
+		value  string
+		target *string
+	}{{*dbPath, &cfg.DBPath}, {*walletsFile, &cfg.WalletsFile}, {*network, &cfg.Network}} {
+		if o.value != "" {
+			*o.target = o.value
+		}
+	}
+	if cfg.DBPath == "" {
+		cfg.DBPath = defaultDBPath
+	}
+	if cfg.WalletsFile == "" {
+		cfg.WalletsFile = defaultWalletsFile
+	}
+	cfg.DBPath = util.ExpandPath(cfg.DBPath)
+	cfg.WalletsFile = util.ExpandPath(cfg.WalletsFile)
+	cfg.Genesis = util.ExpandPath(cfg.Genesis)
+
+	return &cfg, fs.Args(), nil
+}
+
+// Make a flag set for a command
+func (c *command) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stdout)
+
+	return fs
+}
+
+// Get the parameters of the configured network. The main network is
+// used if no network is configured.
+func (c *command) params() (*blockchain.ChainParams, error) {
+	if c.cfg.Network == "" {
+		return &blockchain.MainNetParams, nil
+	}
+
+	return blockchain.ChainParamsByName(c.cfg.Network)
+}
+
+// Open the existing blockchain. The network is checked if it is configured.
+func (c *command) openChain(optFns ...blockchain.BlockChainOptionsFunc) (*blockchain.BlockChain, error) {
+	if c.cfg.Network != "" {
+		params, err := c.params()
+		if err != nil {
+			return nil, err
+		}
+		optFns = append(optFns, blockchain.WithChainParams(params))
+	}
+
+	return blockchain.ContinueBlockChain(c.cfg.DBPath, optFns...), nil
+}
+
+// Load the wallets. A missing wallets file means there are no wallets yet.
+func (c *command) loadWallets() (*blockchain.Wallets, error) {
+	wallets, err := blockchain.CreateWallets(c.cfg.WalletsFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	return wallets, nil
+}
+
+// Read the genesis spec given by the flag or the config file
+func (c *command) genesisSpec(path string) (*blockchain.GenesisSpec, error) {
+	if path == "" {
+		path = c.cfg.Genesis
+	}
+	if path == "" {
+		return nil, nil
+	}
+
+	return blockchain.ReadGenesisSpec(util.ExpandPath(path))
+}
+
+// Create a new blockchain
+func (c *command) createBlockChain(args []string) error {
+	fs := c.flagSet("createblockchain")
+	address := fs.String("address", "", "address that receives the genesis reward")
+	genesis := fs.String("genesis", "", "genesis spec file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	params, err := c.params()
+	if err != nil {
+		return err
+	}
+	spec, err := c.genesisSpec(*genesis)
+	if err != nil {
+		return err
+	}
+
+	optFns := []blockchain.BlockChainOptionsFunc{blockchain.WithChainParams(params)}
+	if spec != nil {
+		optFns = append(optFns, blockchain.WithGenesisSpec(spec))
+	} else if !blockchain.ValidateAddress(*address, params) {
+		return fmt.Errorf("invalid %s address %q", params.Name, *address)
+	}
+
+	if err = os.MkdirAll(c.cfg.DBPath, 0755); err != nil {
+		return err
+	}
+	chain := blockchain.InitBlockChain(c.cfg.DBPath, *address, optFns...)
+	defer chain.Close()
+
+	if err = (blockchain.UTXOSet{Chain: chain}).Reindex(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stdout, "Created %s blockchain with genesis block %x\n", params.Name, chain.LastHash)
+	return nil
+}
+
+// Create a new wallet and save it to the wallets file
+func (c *command) createWallet(args []string) error {
+	fs := c.flagSet("createwallet")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	params, err := c.params()
+	if err != nil {
+		return err
+	}
+	wallets, err := c.loadWallets()
+	if err != nil {
+		return err
+	}
+
+	address := wallets.AddWallet(params)
+	if err = os.MkdirAll(filepath.Dir(c.cfg.WalletsFile), 0755); err != nil {
+		return err
+	}
+	if err = wallets.Persist(c.cfg.WalletsFile); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(c.stdout, address)
+	return nil
+}
+
+// List the addresses of all wallets
+func (c *command) listAddresses(args []string) error {
+	fs := c.flagSet("listaddresses")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	wallets, err := c.loadWallets()
+	if err != nil {
+		return err
+	}
+
+	addresses := wallets.GetAllAddresses()
+	sort.Strings(addresses)
+	for _, address := range addresses {
+		fmt.Fprintln(c.stdout, address)
+	}
+
+	return nil
+}
+
+// Print the balance of the given addresses or of all wallets
+func (c *command) getBalance(args []string) error {
+	var addresses types.StringArrayFlag
+
+	fs := c.flagSet("getbalance")
+	fs.Var(&addresses, "address", "address to get the balance of, can be repeated")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if len(addresses) == 0 {
+		wallets, err := c.loadWallets()
+		if err != nil {
+			return err
+		}
+		addresses = wallets.GetAllAddresses()
+		sort.Strings(addresses)
+	}
+
+	chain, err := c.openChain()
+	if err != nil {
+		return err
+	}
+	defer chain.Close()
+
+	// Fall back to walking the chain if the index has not been built
+	utxoSet := blockchain.UTXOSet{Chain: chain}
+	indexed, err := utxoSet.Indexed()
+	if err != nil {
+		return err
+	}
+
+	total := 0
+	for _, address := range addresses {
+		if !blockchain.ValidateAddress(address, chain.Params) {
+			return fmt.Errorf("invalid %s address %q", chain.Params.Name, address)
+		}
+		balance := 0
+		if indexed {
+			if balance, err = utxoSet.Balance(address); err != nil {
+				return err
+			}
+		} else {
+			balance = chain.Balance(address)
+		}
+		total += balance
+		fmt.Fprintf(c.stdout, "%s: %d\n", address, balance)
+	}
+	if len(addresses) > 1 {
+		fmt.Fprintf(c.stdout, "Total: %d\n", total)
+	}
+
+	return nil
+}
+
+// Get a coin selector by name
+func coinSelector(name string) (blockchain.CoinSelector, error) {
+	switch name {
+	case "largest":
+		return blockchain.LargestFirstSelector{}, nil
+	case "smallest":
+		return blockchain.SmallestFirstSelector{}, nil
+	case "bnb":
+		return blockchain.BranchAndBoundSelector{Fallback: blockchain.LargestFirstSelector{}}, nil
+	case "random":
+		return blockchain.RandomSelector{}, nil
+	}
+
+	return nil, fmt.Errorf("unknown coin selector %q", name)
+}
+
+// Send coins from a wallet to an address and mine a block
+func (c *command) send(args []string) error {
+	fs := c.flagSet("send")
+	from := fs.String("from", "", "wallet address to send from")
+	to := fs.String("to", "", "address to send to")
+	amount := fs.Int("amount", 0, "amount to send")
+	selectorName := fs.String("selector", "largest", "coin selection strategy")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *amount <= 0 {
+		return fmt.Errorf("invalid amount %d", *amount)
+	}
+	selector, err := coinSelector(*selectorName)
+	if err != nil {
+		return err
+	}
+	wallets, err := c.loadWallets()
+	if err != nil {
+		return err
+	}
+	if _, ok := wallets.Wallets[*from]; !ok {
+		return fmt.Errorf("wallet %q not found", *from)
+	}
+
+	chain, err := c.openChain()
+	if err != nil {
+		return err
+	}
+	defer chain.Close()
+
+	if !blockchain.ValidateAddress(*to, chain.Params) {
+		return fmt.Errorf("invalid %s address %q", chain.Params.Name, *to)
+	}
+
+	tx, err := blockchain.NewTransaction(*from, *to, *amount, chain, blockchain.WithCoinSelector(selector))
+	if err != nil {
+		return err
+	}
+	chain.AddBlock([]*blockchain.Transaction{tx})
+
+	fmt.Fprintf(c.stdout, "Sent %d from %s to %s in transaction %x\n", *amount, *from, *to, tx.ID)
+	return nil
+}
+
+// Print every block of the chain from the last one
+func (c *command) printChain(args []string) error {
+	fs := c.flagSet("printchain")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	chain, err := c.openChain()
+	if err != nil {
+		return err
+	}
+	defer chain.Close()
+
+	iter := chain.Iterator()
+	for {
+		block := iter.Next()
+
+		fmt.Fprintf(c.stdout, "Hash: %x\n", block.Hash)
+		fmt.Fprintf(c.stdout, "Prev. hash: %x\n", block.PrevHash)
+		if block.Timestamp != 0 {
+			fmt.Fprintf(c.stdout, "Time: %s\n", time.Unix(block.Timestamp, 0).UTC().Format(time.RFC3339))
+		}
+		fmt.Fprintf(c.stdout, "Nonce: %d\n", block.Nonce)
+		fmt.Fprintf(c.stdout, "PoW: %t\n", blockchain.NewProof(block).Validate())
+		for _, tx := range block.Transactions {
+			fmt.Fprintf(c.stdout, "  Transaction %x\n", tx.ID)
+			for _, in := range tx.Inputs {
+				fmt.Fprintf(c.stdout, "    Input: %s:%d %s\n", hex.EncodeToString(in.ID), in.Out, in.Sig)
+			}
+			for i, out := range tx.Outputs {
+				fmt.Fprintf(c.stdout, "    Output %d: %d to %s\n", i, out.Value, out.PubKey)
+			}
+		}
+		fmt.Fprintln(c.stdout)
+
+		if len(block.PrevHash) == 0 {
+			break
+		}
+	}
+
+	return nil
+}
+
+// Rebuild the unspent transaction output index
+func (c *command) reindexUTXO(args []string) error {
+	fs := c.flagSet("reindexutxo")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	chain, err := c.openChain()
+	if err != nil {
+		return err
+	}
+	defer chain.Close()
+
+	utxoSet := blockchain.UTXOSet{Chain: chain}
+	if err = utxoSet.Reindex(); err != nil {
+		return err
+	}
+	count, err := utxoSet.CountTransactions()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stdout, "Done! There are %d transactions in the UTXO set.\n", count)
+	return nil
+}
+
+// Verify every block of the chain and optionally its genesis block
+func (c *command) verify(args []string) error {
+	fs := c.flagSet("verify")
+	genesis := fs.String("genesis", "", "genesis spec file the chain must match")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	spec, err := c.genesisSpec(*genesis)
+	if err != nil {
+		return err
+	}
+	var optFns []blockchain.BlockChainOptionsFunc
+	if spec != nil {
+		optFns = append(optFns, blockchain.WithGenesisSpec(spec))
+	}
+
+	chain, err := c.openChain(optFns...)
+	if err != nil {
+		return err
+	}
+	defer chain.Close()
+
+	if err = chain.Verify(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stdout, "Blockchain is valid. Last block: %x\n", chain.LastHash)
+	return nil
+}
@@ -0,0 +1,159 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Run the tool with a regtest chain in the given directory
+func _runAxolchain(dir string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	globalArgs := []string{
+		"-db", filepath.Join(dir, "blocks"),
+		"-wallets", filepath.Join(dir, "wallets.dat"),
+		"-network", "regtest",
+	}
+	code := run(append(globalArgs, args...), &stdout, &stderr)
+
+	return code, stdout.String(), stderr.String()
+}
+
+// Test the whole workflow from creating a chain to sending coins
+func TestRun(t *testing.T) {
+	dir := filepath.Join("testdata", "run")
+	os.RemoveAll(dir)
+	defer os.RemoveAll(dir)
+
+	code, john, _ := _runAxolchain(dir, "createwallet")
+	assert.Equal(t, 0, code)
+	john = strings.TrimSpace(john)
+	code, jane, _ := _runAxolchain(dir, "createwallet")
+	assert.Equal(t, 0, code)
+	jane = strings.TrimSpace(jane)
+
+	code, stdout, _ := _runAxolchain(dir, "listaddresses")
+	assert.Equal(t, 0, code)
+	assert.ElementsMatch(t, []string{john, jane}, strings.Fields(stdout))
+
+	code, stdout, _ = _runAxolchain(dir, "createblockchain", "-address", john)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "Created regtest blockchain")
+
+	code, stdout, _ = _runAxolchain(dir, "send", "-from", john, "-to", jane, "-amount", "30", "-selector", "bnb")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "Sent 30")
+
+	code, stdout, _ = _runAxolchain(dir, "getbalance", "-address", john, "-address", jane)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, john+": 70\n")
+	assert.Contains(t, stdout, jane+": 30\n")
+	assert.Contains(t, stdout, "Total: 100\n")
+
+	code, stdout, _ = _runAxolchain(dir, "reindexutxo")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "There are 1 transactions")
+
+	code, stdout, _ = _runAxolchain(dir, "printchain")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, 2, strings.Count(stdout, "PoW: true"))
+
+	code, stdout, _ = _runAxolchain(dir, "verify")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "Blockchain is valid")
+}
+
+// Test that invalid input is rejected
+func TestRunErrors(t *testing.T) {
+	dir := filepath.Join("testdata", "errors")
+	os.RemoveAll(dir)
+	defer os.RemoveAll(dir)
+
+	cases := map[string]struct {
+		args   []string
+		code   int
+		stderr string
+	}{
+		"no command": {
+			code:   2,
+			stderr: "Usage: axolchain",
+		},
+		"unknown command": {
+			args:   []string{"mine"},
+			code:   2,
+			stderr: `unknown command "mine"`,
+		},
+		"invalid address": {
+			args:   []string{"createblockchain", "-address", "invalid"},
+			code:   1,
+			stderr: `invalid regtest address "invalid"`,
+		},
+		"invalid amount": {
+			args:   []string{"send", "-from", "a", "-to", "b", "-amount", "0"},
+			code:   1,
+			stderr: "invalid amount 0",
+		},
+		"unknown selector": {
+			args:   []string{"send", "-from", "a", "-to", "b", "-amount", "1", "-selector", "best"},
+			code:   1,
+			stderr: `unknown coin selector "best"`,
+		},
+		"missing wallet": {
+			args:   []string{"send", "-from", "a", "-to", "b", "-amount", "1"},
+			code:   1,
+			stderr: `wallet "a" not found`,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			code, _, stderr := _runAxolchain(dir, c.args...)
+			assert.Equal(t, c.code, code)
+			assert.Contains(t, stderr, c.stderr)
+		})
+	}
+}
+
+// Test that the config file is read and flags take precedence
+func TestParseGlobalFlags(t *testing.T) {
+	var stderr bytes.Buffer
+
+	cfg, args, err := parseGlobalFlags([]string{"-config", "testdata/axolchain.yaml", "-network", "regtest", "verify"}, &stderr)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"verify"}, args)
+	assert.Equal(t, "testdata/blocks", cfg.DBPath)
+	assert.Equal(t, "testdata/wallets.dat", cfg.WalletsFile)
+	assert.Equal(t, "regtest", cfg.Network)
+
+	cfg, _, err = parseGlobalFlags([]string{}, &stderr)
+	assert.NoError(t, err)
+	assert.Equal(t, defaultDBPath, cfg.DBPath)
+	assert.Equal(t, defaultWalletsFile, cfg.WalletsFile)
+	assert.Equal(t, "", cfg.Network)
+}
@@ -0,0 +1,523 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+// Command axolcrypt encrypts, decrypts and signs data with the
+// formats of the cryptography packages.
+//
+// Usage:
+//
+//	axolcrypt <command> [flags]
+//
+// Input is read from stdin and output is written to stdout unless
+// -in and -out are given. Run "axolcrypt help" for the list of commands.
+package main
+
+import (
+	crsa "crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/tchiunam/axolgo-lib/cryptography"
+	"github.com/tchiunam/axolgo-lib/cryptography/rsa"
+	"github.com/tchiunam/axolgo-lib/util"
+)
+
+// Default size of generated RSA keys
+const defaultRSABits = 4096
+
+const usage = `Usage: axolcrypt <command> [flags]
+
+Commands:
+  encrypt    [-in FILE] [-out FILE] [-cipher NAME] [passphrase flags]
+             encrypt a stream with a passphrase; NAME is aes-256-gcm,
+             chacha20-poly1305 or xchacha20-poly1305
+  decrypt    [-in FILE] [-out FILE] [passphrase flags]   decrypt with a passphrase
+  genrsa     -out FILE -pubout FILE [-bits N]            generate an RSA key pair as PEM files
+  rsaencrypt -pubkey FILE [-in FILE] [-out FILE]         encrypt with an RSA public key
+  rsadecrypt -key FILE [-in FILE] [-out FILE] [passphrase flags]
+             decrypt with an RSA private key
+  sign       -key FILE [-in FILE] [-out FILE] [passphrase flags]
+             sign with an RSA private key
+  verify     -pubkey FILE -signature FILE [-in FILE]     verify a signature with an RSA public key
+
+Passphrase flags:
+  -passphrase-env NAME    read the passphrase from an environment variable
+  -passphrase-file FILE   read the passphrase from the first line of a file
+The passphrase is prompted for on the terminal if neither is given.
+rsadecrypt and sign only need it for an encrypted private key.
+
+Private keys can be PKCS#1, SEC 1, PKCS#8 or encrypted PKCS#8 and
+public keys PKIX, PKCS#1 or OpenSSH authorized_keys lines.
+
+Input is read from stdin and output is written to stdout by default.
+`
+
+// PEM block types of the generated keys
+const (
+	pemTypeRSAPrivateKey = "RSA PRIVATE KEY"
+	pemTypePublicKey     = "PUBLIC KEY"
+)
+
+// The command line tool and its input and output
+type app struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	// Read a passphrase from the terminal without echoing it
+	prompt func(string) (string, error)
+}
+
+func main() {
+	a := app{
+		stdin:  os.Stdin,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+		prompt: promptTerminal,
+	}
+	os.Exit(a.run(os.Args[1:]))
+}
+
+// Run the command line tool and return the exit code
+func (a *app) run(args []string) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "-help" {
+		fmt.Fprint(a.stderr, usage)
+		return 2
+	}
+
+	commands := map[string]func([]string) error{
+		"encrypt":    a.encrypt,
+		"decrypt":    a.decrypt,
+		"genrsa":     a.genRSA,
+		"rsaencrypt": a.rsaEncrypt,
+		"rsadecrypt": a.rsaDecrypt,
+		"sign":       a.sign,
+		"verify":     a.verify,
+	}
+
+	fn, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(a.stderr, "Error: unknown command %q\n", args[0])
+		fmt.Fprint(a.stderr, usage)
+		return 2
+	}
+	if err := fn(args[1:]); err != nil {
+		if err != flag.ErrHelp {
+			fmt.Fprintf(a.stderr, "Error: %v\n", err)
+		}
+		return 1
+	}
+
+	return 0
+}
+
+// Make a flag set for a command
+func (a *app) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+
+	return fs
+}
+
+// Read the input from a file or stdin if the path is empty
+func (a *app) readInput(path string) ([]byte, error) {
+	if path == "" || path == "-" {
+		return io.ReadAll(a.stdin)
+	}
+
+	return os.ReadFile(util.ExpandPath(path))
+}
+
+// Write the output to a file or stdout if the path is empty. An existing
+// file is replaced so that it gets the given mode.
+func (a *app) writeOutput(path string, data []byte, perm os.FileMode) error {
+	if path == "" || path == "-" {
+		_, err := a.stdout.Write(data)
+		return err
+	}
+
+	return util.WriteFileAtomic(util.ExpandPath(path), data, perm)
+}
+
+// Flags that select where the passphrase comes from
+type passphraseFlags struct {
+	env  string
+	file string
+}
+
+// Register the passphrase flags on a flag set
+func (p *passphraseFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&p.env, "passphrase-env", "", "environment variable holding the passphrase")
+	fs.StringVar(&p.file, "passphrase-file", "", "file holding the passphrase")
+}
+
+// Get the passphrase from the environment, a file or the terminal.
+// A prompted passphrase is asked twice if confirm is true.
+func (a *app) passphrase(p passphraseFlags, confirm bool) (string, error) {
+	var passphrase string
+
+	switch {
+	case p.env != "" && p.file != "":
+		return "", errors.New("-passphrase-env and -passphrase-file cannot be used together")
+	case p.env != "":
+		v, ok := os.LookupEnv(p.env)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", p.env)
+		}
+		passphrase = v
+	case p.file != "":
+		content, err := os.ReadFile(util.ExpandPath(p.file))
+		if err != nil {
+			return "", err
+		}
+		passphrase = strings.TrimRight(strings.SplitN(string(content), "\n", 2)[0], "\r")
+	default:
+		v, err := a.prompt("Passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if confirm {
+			again, err := a.prompt("Confirm passphrase: ")
+			if err != nil {
+				return "", err
+			}
+			if v != again {
+				return "", errors.New("passphrases do not match")
+			}
+		}
+		passphrase = v
+	}
+
+	if passphrase == "" {
+		return "", errors.New("passphrase is empty")
+	}
+
+	return passphrase, nil
+}
+
+// Prompt for a passphrase on the terminal. The terminal is opened
+// directly so that stdin can still be used for the input.
+func promptTerminal(message string) (string, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", fmt.Errorf("cannot prompt for a passphrase: %v", err)
+	}
+	defer tty.Close()
+
+	fmt.Fprint(tty, message)
+	passphrase, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(tty)
+	if err != nil {
+		return "", err
+	}
+
+	return string(passphrase), nil
+}
+
+// Encrypt or decrypt a stream with a passphrase
+func (a *app) passphraseCrypt(
+	name string,
+	args []string,
+	fn func(io.Writer, io.Reader, string, ...cryptography.CryptographyOptionsFunc) error) error {
+	var p passphraseFlags
+	var cipherName string
+
+	fs := a.flagSet(name)
+	in := fs.String("in", "", "input file, stdin by default")
+	out := fs.String("out", "", "output file, stdout by default")
+	p.register(fs)
+	if name == "encrypt" {
+		fs.StringVar(&cipherName, "cipher", cryptography.CipherAES256GCM.String(), "cipher to encrypt with")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var optFns []cryptography.CryptographyOptionsFunc
+	if cipherName != "" {
+		c, err := cryptography.ParseCipher(cipherName)
+		if err != nil {
+			return err
+		}
+		optFns = append(optFns, cryptography.WithCipher(c))
+	}
+
+	passphrase, err := a.passphrase(p, name == "encrypt")
+	if err != nil {
+		return err
+	}
+
+	r := a.stdin
+	if *in != "" && *in != "-" {
+		f, err := os.Open(util.ExpandPath(*in))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	if *out == "" || *out == "-" {
+		return fn(a.stdout, r, passphrase, optFns...)
+	}
+
+	// A new file replaces the output so that an existing file does not
+	// keep a wider mode, and no partial output is left behind
+	return util.WriteFileAtomicFunc(util.ExpandPath(*out), 0600, func(w io.Writer) error {
+		return fn(w, r, passphrase, optFns...)
+	})
+}
+
+// Encrypt with a passphrase
+func (a *app) encrypt(args []string) error {
+	return a.passphraseCrypt("encrypt", args, func(w io.Writer, r io.Reader, passphrase string, optFns ...cryptography.CryptographyOptionsFunc) error {
+		ew, err := cryptography.NewEncryptWriter(w, passphrase, optFns...)
+		if err != nil {
+			return err
+		}
+		if _, err = io.Copy(ew, r); err != nil {
+			return err
+		}
+		return ew.Close()
+	})
+}
+
+// Decrypt with a passphrase
+func (a *app) decrypt(args []string) error {
+	return a.passphraseCrypt("decrypt", args, func(w io.Writer, r io.Reader, passphrase string, optFns ...cryptography.CryptographyOptionsFunc) error {
+		dr, err := cryptography.NewDecryptReader(r, passphrase, optFns...)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, dr)
+		return err
+	})
+}
+
+// Generate an RSA key pair and write it as PEM files
+func (a *app) genRSA(args []string) error {
+	fs := a.flagSet("genrsa")
+	bits := fs.Int("bits", defaultRSABits, "key size in bits")
+	out := fs.String("out", "", "private key file")
+	pubout := fs.String("pubout", "", "public key file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *out == "" || *pubout == "" {
+		return errors.New("-out and -pubout are required")
+	}
+
+	privateKey, publicKey, err := rsa.GenerateRSAKeyPair(*bits)
+	if err != nil {
+		return err
+	}
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: pemTypeRSAPrivateKey, Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+	if err = a.writeOutput(*out, privatePEM, 0600); err != nil {
+		return err
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: pemTypePublicKey, Bytes: publicKeyBytes})
+
+	return a.writeOutput(*pubout, publicPEM, 0644)
+}
+
+// Read an RSA private key from a PEM or DER file in any format that
+// cryptography.ParsePrivateKey accepts. The passphrase of an encrypted
+// key is taken from the passphrase flags.
+func (a *app) readRSAPrivateKey(path string, p passphraseFlags) (*crsa.PrivateKey, error) {
+	content, err := os.ReadFile(util.ExpandPath(path))
+	if err != nil {
+		return nil, err
+	}
+	key, err := cryptography.ParsePrivateKey(content)
+	if errors.Is(err, cryptography.ErrKeyPassphraseRequired) {
+		passphrase, perr := a.passphrase(p, false)
+		if perr != nil {
+			return nil, perr
+		}
+		key, err = cryptography.ParsePrivateKey(content, cryptography.WithKeyPassphrase(passphrase))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse private key in %s: %v", path, err)
+	}
+	rsaKey, ok := key.(*crsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an RSA private key", path)
+	}
+
+	return rsaKey, nil
+}
+
+// Read an RSA public key from a PEM, DER or OpenSSH authorized_keys file
+func readRSAPublicKey(path string) (*crsa.PublicKey, error) {
+	content, err := os.ReadFile(util.ExpandPath(path))
+	if err != nil {
+		return nil, err
+	}
+	key, err := cryptography.ParsePublicKey(content)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse public key in %s: %v", path, err)
+	}
+	rsaKey, ok := key.(*crsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an RSA public key", path)
+	}
+
+	return rsaKey, nil
+}
+
+// Encrypt with an RSA public key
+func (a *app) rsaEncrypt(args []string) error {
+	fs := a.flagSet("rsaencrypt")
+	pubkey := fs.String("pubkey", "", "public key file")
+	in := fs.String("in", "", "input file, stdin by default")
+	out := fs.String("out", "", "output file, stdout by default")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *pubkey == "" {
+		return errors.New("-pubkey is required")
+	}
+	publicKey, err := readRSAPublicKey(*pubkey)
+	if err != nil {
+		return err
+	}
+	data, err := a.readInput(*in)
+	if err != nil {
+		return err
+	}
+	result, err := rsa.EncryptRSA(data, *publicKey)
+	if err != nil {
+		return err
+	}
+
+	return a.writeOutput(*out, result, 0644)
+}
+
+// Decrypt with an RSA private key
+func (a *app) rsaDecrypt(args []string) error {
+	var p passphraseFlags
+
+	fs := a.flagSet("rsadecrypt")
+	key := fs.String("key", "", "private key file")
+	in := fs.String("in", "", "input file, stdin by default")
+	out := fs.String("out", "", "output file, stdout by default")
+	p.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *key == "" {
+		return errors.New("-key is required")
+	}
+	privateKey, err := a.readRSAPrivateKey(*key, p)
+	if err != nil {
+		return err
+	}
+	data, err := a.readInput(*in)
+	if err != nil {
+		return err
+	}
+	result, err := rsa.DecryptRSA(data, privateKey)
+	if err != nil {
+		return err
+	}
+
+	return a.writeOutput(*out, result, 0600)
+}
+
+// Sign with an RSA private key
+func (a *app) sign(args []string) error {
+	var p passphraseFlags
+
+	fs := a.flagSet("sign")
+	key := fs.String("key", "", "private key file")
+	in := fs.String("in", "", "input file, stdin by default")
+	out := fs.String("out", "", "signature file, stdout by default")
+	p.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *key == "" {
+		return errors.New("-key is required")
+	}
+	privateKey, err := a.readRSAPrivateKey(*key, p)
+	if err != nil {
+		return err
+	}
+	data, err := a.readInput(*in)
+	if err != nil {
+		return err
+	}
+	signature, err := rsa.SignRSA(data, privateKey)
+	if err != nil {
+		return err
+	}
+
+	return a.writeOutput(*out, signature, 0644)
+}
+
+// Verify a signature with an RSA public key
+func (a *app) verify(args []string) error {
+	fs := a.flagSet("verify")
+	pubkey := fs.String("pubkey", "", "public key file")
+	signatureFile := fs.String("signature", "", "signature file")
+	in := fs.String("in", "", "input file, stdin by default")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *pubkey == "" || *signatureFile == "" {
+		return errors.New("-pubkey and -signature are required")
+	}
+	publicKey, err := readRSAPublicKey(*pubkey)
+	if err != nil {
+		return err
+	}
+	signature, err := os.ReadFile(util.ExpandPath(*signatureFile))
+	if err != nil {
+		return err
+	}
+	data, err := a.readInput(*in)
+	if err != nil {
+		return err
+	}
+	if err = rsa.VerifyRSA(data, publicKey, signature); err != nil {
+		return fmt.Errorf("verification failed: %v", err)
+	}
+
+	fmt.Fprintln(a.stdout, "Verified OK")
+	return nil
+}
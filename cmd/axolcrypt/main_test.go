@@ -0,0 +1,301 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tchiunam/axolgo-lib/cryptography"
+	"github.com/tchiunam/axolgo-lib/cryptography/rsa"
+)
+
+// Make an app reading from stdin and answering prompts with the given replies
+func _newTestApp(stdin []byte, replies ...string) (*app, *bytes.Buffer, *bytes.Buffer) {
+	var stdout, stderr bytes.Buffer
+	a := app{
+		stdin:  bytes.NewReader(stdin),
+		stdout: &stdout,
+		stderr: &stderr,
+		prompt: func(string) (string, error) {
+			if len(replies) == 0 {
+				return "", errors.New("no terminal")
+			}
+			reply := replies[0]
+			replies = replies[1:]
+			return reply, nil
+		},
+	}
+
+	return &a, &stdout, &stderr
+}
+
+// Test encryption and decryption with a passphrase through pipes
+func TestPassphraseCrypt(t *testing.T) {
+	dir := t.TempDir()
+	passphraseFile := filepath.Join(dir, "passphrase")
+	assert.NoError(t, os.WriteFile(passphraseFile, []byte("axolotl\nignored\n"), 0600))
+	t.Setenv("AXOLCRYPT_TEST_PASSPHRASE", "axolotl")
+
+	plaintext := []byte("Axolotls can regenerate their limbs.")
+	cases := map[string]struct {
+		encryptArgs []string
+		decryptArgs []string
+		replies     []string
+	}{
+		"prompt": {
+			replies: []string{"axolotl", "axolotl", "axolotl"},
+		},
+		"env": {
+			encryptArgs: []string{"-passphrase-env", "AXOLCRYPT_TEST_PASSPHRASE"},
+			decryptArgs: []string{"-passphrase-env", "AXOLCRYPT_TEST_PASSPHRASE"},
+		},
+		"file": {
+			encryptArgs: []string{"-passphrase-file", passphraseFile},
+			decryptArgs: []string{"-passphrase-file", passphraseFile},
+		},
+		"cipher": {
+			encryptArgs: []string{"-cipher", "xchacha20-poly1305", "-passphrase-env", "AXOLCRYPT_TEST_PASSPHRASE"},
+			decryptArgs: []string{"-passphrase-env", "AXOLCRYPT_TEST_PASSPHRASE"},
+		},
+		"env and file": {
+			encryptArgs: []string{"-passphrase-env", "AXOLCRYPT_TEST_PASSPHRASE"},
+			decryptArgs: []string{"-passphrase-file", passphraseFile},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			a, stdout, stderr := _newTestApp(plaintext, c.replies...)
+			assert.Equal(t, 0, a.run(append([]string{"encrypt"}, c.encryptArgs...)), stderr.String())
+			assert.NotEqual(t, plaintext, stdout.Bytes())
+
+			replies := c.replies
+			if len(replies) > 0 {
+				replies = replies[2:]
+			}
+			a, decrypted, stderr := _newTestApp(stdout.Bytes(), replies...)
+			assert.Equal(t, 0, a.run(append([]string{"decrypt"}, c.decryptArgs...)), stderr.String())
+			assert.Equal(t, plaintext, decrypted.Bytes())
+		})
+	}
+}
+
+// Test that passphrase errors are reported
+func TestPassphraseErrors(t *testing.T) {
+	cases := map[string]struct {
+		args    []string
+		replies []string
+		stderr  string
+	}{
+		"mismatch": {
+			args:    []string{"encrypt"},
+			replies: []string{"axolotl", "salamander"},
+			stderr:  "passphrases do not match",
+		},
+		"empty": {
+			args:    []string{"encrypt"},
+			replies: []string{"", ""},
+			stderr:  "passphrase is empty",
+		},
+		"no terminal": {
+			args:   []string{"decrypt"},
+			stderr: "no terminal",
+		},
+		"unset env": {
+			args:   []string{"decrypt", "-passphrase-env", "AXOLCRYPT_TEST_UNSET"},
+			stderr: "environment variable AXOLCRYPT_TEST_UNSET is not set",
+		},
+		"wrong passphrase": {
+			args:    []string{"decrypt"},
+			replies: []string{"salamander"},
+			stderr:  "message authentication failed",
+		},
+	}
+
+	a, encrypted, _ := _newTestApp([]byte("data"), "axolotl", "axolotl")
+	assert.Equal(t, 0, a.run([]string{"encrypt"}))
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			a, _, stderr := _newTestApp(encrypted.Bytes(), c.replies...)
+			assert.Equal(t, 1, a.run(c.args))
+			assert.Contains(t, stderr.String(), c.stderr)
+		})
+	}
+}
+
+// Test that decrypted output files are only readable by the owner and
+// are left as they are when decryption fails
+func TestPassphraseCryptFile(t *testing.T) {
+	dir := t.TempDir()
+	encryptedFile := filepath.Join(dir, "message.axol")
+	decryptedFile := filepath.Join(dir, "message.txt")
+	t.Setenv("AXOLCRYPT_TEST_PASSPHRASE", "axolotl")
+	t.Setenv("AXOLCRYPT_TEST_WRONG_PASSPHRASE", "salamander")
+	plaintext := []byte("Axolotls can regenerate their limbs.")
+
+	a, _, stderr := _newTestApp(plaintext)
+	assert.Equal(t, 0, a.run([]string{"encrypt", "-passphrase-env", "AXOLCRYPT_TEST_PASSPHRASE", "-out", encryptedFile}), stderr.String())
+
+	assert.NoError(t, os.WriteFile(decryptedFile, []byte("keep"), 0644))
+	a, _, _ = _newTestApp(nil)
+	assert.Equal(t, 1, a.run([]string{"decrypt", "-passphrase-env", "AXOLCRYPT_TEST_WRONG_PASSPHRASE", "-in", encryptedFile, "-out", decryptedFile}))
+	content, err := os.ReadFile(decryptedFile)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("keep"), content)
+
+	a, _, stderr = _newTestApp(nil)
+	assert.Equal(t, 0, a.run([]string{"decrypt", "-passphrase-env", "AXOLCRYPT_TEST_PASSPHRASE", "-in", encryptedFile, "-out", decryptedFile}), stderr.String())
+	content, err = os.ReadFile(decryptedFile)
+	assert.NoError(t, err)
+	assert.Equal(t, plaintext, content)
+	info, err := os.Stat(decryptedFile)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+// Test RSA key generation, encryption and signing with files
+func TestRSA(t *testing.T) {
+	dir := t.TempDir()
+	privateKeyFile := filepath.Join(dir, "private.pem")
+	publicKeyFile := filepath.Join(dir, "public.pem")
+	messageFile := filepath.Join(dir, "message.txt")
+	signatureFile := filepath.Join(dir, "message.sig")
+	message := []byte("Axolotls are neotenic salamanders.")
+	assert.NoError(t, os.WriteFile(messageFile, message, 0644))
+
+	// An existing file readable by everyone does not keep its mode
+	assert.NoError(t, os.WriteFile(privateKeyFile, nil, 0644))
+	a, _, stderr := _newTestApp(nil)
+	assert.Equal(t, 0, a.run([]string{"genrsa", "-bits", "2048", "-out", privateKeyFile, "-pubout", publicKeyFile}), stderr.String())
+	info, err := os.Stat(privateKeyFile)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	a, encrypted, stderr := _newTestApp(message)
+	assert.Equal(t, 0, a.run([]string{"rsaencrypt", "-pubkey", publicKeyFile}), stderr.String())
+	a, decrypted, stderr := _newTestApp(encrypted.Bytes())
+	assert.Equal(t, 0, a.run([]string{"rsadecrypt", "-key", privateKeyFile}), stderr.String())
+	assert.Equal(t, message, decrypted.Bytes())
+
+	a, _, stderr = _newTestApp(nil)
+	assert.Equal(t, 0, a.run([]string{"sign", "-key", privateKeyFile, "-in", messageFile, "-out", signatureFile}), stderr.String())
+	a, stdout, stderr := _newTestApp(message)
+	assert.Equal(t, 0, a.run([]string{"verify", "-pubkey", publicKeyFile, "-signature", signatureFile}), stderr.String())
+	assert.Equal(t, "Verified OK\n", stdout.String())
+
+	a, _, stderr = _newTestApp([]byte("tampered"))
+	assert.Equal(t, 1, a.run([]string{"verify", "-pubkey", publicKeyFile, "-signature", signatureFile}))
+	assert.Contains(t, stderr.String(), "verification failed")
+
+	a, _, stderr = _newTestApp(message)
+	assert.Equal(t, 1, a.run([]string{"rsaencrypt", "-pubkey", privateKeyFile}))
+	assert.Contains(t, stderr.String(), "cannot parse public key")
+}
+
+// Test that an encrypted PKCS#8 key is accepted and decrypted output
+// is only readable by the owner
+func TestRSAEncryptedKey(t *testing.T) {
+	dir := t.TempDir()
+	privateKeyFile := filepath.Join(dir, "private.pem")
+	publicKeyFile := filepath.Join(dir, "public.pem")
+	decryptedFile := filepath.Join(dir, "message.txt")
+	message := []byte("Axolotls are neotenic salamanders.")
+
+	privateKey, publicKey, err := rsa.GenerateRSAKeyPair(2048)
+	assert.NoError(t, err)
+	privatePEM, err := cryptography.MarshalPrivateKeyPEM(privateKey, cryptography.WithKeyPassphrase("axolotl"))
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(privateKeyFile, privatePEM, 0600))
+	publicPEM, err := cryptography.MarshalPublicKeyPEM(publicKey)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(publicKeyFile, publicPEM, 0644))
+
+	a, encrypted, stderr := _newTestApp(message)
+	assert.Equal(t, 0, a.run([]string{"rsaencrypt", "-pubkey", publicKeyFile}), stderr.String())
+
+	a, _, stderr = _newTestApp(encrypted.Bytes())
+	assert.Equal(t, 1, a.run([]string{"rsadecrypt", "-key", privateKeyFile}))
+	assert.Contains(t, stderr.String(), "no terminal")
+
+	a, _, stderr = _newTestApp(encrypted.Bytes(), "wrong")
+	assert.Equal(t, 1, a.run([]string{"rsadecrypt", "-key", privateKeyFile}))
+	assert.Contains(t, stderr.String(), "cannot parse private key")
+
+	assert.NoError(t, os.WriteFile(decryptedFile, nil, 0644))
+	a, _, stderr = _newTestApp(encrypted.Bytes(), "axolotl")
+	assert.Equal(t, 0, a.run([]string{"rsadecrypt", "-key", privateKeyFile, "-out", decryptedFile}), stderr.String())
+	decrypted, err := os.ReadFile(decryptedFile)
+	assert.NoError(t, err)
+	assert.Equal(t, message, decrypted)
+	info, err := os.Stat(decryptedFile)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+// Test that invalid commands are rejected
+func TestRunUsage(t *testing.T) {
+	cases := map[string]struct {
+		args   []string
+		code   int
+		stderr string
+	}{
+		"no command": {
+			code:   2,
+			stderr: "Usage: axolcrypt",
+		},
+		"unknown command": {
+			args:   []string{"compress"},
+			code:   2,
+			stderr: `unknown command "compress"`,
+		},
+		"unknown cipher": {
+			args:   []string{"encrypt", "-cipher", "rot13"},
+			code:   1,
+			stderr: `Unknown cipher "rot13"`,
+		},
+		"missing key": {
+			args:   []string{"sign"},
+			code:   1,
+			stderr: "-key is required",
+		},
+		"missing output": {
+			args:   []string{"genrsa", "-out", "private.pem"},
+			code:   1,
+			stderr: "-out and -pubout are required",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			a, _, stderr := _newTestApp(nil)
+			assert.Equal(t, c.code, a.run(c.args))
+			assert.Contains(t, stderr.String(), c.stderr)
+		})
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cryptography
+
+import (
+	"io"
+)
+
+// Crypter encrypts and decrypts data with one key, such as a passphrase
+// or a data key. Data it encrypts records its key ID in the envelope.
+type Crypter interface {
+	// Encrypt everything read from r and write it to w
+	EncryptStream(w io.Writer, r io.Reader) error
+	// Decrypt everything read from r and write it to w
+	DecryptStream(w io.Writer, r io.Reader) error
+	// Get the ID of the key, which may be empty
+	KeyID() string
+	// Get the key ID recorded at the start of data it encrypted
+	ReadKeyID(prefix []byte) (string, error)
+}
+
+// A crypter with a passphrase
+type passphraseCrypter struct {
+	passphrase string
+	options    CryptographyOptions
+	optFns     []CryptographyOptionsFunc
+}
+
+// Create a crypter that encrypts streams with a passphrase, see
+// NewEncryptWriter. Set the key ID with WithKeyID.
+func NewPassphraseCrypter(passphrase string, optFns ...CryptographyOptionsFunc) (Crypter, error) {
+	c := passphraseCrypter{passphrase: passphrase, optFns: optFns}
+	if err := c.options.Merge(optFns...); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// Encrypt a stream with the passphrase, see NewEncryptWriter
+func (c *passphraseCrypter) EncryptStream(w io.Writer, r io.Reader) error {
+	return _encryptStream(w, r, c.passphrase, c.optFns...)
+}
+
+// Decrypt a stream with the passphrase, see NewDecryptReader
+func (c *passphraseCrypter) DecryptStream(w io.Writer, r io.Reader) error {
+	return _decryptStream(w, r, c.passphrase, c.optFns...)
+}
+
+// Get the key ID set with WithKeyID
+func (c *passphraseCrypter) KeyID() string {
+	return c.options.KeyID
+}
+
+// Get the key ID in the envelope header, see EnvelopeKeyID
+func (c *passphraseCrypter) ReadKeyID(prefix []byte) (string, error) {
+	return EnvelopeKeyID(prefix)
+}
+
+// A crypter with a data key
+type keyCrypter struct {
+	key     []byte
+	options CryptographyOptions
+	optFns  []CryptographyOptionsFunc
+}
+
+// Create a crypter that encrypts streams with a data key, see
+// NewEncryptWriterWithKey. Set the key ID with WithKeyID.
+func NewKeyCrypter(key []byte, optFns ...CryptographyOptionsFunc) (Crypter, error) {
+	if len(key) != DataKeyLength {
+		return nil, ErrInvalidKeyLength
+	}
+	c := keyCrypter{key: key, optFns: optFns}
+	if err := c.options.Merge(optFns...); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// Encrypt a stream with the data key, see NewEncryptWriterWithKey
+func (c *keyCrypter) EncryptStream(w io.Writer, r io.Reader) error {
+	ew, err := NewEncryptWriterWithKey(w, c.key, c.optFns...)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(ew, r); err != nil {
+		return err
+	}
+
+	return ew.Close()
+}
+
+// Decrypt a stream with the data key, see NewDecryptReaderWithKey
+func (c *keyCrypter) DecryptStream(w io.Writer, r io.Reader) error {
+	dr, err := NewDecryptReaderWithKey(r, c.key, c.optFns...)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, dr)
+
+	return err
+}
+
+// Get the key ID set with WithKeyID
+func (c *keyCrypter) KeyID() string {
+	return c.options.KeyID
+}
+
+// Get the key ID in the envelope header, see EnvelopeKeyID
+func (c *keyCrypter) ReadKeyID(prefix []byte) (string, error) {
+	return EnvelopeKeyID(prefix)
+}
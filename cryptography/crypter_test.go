@@ -0,0 +1,75 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cryptography
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCrypter encrypts and decrypts streams with the crypters
+func TestCrypter(t *testing.T) {
+	data := []byte("The quick brown fox jumps over the lazy dog")
+	key, err := GenerateDataKey()
+	assert.NoError(t, err)
+
+	cases := map[string]struct {
+		newCrypter func(optFns ...CryptographyOptionsFunc) (Crypter, error)
+	}{
+		"passphrase": {
+			newCrypter: func(optFns ...CryptographyOptionsFunc) (Crypter, error) {
+				return NewPassphraseCrypter("iamthebest", append(optFns, WithPBKDF2Params(testPBKDF2Params))...)
+			},
+		},
+		"key": {
+			newCrypter: func(optFns ...CryptographyOptionsFunc) (Crypter, error) {
+				return NewKeyCrypter(key, optFns...)
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c, err := tc.newCrypter(WithKeyID("axolotl"), WithChunkSize(testChunkSize))
+			assert.NoError(t, err)
+			assert.Equal(t, "axolotl", c.KeyID())
+
+			var encrypted bytes.Buffer
+			assert.NoError(t, c.EncryptStream(&encrypted, bytes.NewReader(data)))
+			keyID, err := c.ReadKeyID(encrypted.Bytes())
+			assert.NoError(t, err)
+			assert.Equal(t, "axolotl", keyID)
+
+			var decrypted bytes.Buffer
+			assert.NoError(t, c.DecryptStream(&decrypted, bytes.NewReader(encrypted.Bytes())))
+			assert.Equal(t, data, decrypted.Bytes())
+
+			_, err = tc.newCrypter(WithChunkSize(-1))
+			assert.Error(t, err)
+		})
+	}
+
+	_, err = NewKeyCrypter(key[:16])
+	assert.Equal(t, ErrInvalidKeyLength, err)
+}
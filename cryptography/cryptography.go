@@ -23,15 +23,19 @@ THE SOFTWARE.
 package cryptography
 
 import (
+	"bytes"
+	"crypto"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/md5"
 	"crypto/rand"
+	"encoding/binary"
 	"encoding/hex"
 	"fmt"
 	"hash"
 	"io"
 	"os"
+	"path/filepath"
 )
 
 // PassphraseHashFunc is a function that returns a hash of a passphrase.
@@ -43,13 +47,56 @@ type CryptographyOptionsFunc func(*CryptographyOptions) error
 // CryptographyOptions are discrete set of options that are valid for loading the
 // configuration that is used to encrypt/decrypt files.
 type CryptographyOptions struct {
-	HashFunc       PassphraseHashFunc
+	HashFunc PassphraseHashFunc
+	// Deprecated: Merge replaces it with OAEPHash, use WithOAEPHash.
 	OAEPHashFunc   hash.Hash
 	OutputFilename string
+	KDF            KDF
+	Argon2idParams Argon2idParams
+	ScryptParams   ScryptParams
+	PBKDF2Params   PBKDF2Params
+	ChunkSize      int
+	Cipher         Cipher
+	AssociatedData []byte
+	BindFilename   bool
+	// Format and passphrase of serialized private keys
+	PrivateKeyFormat KeyFormat
+	KeyPassphrase    string
+	// Format of serialized public keys
+	PublicKeyFormat KeyFormat
+	// Scheme and hash of signatures
+	SignatureScheme SignatureScheme
+	Hash            crypto.Hash
+	PSSSaltLength   int
+	// Hash of RSA-OAEP, Hash if it is not set
+	OAEPHash crypto.Hash
+	// How RSA encrypts messages longer than one OAEP block
+	RSAMode RSAMode
+	// ID of the key recorded in the envelope header
+	KeyID string
 }
 
+// RSAMode is how RSA encryption handles the length of messages
+type RSAMode byte
+
+// Supported RSA modes
+const (
+	// Raw RSA-OAEP when the message fits in one block, hybrid otherwise
+	RSAModeAuto RSAMode = 0
+	// Raw RSA-OAEP only, longer messages are an error
+	RSAModeRaw RSAMode = 1
+	// A random data key wrapped with RSA-OAEP encrypts the message
+	RSAModeHybrid RSAMode = 2
+	// The message is split into blocks encrypted with RSA-OAEP each
+	RSAModeChunked RSAMode = 3
+)
+
 // WithHashFunc is a helper function to construct functional options
 // that sets a custom hash function for the passphrase.
+//
+// Deprecated: the hash is used as the key without a salt. Encrypt only
+// uses it when it is set explicitly, Decrypt uses it for data without
+// a header. Use WithKDF instead.
 func WithHashFunc(fn PassphraseHashFunc) CryptographyOptionsFunc {
 	return func(o *CryptographyOptions) error {
 		o.HashFunc = fn
@@ -59,6 +106,10 @@ func WithHashFunc(fn PassphraseHashFunc) CryptographyOptionsFunc {
 
 // WithOAEPHashFunc is a helper function to construct functional options
 // that sets a custom OAEP hash function for the message.
+//
+// Deprecated: a hash.Hash cannot be shared between calls. The hash is
+// reset and mapped to the crypto.Hash with the same digest, and options
+// with any other hash fail to merge. Use WithOAEPHash instead.
 func WithOAEPHashFunc(fn hash.Hash) CryptographyOptionsFunc {
 	return func(o *CryptographyOptions) error {
 		o.OAEPHashFunc = fn
@@ -66,6 +117,58 @@ func WithOAEPHashFunc(fn hash.Hash) CryptographyOptionsFunc {
 	}
 }
 
+// WithOAEPHash is a helper function to construct functional options
+// that sets the hash of RSA-OAEP independently of the signature hash.
+func WithOAEPHash(h crypto.Hash) CryptographyOptionsFunc {
+	return func(o *CryptographyOptions) error {
+		if !h.Available() {
+			return fmt.Errorf("Unavailable OAEP hash %d", h)
+		}
+		o.OAEPHash = h
+		return nil
+	}
+}
+
+// Get the crypto.Hash whose digest of empty input is the same as that
+// of the hash, which is reset
+func _cryptoHashOf(fn hash.Hash) (crypto.Hash, error) {
+	fn.Reset()
+	sum := fn.Sum(nil)
+	for h := crypto.MD4; h <= crypto.BLAKE2b_512; h++ {
+		if h.Available() && h.Size() == len(sum) && bytes.Equal(h.New().Sum(nil), sum) {
+			return h, nil
+		}
+	}
+
+	return 0, fmt.Errorf("Unsupported OAEP hash function")
+}
+
+// WithRSAMode is a helper function to construct functional options
+// that sets how RSA encryption handles the length of messages.
+func WithRSAMode(mode RSAMode) CryptographyOptionsFunc {
+	return func(o *CryptographyOptions) error {
+		if mode > RSAModeChunked {
+			return fmt.Errorf("Unsupported RSA mode %d", mode)
+		}
+		o.RSAMode = mode
+		return nil
+	}
+}
+
+// WithKeyID is a helper function to construct functional options
+// that records the ID of the key in the envelope header, so that the
+// key needed to decrypt can be found, see EnvelopeKeyID. The ID is
+// authenticated but not encrypted.
+func WithKeyID(id string) CryptographyOptionsFunc {
+	return func(o *CryptographyOptions) error {
+		if len(id) > 0xff {
+			return fmt.Errorf("Key ID is longer than 255 bytes")
+		}
+		o.KeyID = id
+		return nil
+	}
+}
+
 // WithOutputFilename is a helper function to construct functional options
 // that sets the output filename for the encrypted/decrypted file.
 func WithOutputFilename(v string) CryptographyOptionsFunc {
@@ -75,6 +178,28 @@ func WithOutputFilename(v string) CryptographyOptionsFunc {
 	}
 }
 
+// WithAssociatedData is a helper function to construct functional options
+// that sets data, such as a record ID, that the ciphertext is bound to.
+// The data is authenticated but not stored in the output, so the same
+// data must be given to decrypt.
+func WithAssociatedData(data []byte) CryptographyOptionsFunc {
+	return func(o *CryptographyOptions) error {
+		o.AssociatedData = data
+		return nil
+	}
+}
+
+// WithFilenameBinding is a helper function to construct functional options
+// that binds the ciphertext of EncryptFile and DecryptFile to the base
+// name of the encrypted file, so that encrypted files cannot be swapped.
+// EncryptFile then requires an output filename.
+func WithFilenameBinding(v bool) CryptographyOptionsFunc {
+	return func(o *CryptographyOptions) error {
+		o.BindFilename = v
+		return nil
+	}
+}
+
 // Generate a random passphrase. Returns a random passphrase and an error if any.
 func GeneratePassphrase(length int) (string, error) {
 	bytes := make([]byte, length)
@@ -84,7 +209,7 @@ func GeneratePassphrase(length int) (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
-// Create a hash from a string
+// Create an MD5 hash from a string. Use HashReader for other hash functions.
 func CreateHash(input string) string {
 	hasher := md5.New()
 	hasher.Write([]byte(input))
@@ -99,18 +224,68 @@ func (options *CryptographyOptions) Merge(optFns ...CryptographyOptionsFunc) err
 		}
 	}
 
+	// The deprecated hash function is not safe to share, so only the
+	// algorithm is kept
+	if options.OAEPHashFunc != nil {
+		h, err := _cryptoHashOf(options.OAEPHashFunc)
+		if err != nil {
+			return fmt.Errorf("Fail to read cryptography options: %v", err)
+		}
+		options.OAEPHash = h
+		options.OAEPHashFunc = nil
+	}
+
 	return nil
 }
 
-// Encrypt data with a passphrase. Nonce is created by the function.
-// Returns the encrypted data and an error if any.
+// Encrypt data with a passphrase. The key is derived from the passphrase
+// with a random salt, Argon2id by default. The output is an envelope
+// header describing the cipher, the KDF, the salt and the nonce,
+// followed by the ciphertext. Returns the encrypted data and an error if any.
 func Encrypt(data []byte, passphrase string, optFns ...CryptographyOptionsFunc) ([]byte, error) {
+	var options CryptographyOptions
+	if err := options.Merge(optFns...); err != nil {
+		return nil, err
+	}
+
+	if options.HashFunc != nil {
+		return _sealAESGCM([]byte(options.HashFunc(passphrase)), options.AssociatedData, data)
+	}
+
+	return _sealEnvelope(data, secret{passphrase: passphrase}, &options)
+}
+
+// Decrypt data with a passphrase. The algorithm is read from the envelope
+// header. Data without a header is treated as legacy data whose key is
+// the hash of the passphrase. Returns the decrypted data and an error if any.
+func Decrypt(data []byte, passphrase string, optFns ...CryptographyOptionsFunc) ([]byte, error) {
 	options := CryptographyOptions{HashFunc: CreateHash}
 	if err := options.Merge(optFns...); err != nil {
 		return nil, err
 	}
 
-	block, _ := aes.NewCipher([]byte(options.HashFunc(passphrase)))
+	header, headerBytes, ciphertext, err := unmarshalEnvelope(data)
+	if err != nil {
+		if bytes.HasPrefix(data, envelopeMagic) {
+			// Legacy data may start with the magic bytes by chance
+			if plaintext, legacyErr := _openAESGCM([]byte(options.HashFunc(passphrase)), options.AssociatedData, data); legacyErr == nil {
+				return plaintext, nil
+			}
+			return nil, err
+		}
+		return _openAESGCM([]byte(options.HashFunc(passphrase)), options.AssociatedData, data)
+	}
+
+	return _decryptEnvelope(header, headerBytes, ciphertext, secret{passphrase: passphrase}, &options)
+}
+
+// Seal legacy data with AES-GCM and a random nonce. Returns the nonce
+// followed by the ciphertext.
+func _sealAESGCM(key []byte, additionalData []byte, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
 	gcm, err := cipher.NewGCM(block)
 	if err != nil {
 		return nil, err
@@ -119,19 +294,12 @@ func Encrypt(data []byte, passphrase string, optFns ...CryptographyOptionsFunc)
 	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
 		return nil, err
 	}
-	ciphertext := gcm.Seal(nonce, nonce, data, nil)
-	return ciphertext, nil
-}
 
-// Decrypt data with a passphrase. Returns the decrypted
-// data and an error if any.
-func Decrypt(data []byte, passphrase string, optFns ...CryptographyOptionsFunc) ([]byte, error) {
-	options := CryptographyOptions{HashFunc: CreateHash}
-	if err := options.Merge(optFns...); err != nil {
-		return nil, err
-	}
+	return gcm.Seal(nonce, nonce, data, additionalData), nil
+}
 
-	key := []byte(options.HashFunc(passphrase))
+// Open data sealed by _sealAESGCM
+func _openAESGCM(key []byte, additionalData []byte, data []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
@@ -141,31 +309,94 @@ func Decrypt(data []byte, passphrase string, optFns ...CryptographyOptionsFunc)
 		return nil, err
 	}
 	nonceSize := gcm.NonceSize()
-	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
-	if err != nil {
-		return nil, err
+	if len(data) < nonceSize+gcm.Overhead() {
+		return nil, ErrDataTooShort
 	}
-	return plaintext, nil
+	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
+
+	return gcm.Open(nil, nonce, ciphertext, additionalData)
 }
 
-// Encrypt a file with a passphrase. Returns an error if any.
+// Encrypt a file with a passphrase as a stream of chunks, see
+// NewEncryptWriter. Returns the encrypted data, which is also written
+// to the output filename if it is set.
 // filename is the path to the file to be encrypted.
 // passphrase is the passphrase to use to encrypt the file.
 func EncryptFile(filename string, passphrase string, optFns ...CryptographyOptionsFunc) ([]byte, error) {
-	return _cryptFile(Encrypt, filename, passphrase, optFns...)
+	return _cryptFile(_encryptStream, true, true, filename, passphrase, optFns...)
 }
 
-// Decrypt a file with a passphrase. Returns an error if any.
+// Decrypt a file with a passphrase, see NewDecryptReader. Returns the
+// decrypted data, which is also written to the output filename if it
+// is set.
 // filename is the path to the file to be decrypted.
 // passphrase is the passphrase to use to decrypt the file.
 func DecryptFile(filename string, passphrase string, optFns ...CryptographyOptionsFunc) ([]byte, error) {
-	return _cryptFile(Decrypt, filename, passphrase, optFns...)
+	return _cryptFile(_decryptStream, false, true, filename, passphrase, optFns...)
+}
+
+// Encrypt a file with a passphrase to the output filename, which must be
+// set. Unlike EncryptFile, the data is streamed without being held in
+// memory.
+func EncryptFileStream(filename string, passphrase string, optFns ...CryptographyOptionsFunc) error {
+	_, err := _cryptFile(_encryptStream, true, false, filename, passphrase, optFns...)
+
+	return err
+}
+
+// Decrypt a file with a passphrase to the output filename, which must be
+// set. Unlike DecryptFile, the data is streamed without being held in
+// memory. The output file is removed if the data fails authentication.
+func DecryptFileStream(filename string, passphrase string, optFns ...CryptographyOptionsFunc) error {
+	_, err := _cryptFile(_decryptStream, false, false, filename, passphrase, optFns...)
+
+	return err
+}
+
+// Append a file name and its length to the associated data. The length
+// keeps the boundary between the two unambiguous.
+func _bindFilename(associatedData []byte, name string) []byte {
+	length := make([]byte, 4)
+	binary.BigEndian.PutUint32(length, uint32(len(name)))
+
+	bound := make([]byte, 0, len(associatedData)+len(name)+len(length))
+	bound = append(bound, associatedData...)
+	bound = append(bound, name...)
+
+	return append(bound, length...)
+}
+
+// Encrypt everything read from r and write it to w
+func _encryptStream(w io.Writer, r io.Reader, passphrase string, optFns ...CryptographyOptionsFunc) error {
+	ew, err := NewEncryptWriter(w, passphrase, optFns...)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(ew, r); err != nil {
+		return err
+	}
+
+	return ew.Close()
+}
+
+// Decrypt everything read from r and write it to w
+func _decryptStream(w io.Writer, r io.Reader, passphrase string, optFns ...CryptographyOptionsFunc) error {
+	dr, err := NewDecryptReader(r, passphrase, optFns...)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, dr)
+
+	return err
 }
 
 // This is the function to be used to encrypt or decrypt a file.
+// encrypt tells which of the files is the encrypted one. The output is
+// returned if keep is true; otherwise it must go to the output filename.
 func _cryptFile(
-	fn func([]byte, string, ...CryptographyOptionsFunc) ([]byte, error),
+	fn func(io.Writer, io.Reader, string, ...CryptographyOptionsFunc) error,
+	encrypt bool,
+	keep bool,
 	filename string,
 	passphrase string,
 	optFns ...CryptographyOptionsFunc) ([]byte, error) {
@@ -174,18 +405,62 @@ func _cryptFile(
 		return nil, err
 	}
 
-	if content, err := os.ReadFile(filename); err == nil {
-		if data, err := fn(content, passphrase, optFns...); err == nil {
-			if options.OutputFilename != "" {
-				if err = os.WriteFile(options.OutputFilename, data, 0644); err != nil {
-					return nil, err
-				}
+	if options.BindFilename {
+		encryptedFilename := filename
+		if encrypt {
+			if options.OutputFilename == "" {
+				return nil, fmt.Errorf("Binding the file name requires an output filename")
 			}
-			return data, nil
-		} else {
+			encryptedFilename = options.OutputFilename
+		}
+		optFns = append(optFns, WithAssociatedData(_bindFilename(options.AssociatedData, filepath.Base(encryptedFilename))))
+	}
+
+	if !keep && options.OutputFilename == "" {
+		return nil, fmt.Errorf("Streaming a file requires an output filename")
+	}
+
+	in, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	var buffer bytes.Buffer
+	if options.OutputFilename == "" {
+		if err = fn(&buffer, in, passphrase, optFns...); err != nil {
 			return nil, err
 		}
+		return buffer.Bytes(), nil
+	}
+
+	// The output is written while the input is read, so they must differ
+	if inInfo, err := in.Stat(); err == nil {
+		if outInfo, err := os.Stat(options.OutputFilename); err == nil && os.SameFile(inInfo, outInfo) {
+			return nil, fmt.Errorf("Output file %s is the input file", options.OutputFilename)
+		}
+	}
+	out, err := os.OpenFile(options.OutputFilename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+	var w io.Writer = out
+	if keep {
+		w = io.MultiWriter(out, &buffer)
+	}
+	if err = fn(w, in, passphrase, optFns...); err == nil {
+		err = out.Close()
 	} else {
+		out.Close()
+	}
+	if err != nil {
+		// Do not leave partial or unauthenticated output behind
+		os.Remove(options.OutputFilename)
 		return nil, err
 	}
+	if !keep {
+		return nil, nil
+	}
+
+	return buffer.Bytes(), nil
 }
@@ -23,6 +23,7 @@ THE SOFTWARE.
 package cryptography
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -145,10 +146,9 @@ func TestEncryptDecryptInvalid(t *testing.T) {
 	}
 }
 
-// TestDecryptPanic calls Decrypt and checks the panic
-func TestDecryptPanic(t *testing.T) {
+// TestDecryptTooShort calls Decrypt with data shorter than a nonce and a tag
+func TestDecryptTooShort(t *testing.T) {
 	cases := map[string]struct {
-		optFn      func(*CryptographyOptions) error
 		data       []byte
 		passphrase string
 	}{
@@ -160,11 +160,20 @@ func TestDecryptPanic(t *testing.T) {
 			data:       []byte(""),
 			passphrase: "notthebest",
 		},
+		"nonce only": {
+			data:       make([]byte, 12),
+			passphrase: "iamthebest",
+		},
+		"one byte short": {
+			data:       make([]byte, 12+16-1),
+			passphrase: "iamthebest",
+		},
 	}
 
 	for name, c := range cases {
 		t.Run(name, func(t *testing.T) {
-			assert.Panics(t, func() { Decrypt(c.data, c.passphrase) }, "Decrypt(%x, %v), want panic, %v", c.data, c.passphrase)
+			_, err := Decrypt(c.data, c.passphrase)
+			assert.ErrorIs(t, err, ErrDataTooShort, "Decrypt(%x, %v) = %v, want %v", c.data, c.passphrase, err, ErrDataTooShort)
 		})
 	}
 }
@@ -236,10 +245,17 @@ func TestEncryptDecryptFile(t *testing.T) {
 	for name, c := range cases {
 		_cleanTestEncryptDecryptFile(c.encOutputFilename, c.decOutputFilename)
 		t.Run(name, func(t *testing.T) {
-			_, err := EncryptFile(c.filename, c.passphrase, WithOutputFilename(c.encOutputFilename))
+			encrypted, err := EncryptFile(c.filename, c.passphrase, WithOutputFilename(c.encOutputFilename))
 			assert.Nil(t, err, "EncryptFile(%v, %v, %v) = %v, want nil", c.filename, c.passphrase, c.encOutputFilename, err)
-			_, err = DecryptFile(c.encOutputFilename, c.passphrase, WithOutputFilename(c.decOutputFilename))
+			written, _ := os.ReadFile(c.encOutputFilename)
+			assert.Equal(t, written, encrypted, "EncryptFile() should return the data written to the output file")
+			decrypted, err := DecryptFile(c.encOutputFilename, c.passphrase, WithOutputFilename(c.decOutputFilename))
 			assert.Nil(t, err, "DecryptFile(%v, %v, %v) = %v, want nil", c.encFilename, c.passphrase, c.decOutputFilename, err)
+			expect, _ := os.ReadFile(c.filename)
+			assert.Equal(t, expect, decrypted, "DecryptFile() should return the decrypted data")
+			info, err := os.Stat(c.decOutputFilename)
+			assert.NoError(t, err)
+			assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
 		})
 		_cleanTestEncryptDecryptFile(c.encOutputFilename, c.decOutputFilename)
 	}
@@ -305,3 +321,81 @@ func TestEncryptDecryptFileInvalid(t *testing.T) {
 		_cleanTestEncryptDecryptFile(c.encOutputFilename, c.decOutputFilename)
 	}
 }
+
+// TestAssociatedData checks that ciphertext is bound to the associated data
+func TestAssociatedData(t *testing.T) {
+	data := []byte("The quick brown fox jumps over the lazy dog")
+	recordID := []byte("record-42")
+
+	cases := map[string]struct {
+		optFns []CryptographyOptionsFunc
+	}{
+		"envelope": {
+			optFns: []CryptographyOptionsFunc{WithPBKDF2Params(testPBKDF2Params)},
+		},
+		"stream": {
+			optFns: []CryptographyOptionsFunc{WithPBKDF2Params(testPBKDF2Params), WithChunkSize(testChunkSize)},
+		},
+		"legacy": {
+			optFns: []CryptographyOptionsFunc{WithHashFunc(CreateHash)},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			optFns := append(c.optFns, WithAssociatedData(recordID))
+			var encrypted []byte
+			var err error
+			if name == "stream" {
+				var buffer bytes.Buffer
+				w, err := NewEncryptWriter(&buffer, "iamthebest", optFns...)
+				assert.NoError(t, err)
+				_, err = w.Write(data)
+				assert.NoError(t, err)
+				assert.NoError(t, w.Close())
+				encrypted = buffer.Bytes()
+			} else {
+				encrypted, err = Encrypt(data, "iamthebest", optFns...)
+				assert.NoError(t, err)
+			}
+
+			decrypted, err := Decrypt(encrypted, "iamthebest", WithAssociatedData(recordID))
+			assert.NoError(t, err)
+			assert.Equal(t, data, decrypted)
+
+			_, err = Decrypt(encrypted, "iamthebest", WithAssociatedData([]byte("record-43")))
+			assert.Error(t, err)
+			_, err = Decrypt(encrypted, "iamthebest")
+			assert.Error(t, err)
+		})
+	}
+}
+
+// TestEncryptDecryptFileBinding checks that encrypted files cannot be swapped
+// when their names are bound.
+func TestEncryptDecryptFileBinding(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join("testdata", "story.txt")
+	encFilename := filepath.Join(dir, "story.txt.enc")
+	swappedFilename := filepath.Join(dir, "other.txt.enc")
+	optFns := []CryptographyOptionsFunc{WithFilenameBinding(true), WithPBKDF2Params(testPBKDF2Params)}
+
+	_, err := EncryptFile(filename, "iamthebest", append(optFns, WithOutputFilename(encFilename))...)
+	assert.NoError(t, err)
+	decrypted, err := DecryptFile(encFilename, "iamthebest", optFns...)
+	assert.NoError(t, err)
+	expect, _ := os.ReadFile(filename)
+	assert.Equal(t, expect, decrypted)
+
+	// The same content under another name does not decrypt
+	encrypted, _ := os.ReadFile(encFilename)
+	assert.NoError(t, os.WriteFile(swappedFilename, encrypted, 0644))
+	_, err = DecryptFile(swappedFilename, "iamthebest", optFns...)
+	assert.Error(t, err)
+	// Nor does it decrypt without the binding
+	_, err = DecryptFile(encFilename, "iamthebest")
+	assert.Error(t, err)
+
+	_, err = EncryptFile(filename, "iamthebest", optFns...)
+	assert.EqualError(t, err, "Binding the file name requires an output filename")
+}
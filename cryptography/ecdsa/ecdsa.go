@@ -0,0 +1,85 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package ecdsa
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+
+	"github.com/tchiunam/axolgo-lib/cryptography"
+)
+
+// ErrVerification is returned when a signature does not match the message
+var ErrVerification = errors.New("ECDSA verification error")
+
+// Generate a new ECDSA key pair on the curve, such as elliptic.P256()
+func GenerateECDSAKeyPair(curve elliptic.Curve) (*ecdsa.PrivateKey, *ecdsa.PublicKey, error) {
+	privateKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return privateKey, &privateKey.PublicKey, nil
+}
+
+// Sign a message using ECDSA private key. The message is hashed with
+// the hash of the options, SHA-256 by default. The signature is ASN.1 DER.
+func SignECDSA(data []byte, privateKey *ecdsa.PrivateKey, optFns ...cryptography.CryptographyOptionsFunc) ([]byte, error) {
+	options := cryptography.CryptographyOptions{Hash: crypto.SHA256}
+	if err := options.Merge(optFns...); err != nil {
+		return nil, err
+	}
+
+	msgHash := options.Hash.New()
+	if _, err := msgHash.Write(data); err != nil {
+		return nil, err
+	}
+
+	return ecdsa.SignASN1(rand.Reader, privateKey, msgHash.Sum(nil))
+}
+
+// Verify a message using ECDSA public key. The same options as for
+// SignECDSA must be given.
+func VerifyECDSA(
+	data []byte,
+	publicKey *ecdsa.PublicKey,
+	signature []byte,
+	optFns ...cryptography.CryptographyOptionsFunc) error {
+	options := cryptography.CryptographyOptions{Hash: crypto.SHA256}
+	if err := options.Merge(optFns...); err != nil {
+		return err
+	}
+
+	msgHash := options.Hash.New()
+	if _, err := msgHash.Write(data); err != nil {
+		return err
+	}
+	if !ecdsa.VerifyASN1(publicKey, msgHash.Sum(nil), signature) {
+		return ErrVerification
+	}
+
+	return nil
+}
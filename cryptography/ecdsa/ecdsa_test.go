@@ -0,0 +1,88 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package ecdsa
+
+import (
+	"crypto"
+	"crypto/elliptic"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tchiunam/axolgo-lib/cryptography"
+)
+
+// TestSignVerifyECDSA calls the SignECDSA and VerifyECDSA function
+// to make sure message can be signed and verified.
+func TestSignVerifyECDSA(t *testing.T) {
+	cases := map[string]struct {
+		curve  elliptic.Curve
+		optFns []cryptography.CryptographyOptionsFunc
+	}{
+		"p256": {
+			curve: elliptic.P256(),
+		},
+		"p384 with sha384": {
+			curve:  elliptic.P384(),
+			optFns: []cryptography.CryptographyOptionsFunc{cryptography.WithHash(crypto.SHA384)},
+		},
+		"p521 with sha512": {
+			curve:  elliptic.P521(),
+			optFns: []cryptography.CryptographyOptionsFunc{cryptography.WithHash(crypto.SHA512)},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			data := []byte("hello world")
+			privateKey, publicKey, err := GenerateECDSAKeyPair(c.curve)
+			assert.NoError(t, err)
+
+			signature, err := SignECDSA(data, privateKey, c.optFns...)
+			assert.NoError(t, err)
+			assert.NoError(t, VerifyECDSA(data, publicKey, signature, c.optFns...))
+			assert.Equal(t, ErrVerification, VerifyECDSA([]byte("hello axolotl"), publicKey, signature, c.optFns...))
+			assert.Equal(t, ErrVerification, VerifyECDSA(data, publicKey, signature[1:], c.optFns...))
+		})
+	}
+}
+
+// MockWithCryptographyOptionsError is a mock implementation of CryptographyOptions
+// that can be used for testing error.
+func MockWithCryptographyOptionsError() cryptography.CryptographyOptionsFunc {
+	return func(o *cryptography.CryptographyOptions) error {
+		return fmt.Errorf("mock error")
+	}
+}
+
+// TestSignVerifyECDSAInvalid calls the SignECDSA and VerifyECDSA
+// function to make sure errors are returned when options are invalid.
+func TestSignVerifyECDSAInvalid(t *testing.T) {
+	privateKey, publicKey, err := GenerateECDSAKeyPair(elliptic.P256())
+	assert.NoError(t, err)
+
+	_, err = SignECDSA([]byte("hello world"), privateKey, MockWithCryptographyOptionsError())
+	assert.EqualError(t, err, "Fail to read cryptography options: mock error")
+	err = VerifyECDSA([]byte("hello world"), publicKey, nil, MockWithCryptographyOptionsError())
+	assert.EqualError(t, err, "Fail to read cryptography options: mock error")
+}
@@ -0,0 +1,88 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package ecdsa
+
+import (
+	"crypto/ecdsa"
+	"errors"
+
+	"github.com/tchiunam/axolgo-lib/cryptography"
+)
+
+// ErrNotECDSAKey is returned when a parsed key is not an ECDSA key
+var ErrNotECDSAKey = errors.New("Key is not an ECDSA key")
+
+// Serialize a private key as DER. The format is PKCS#8 by default, or
+// SEC 1 with cryptography.WithPrivateKeyFormat(cryptography.KeyFormatSEC1).
+// With cryptography.WithKeyPassphrase the key is encrypted, which requires PKCS#8.
+func MarshalPrivateKeyDER(privateKey *ecdsa.PrivateKey, optFns ...cryptography.CryptographyOptionsFunc) ([]byte, error) {
+	return cryptography.MarshalPrivateKeyDER(privateKey, optFns...)
+}
+
+// Serialize a private key as PEM. The options are the same as for
+// MarshalPrivateKeyDER.
+func MarshalPrivateKeyPEM(privateKey *ecdsa.PrivateKey, optFns ...cryptography.CryptographyOptionsFunc) ([]byte, error) {
+	return cryptography.MarshalPrivateKeyPEM(privateKey, optFns...)
+}
+
+// Serialize a public key as PKIX PEM
+func MarshalPublicKeyPEM(publicKey *ecdsa.PublicKey) ([]byte, error) {
+	return cryptography.MarshalPublicKeyPEM(publicKey)
+}
+
+// Parse a private key in PEM or DER, as SEC 1, PKCS#8 or encrypted
+// PKCS#8. Encrypted keys need cryptography.WithKeyPassphrase.
+func ParsePrivateKey(data []byte, optFns ...cryptography.CryptographyOptionsFunc) (*ecdsa.PrivateKey, error) {
+	key, err := cryptography.ParsePrivateKey(data, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, ErrNotECDSAKey
+	}
+
+	return ecKey, nil
+}
+
+// Parse a public key in PEM or DER as PKIX, or a line of an OpenSSH
+// authorized_keys file.
+func ParsePublicKey(data []byte) (*ecdsa.PublicKey, error) {
+	key, err := cryptography.ParsePublicKey(data)
+	if err != nil {
+		return nil, err
+	}
+	ecKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, ErrNotECDSAKey
+	}
+
+	return ecKey, nil
+}
+
+// Serialize a public key as a line of an OpenSSH authorized_keys file,
+// followed by the comment if it is not empty. OpenSSH supports the
+// curves P-256, P-384 and P-521.
+func MarshalAuthorizedKey(publicKey *ecdsa.PublicKey, comment string) ([]byte, error) {
+	return cryptography.MarshalAuthorizedKey(publicKey, comment)
+}
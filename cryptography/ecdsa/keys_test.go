@@ -0,0 +1,97 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package ecdsa
+
+import (
+	"crypto/elliptic"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tchiunam/axolgo-lib/cryptography"
+	"github.com/tchiunam/axolgo-lib/cryptography/ed25519"
+)
+
+// TestMarshalParseKeys serializes ECDSA keys and parses them
+func TestMarshalParseKeys(t *testing.T) {
+	privateKey, publicKey, err := GenerateECDSAKeyPair(elliptic.P384())
+	assert.NoError(t, err)
+
+	cases := map[string]struct {
+		optFns []cryptography.CryptographyOptionsFunc
+	}{
+		"pkcs8": {},
+		"sec1": {
+			optFns: []cryptography.CryptographyOptionsFunc{cryptography.WithPrivateKeyFormat(cryptography.KeyFormatSEC1)},
+		},
+		"encrypted": {
+			optFns: []cryptography.CryptographyOptionsFunc{cryptography.WithKeyPassphrase("iamthebest")},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			data, err := MarshalPrivateKeyPEM(privateKey, c.optFns...)
+			assert.NoError(t, err)
+			parsed, err := ParsePrivateKey(data, c.optFns...)
+			assert.NoError(t, err)
+			assert.True(t, privateKey.Equal(parsed))
+
+			der, err := MarshalPrivateKeyDER(privateKey, c.optFns...)
+			assert.NoError(t, err)
+			parsed, err = ParsePrivateKey(der, c.optFns...)
+			assert.NoError(t, err)
+			assert.True(t, privateKey.Equal(parsed))
+		})
+	}
+
+	data, err := MarshalPublicKeyPEM(publicKey)
+	assert.NoError(t, err)
+	parsedPublicKey, err := ParsePublicKey(data)
+	assert.NoError(t, err)
+	assert.True(t, publicKey.Equal(parsedPublicKey))
+
+	line, err := MarshalAuthorizedKey(publicKey, "axolotl@example.com")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(line), "ecdsa-sha2-nistp384 "))
+	parsedPublicKey, err = ParsePublicKey(line)
+	assert.NoError(t, err)
+	assert.True(t, publicKey.Equal(parsedPublicKey))
+}
+
+// TestParseKeysInvalid checks that keys of other types are rejected
+func TestParseKeysInvalid(t *testing.T) {
+	privateKey, publicKey, err := ed25519.GenerateEd25519KeyPair()
+	assert.NoError(t, err)
+	privatePEM, err := ed25519.MarshalPrivateKeyPEM(privateKey)
+	assert.NoError(t, err)
+	publicPEM, err := ed25519.MarshalPublicKeyPEM(publicKey)
+	assert.NoError(t, err)
+
+	_, err = ParsePrivateKey(privatePEM)
+	assert.Equal(t, ErrNotECDSAKey, err)
+	_, err = ParsePublicKey(publicPEM)
+	assert.Equal(t, ErrNotECDSAKey, err)
+	_, err = ParsePrivateKey([]byte("hello world"))
+	assert.EqualError(t, err, "Cannot parse private key")
+}
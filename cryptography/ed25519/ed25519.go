@@ -0,0 +1,92 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package ed25519
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/tchiunam/axolgo-lib/cryptography"
+)
+
+// ErrVerification is returned when a signature does not match the message
+var ErrVerification = errors.New("Ed25519 verification error")
+
+// Generate a new Ed25519 key pair
+func GenerateEd25519KeyPair() (ed25519.PrivateKey, ed25519.PublicKey, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return privateKey, publicKey, nil
+}
+
+// Sign a message using Ed25519 private key. Ed25519 hashes the message
+// with SHA-512 itself, so a hash set by cryptography.WithHash must be
+// crypto.SHA512.
+func SignEd25519(data []byte, privateKey ed25519.PrivateKey, optFns ...cryptography.CryptographyOptionsFunc) ([]byte, error) {
+	if err := _checkEd25519Options(optFns); err != nil {
+		return nil, err
+	}
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("Invalid Ed25519 private key length %d", len(privateKey))
+	}
+
+	return ed25519.Sign(privateKey, data), nil
+}
+
+// Verify a message using Ed25519 public key
+func VerifyEd25519(
+	data []byte,
+	publicKey ed25519.PublicKey,
+	signature []byte,
+	optFns ...cryptography.CryptographyOptionsFunc) error {
+	if err := _checkEd25519Options(optFns); err != nil {
+		return err
+	}
+	if len(publicKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("Invalid Ed25519 public key length %d", len(publicKey))
+	}
+	if !ed25519.Verify(publicKey, data, signature) {
+		return ErrVerification
+	}
+
+	return nil
+}
+
+// Check that the options suit Ed25519
+func _checkEd25519Options(optFns []cryptography.CryptographyOptionsFunc) error {
+	var options cryptography.CryptographyOptions
+	if err := options.Merge(optFns...); err != nil {
+		return err
+	}
+	if options.Hash != 0 && options.Hash != crypto.SHA512 {
+		return fmt.Errorf("Ed25519 supports SHA-512 only")
+	}
+
+	return nil
+}
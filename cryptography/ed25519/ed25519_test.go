@@ -0,0 +1,74 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package ed25519
+
+import (
+	"crypto"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tchiunam/axolgo-lib/cryptography"
+)
+
+// TestSignVerifyEd25519 calls the SignEd25519 and VerifyEd25519 function
+// to make sure message can be signed and verified.
+func TestSignVerifyEd25519(t *testing.T) {
+	cases := map[string]struct {
+		optFns []cryptography.CryptographyOptionsFunc
+	}{
+		"default": {},
+		"sha512": {
+			optFns: []cryptography.CryptographyOptionsFunc{cryptography.WithHash(crypto.SHA512)},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			data := []byte("hello world")
+			privateKey, publicKey, err := GenerateEd25519KeyPair()
+			assert.NoError(t, err)
+
+			signature, err := SignEd25519(data, privateKey, c.optFns...)
+			assert.NoError(t, err)
+			assert.NoError(t, VerifyEd25519(data, publicKey, signature, c.optFns...))
+			assert.Equal(t, ErrVerification, VerifyEd25519([]byte("hello axolotl"), publicKey, signature, c.optFns...))
+		})
+	}
+}
+
+// TestSignVerifyEd25519Invalid calls the SignEd25519 and VerifyEd25519
+// function to make sure errors are returned when invalid parameters are passed.
+func TestSignVerifyEd25519Invalid(t *testing.T) {
+	data := []byte("hello world")
+	privateKey, publicKey, err := GenerateEd25519KeyPair()
+	assert.NoError(t, err)
+
+	_, err = SignEd25519(data, privateKey, cryptography.WithHash(crypto.SHA256))
+	assert.EqualError(t, err, "Ed25519 supports SHA-512 only")
+	err = VerifyEd25519(data, publicKey, nil, cryptography.WithHash(crypto.SHA256))
+	assert.EqualError(t, err, "Ed25519 supports SHA-512 only")
+	_, err = SignEd25519(data, privateKey[:10])
+	assert.EqualError(t, err, "Invalid Ed25519 private key length 10")
+	err = VerifyEd25519(data, publicKey[:10], nil)
+	assert.EqualError(t, err, "Invalid Ed25519 public key length 10")
+}
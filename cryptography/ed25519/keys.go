@@ -0,0 +1,86 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package ed25519
+
+import (
+	"crypto/ed25519"
+	"errors"
+
+	"github.com/tchiunam/axolgo-lib/cryptography"
+)
+
+// ErrNotEd25519Key is returned when a parsed key is not an Ed25519 key
+var ErrNotEd25519Key = errors.New("Key is not an Ed25519 key")
+
+// Serialize a private key as PKCS#8 DER. With cryptography.WithKeyPassphrase
+// the key is encrypted.
+func MarshalPrivateKeyDER(privateKey ed25519.PrivateKey, optFns ...cryptography.CryptographyOptionsFunc) ([]byte, error) {
+	return cryptography.MarshalPrivateKeyDER(privateKey, optFns...)
+}
+
+// Serialize a private key as PKCS#8 PEM. The options are the same as
+// for MarshalPrivateKeyDER.
+func MarshalPrivateKeyPEM(privateKey ed25519.PrivateKey, optFns ...cryptography.CryptographyOptionsFunc) ([]byte, error) {
+	return cryptography.MarshalPrivateKeyPEM(privateKey, optFns...)
+}
+
+// Serialize a public key as PKIX PEM
+func MarshalPublicKeyPEM(publicKey ed25519.PublicKey) ([]byte, error) {
+	return cryptography.MarshalPublicKeyPEM(publicKey)
+}
+
+// Parse a private key in PEM or DER, as PKCS#8 or encrypted PKCS#8.
+// Encrypted keys need cryptography.WithKeyPassphrase.
+func ParsePrivateKey(data []byte, optFns ...cryptography.CryptographyOptionsFunc) (ed25519.PrivateKey, error) {
+	key, err := cryptography.ParsePrivateKey(data, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, ErrNotEd25519Key
+	}
+
+	return edKey, nil
+}
+
+// Parse a public key in PEM or DER as PKIX, or a line of an OpenSSH
+// authorized_keys file.
+func ParsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	key, err := cryptography.ParsePublicKey(data)
+	if err != nil {
+		return nil, err
+	}
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, ErrNotEd25519Key
+	}
+
+	return edKey, nil
+}
+
+// Serialize a public key as a line of an OpenSSH authorized_keys file,
+// followed by the comment if it is not empty.
+func MarshalAuthorizedKey(publicKey ed25519.PublicKey, comment string) ([]byte, error) {
+	return cryptography.MarshalAuthorizedKey(publicKey, comment)
+}
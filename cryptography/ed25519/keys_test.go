@@ -0,0 +1,86 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package ed25519
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tchiunam/axolgo-lib/cryptography"
+)
+
+// TestMarshalParseKeys serializes Ed25519 keys and parses them
+func TestMarshalParseKeys(t *testing.T) {
+	privateKey, publicKey, err := GenerateEd25519KeyPair()
+	assert.NoError(t, err)
+
+	for _, passphrase := range []string{"", "iamthebest"} {
+		data, err := MarshalPrivateKeyPEM(privateKey, cryptography.WithKeyPassphrase(passphrase))
+		assert.NoError(t, err)
+		parsed, err := ParsePrivateKey(data, cryptography.WithKeyPassphrase(passphrase))
+		assert.NoError(t, err)
+		assert.True(t, privateKey.Equal(parsed))
+
+		der, err := MarshalPrivateKeyDER(privateKey, cryptography.WithKeyPassphrase(passphrase))
+		assert.NoError(t, err)
+		parsed, err = ParsePrivateKey(der, cryptography.WithKeyPassphrase(passphrase))
+		assert.NoError(t, err)
+		assert.True(t, privateKey.Equal(parsed))
+	}
+
+	data, err := MarshalPublicKeyPEM(publicKey)
+	assert.NoError(t, err)
+	parsedPublicKey, err := ParsePublicKey(data)
+	assert.NoError(t, err)
+	assert.True(t, publicKey.Equal(parsedPublicKey))
+
+	line, err := MarshalAuthorizedKey(publicKey, "")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(line), "ssh-ed25519 "))
+	parsedPublicKey, err = ParsePublicKey(line)
+	assert.NoError(t, err)
+	assert.True(t, publicKey.Equal(parsedPublicKey))
+}
+
+// TestParseKeysInvalid checks that keys of other types are rejected
+func TestParseKeysInvalid(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	privatePEM, err := cryptography.MarshalPrivateKeyPEM(ecKey)
+	assert.NoError(t, err)
+	publicPEM, err := cryptography.MarshalPublicKeyPEM(&ecKey.PublicKey)
+	assert.NoError(t, err)
+
+	_, err = ParsePrivateKey(privatePEM)
+	assert.Equal(t, ErrNotEd25519Key, err)
+	_, err = ParsePublicKey(publicPEM)
+	assert.Equal(t, ErrNotEd25519Key, err)
+	privateKey, _, err := GenerateEd25519KeyPair()
+	assert.NoError(t, err)
+	_, err = MarshalPrivateKeyPEM(privateKey, cryptography.WithPrivateKeyFormat(cryptography.KeyFormatPKCS1))
+	assert.EqualError(t, err, "PKCS#1 supports RSA keys only")
+}
@@ -0,0 +1,448 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cryptography
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Cipher identifies the AEAD used to encrypt the data
+type Cipher byte
+
+// Supported ciphers. The values are stored in the ciphertext header
+// and must not change.
+const (
+	CipherAES256GCM         Cipher = 1
+	CipherChaCha20Poly1305  Cipher = 2
+	CipherXChaCha20Poly1305 Cipher = 3
+)
+
+// Names of the ciphers
+var cipherNames = map[Cipher]string{
+	CipherAES256GCM:         "aes-256-gcm",
+	CipherChaCha20Poly1305:  "chacha20-poly1305",
+	CipherXChaCha20Poly1305: "xchacha20-poly1305",
+}
+
+// Magic bytes at the start of encrypted data
+var envelopeMagic = []byte("AXOL")
+
+// Versions of the envelope header. Version 1 was never released and
+// is rejected. Only the latest version is written.
+const (
+	// The cipher, the KDF, the salt and the nonce
+	envelopeVersion2 = 2
+	// Version 2 followed by the chunk size of a stream
+	envelopeVersion3 = 3
+	// Version 2 followed by flags and the optional fields they mark
+	envelopeVersion4 = 4
+)
+
+// Flags of the optional fields of a version 4 header. The fields follow
+// the flags in the order of their bits.
+const (
+	// The chunk size of a stream as a big-endian uint32
+	envelopeFlagChunkSize = 1 << 0
+	// The length-prefixed key ID
+	envelopeFlagKeyID = 1 << 1
+	// Flags that a reader understands
+	envelopeFlagsKnown = envelopeFlagChunkSize | envelopeFlagKeyID
+)
+
+// Upper bound of the length of an envelope header
+const maxEnvelopeHeaderLength = 1024
+
+// Length of the random salt stored in the header
+const saltLength = 16
+
+// ErrInvalidHeader is returned when the ciphertext header is malformed
+// or uses unsupported parameters.
+var ErrInvalidHeader = errors.New("Invalid ciphertext header")
+
+// ErrKeyRequired is returned when data encrypted with a key is
+// decrypted with a passphrase.
+var ErrKeyRequired = errors.New("Data is encrypted with a key, not a passphrase")
+
+// ErrPassphraseRequired is returned when data encrypted with a
+// passphrase is decrypted with a key.
+var ErrPassphraseRequired = errors.New("Data is encrypted with a passphrase, not a key")
+
+// ErrInvalidKeyLength is returned when a data key is not 256 bits long
+var ErrInvalidKeyLength = errors.New("Data key must be 32 bytes long")
+
+// Header of encrypted data. The encoded header is authenticated as
+// associated data so that none of its fields can be tampered with.
+//
+// The layout of version 2 is the magic bytes, the version, the cipher,
+// the KDF and its parameters, then the salt and the nonce, each
+// preceded by its length in one byte. Version 3 appends the chunk size
+// as a big-endian uint32, and the nonce is the prefix of the chunk nonces.
+// Version 4 appends a flags byte to version 2, followed by the optional
+// fields the flags mark. Unknown flags are rejected.
+type envelopeHeader struct {
+	Version   byte
+	Cipher    Cipher
+	KDF       kdfParams
+	Salt      []byte
+	Nonce     []byte
+	ChunkSize uint32
+	KeyID     string
+}
+
+// Tell whether the envelope is encrypted in chunks
+func (h *envelopeHeader) streamed() bool {
+	return h.ChunkSize != 0
+}
+
+// Get the flags of the optional fields that are set
+func (h *envelopeHeader) flags() byte {
+	var flags byte
+	if h.streamed() {
+		flags |= envelopeFlagChunkSize
+	}
+	if h.KeyID != "" {
+		flags |= envelopeFlagKeyID
+	}
+
+	return flags
+}
+
+// WithCipher is a helper function to construct functional options
+// that sets the cipher used to encrypt. The cipher is recorded in the
+// header, so it is not needed to decrypt.
+func WithCipher(c Cipher) CryptographyOptionsFunc {
+	return func(o *CryptographyOptions) error {
+		if !c.supported() {
+			return fmt.Errorf("Unsupported cipher %d", c)
+		}
+		o.Cipher = c
+		return nil
+	}
+}
+
+// Get a cipher by its name, such as "chacha20-poly1305"
+func ParseCipher(name string) (Cipher, error) {
+	for c, cipherName := range cipherNames {
+		if cipherName == name {
+			return c, nil
+		}
+	}
+
+	return 0, fmt.Errorf("Unknown cipher %q", name)
+}
+
+// Get the name of the cipher
+func (c Cipher) String() string {
+	if name, ok := cipherNames[c]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("cipher(%d)", byte(c))
+}
+
+// Create the AEAD of the cipher. All ciphers take a 256-bit key.
+func (c Cipher) newAEAD(key []byte) (cipher.AEAD, error) {
+	switch c {
+	case CipherAES256GCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case CipherChaCha20Poly1305:
+		return chacha20poly1305.New(key)
+	case CipherXChaCha20Poly1305:
+		return chacha20poly1305.NewX(key)
+	}
+
+	return nil, fmt.Errorf("Unsupported cipher %d", c)
+}
+
+// Check whether the cipher is supported
+func (c Cipher) supported() bool {
+	_, ok := cipherNames[c]
+	return ok
+}
+
+// Tell whether data starts with a valid envelope header. Legacy data
+// encrypted with the hash of the passphrase cannot be detected.
+func IsEncrypted(data []byte) bool {
+	_, _, _, err := unmarshalEnvelope(data)
+	return err == nil
+}
+
+// Get the ID of the key recorded in the header of encrypted data. It
+// is empty if the data was encrypted without WithKeyID. Only the header
+// is read, so the start of the data is enough.
+func EnvelopeKeyID(data []byte) (string, error) {
+	header, _, _, err := unmarshalEnvelope(data)
+	if err != nil {
+		return "", err
+	}
+
+	return header.KeyID, nil
+}
+
+// Encode the header
+func (h *envelopeHeader) marshal() []byte {
+	var buffer bytes.Buffer
+
+	buffer.Write(envelopeMagic)
+	buffer.WriteByte(h.Version)
+	buffer.WriteByte(byte(h.Cipher))
+	h.KDF.marshal(&buffer)
+	buffer.WriteByte(byte(len(h.Salt)))
+	buffer.Write(h.Salt)
+	buffer.WriteByte(byte(len(h.Nonce)))
+	buffer.Write(h.Nonce)
+
+	flags := h.flags()
+	if h.Version == envelopeVersion4 {
+		buffer.WriteByte(flags)
+	}
+	if flags&envelopeFlagChunkSize != 0 {
+		binary.Write(&buffer, binary.BigEndian, h.ChunkSize)
+	}
+	if flags&envelopeFlagKeyID != 0 {
+		buffer.WriteByte(byte(len(h.KeyID)))
+		buffer.WriteString(h.KeyID)
+	}
+
+	return buffer.Bytes()
+}
+
+// Read a length-prefixed field
+func _readLengthPrefixed(data []byte) ([]byte, int, error) {
+	if len(data) < 1 || len(data) < 1+int(data[0]) {
+		return nil, 0, ErrInvalidHeader
+	}
+
+	return data[1 : 1+int(data[0])], 1 + int(data[0]), nil
+}
+
+// Decode the header at the start of the data. Returns the header, the
+// encoded header and the ciphertext.
+func unmarshalEnvelope(data []byte) (*envelopeHeader, []byte, []byte, error) {
+	if len(data) < len(envelopeMagic)+1 || !bytes.HasPrefix(data, envelopeMagic) {
+		return nil, nil, nil, ErrInvalidHeader
+	}
+
+	header := envelopeHeader{Version: data[len(envelopeMagic)]}
+	offset := len(envelopeMagic) + 1
+
+	switch header.Version {
+	case envelopeVersion2, envelopeVersion3, envelopeVersion4:
+		if len(data) < offset+1 {
+			return nil, nil, nil, ErrInvalidHeader
+		}
+		header.Cipher = Cipher(data[offset])
+		if !header.Cipher.supported() {
+			return nil, nil, nil, fmt.Errorf("%w: unsupported cipher %d", ErrInvalidHeader, header.Cipher)
+		}
+		offset++
+
+		n, err := header.KDF.unmarshal(data[offset:])
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		offset += n
+
+		if header.Salt, n, err = _readLengthPrefixed(data[offset:]); err != nil {
+			return nil, nil, nil, err
+		}
+		offset += n
+		if header.Nonce, n, err = _readLengthPrefixed(data[offset:]); err != nil {
+			return nil, nil, nil, err
+		}
+		offset += n
+
+		var flags byte
+		switch header.Version {
+		case envelopeVersion3:
+			flags = envelopeFlagChunkSize
+		case envelopeVersion4:
+			if len(data) < offset+1 {
+				return nil, nil, nil, ErrInvalidHeader
+			}
+			flags = data[offset]
+			if flags&^envelopeFlagsKnown != 0 {
+				return nil, nil, nil, fmt.Errorf("%w: unknown flags %#x", ErrInvalidHeader, flags)
+			}
+			offset++
+		}
+
+		if flags&envelopeFlagChunkSize != 0 {
+			if len(data) < offset+4 {
+				return nil, nil, nil, ErrInvalidHeader
+			}
+			header.ChunkSize = binary.BigEndian.Uint32(data[offset:])
+			if header.ChunkSize < 1 || header.ChunkSize > maxChunkSize {
+				return nil, nil, nil, fmt.Errorf("%w: invalid chunk size %d", ErrInvalidHeader, header.ChunkSize)
+			}
+			offset += 4
+		}
+		if flags&envelopeFlagKeyID != 0 {
+			keyID, n, err := _readLengthPrefixed(data[offset:])
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			if len(keyID) == 0 {
+				return nil, nil, nil, fmt.Errorf("%w: empty key ID", ErrInvalidHeader)
+			}
+			header.KeyID = string(keyID)
+			offset += n
+		}
+
+		return &header, data[:offset], data[offset:], nil
+	}
+
+	return nil, nil, nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidHeader, header.Version)
+}
+
+// The secret of an envelope: a passphrase that the key is derived
+// from, or the key itself.
+type secret struct {
+	passphrase string
+	key        []byte
+}
+
+// Make a header with a new salt and nonce, and the AEAD keyed with the
+// secret. The nonce is nonceLength bytes shorter than the nonce of the AEAD.
+func _newEnvelope(s secret, options *CryptographyOptions, nonceLength int) (*envelopeHeader, cipher.AEAD, error) {
+	header := envelopeHeader{
+		Version: envelopeVersion4,
+		Cipher:  options.Cipher,
+		KDF:     kdfParams{KDF: KDFNone},
+		KeyID:   options.KeyID,
+	}
+	if header.Cipher == 0 {
+		header.Cipher = CipherAES256GCM
+	}
+
+	key := s.key
+	if key == nil {
+		header.KDF = newKDFParams(options)
+		header.Salt = make([]byte, saltLength)
+		if _, err := rand.Read(header.Salt); err != nil {
+			return nil, nil, err
+		}
+		var err error
+		if key, err = header.KDF.deriveKey(s.passphrase, header.Salt); err != nil {
+			return nil, nil, err
+		}
+	} else if len(key) != derivedKeyLength {
+		return nil, nil, ErrInvalidKeyLength
+	}
+
+	aead, err := header.Cipher.newAEAD(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	header.Nonce = make([]byte, aead.NonceSize()-nonceLength)
+	if _, err = rand.Read(header.Nonce); err != nil {
+		return nil, nil, err
+	}
+
+	return &header, aead, nil
+}
+
+// Encrypt data into an envelope with the secret
+func _sealEnvelope(data []byte, s secret, options *CryptographyOptions) ([]byte, error) {
+	header, aead, err := _newEnvelope(s, options, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	headerBytes := header.marshal()
+	return aead.Seal(headerBytes, header.Nonce, data, _additionalData(headerBytes, options.AssociatedData)), nil
+}
+
+// Get the data authenticated with the ciphertext, which is the header
+// followed by the associated data given by the caller. The header is
+// self-delimiting, so the concatenation is unambiguous.
+func _additionalData(headerBytes []byte, associatedData []byte) []byte {
+	additionalData := make([]byte, 0, len(headerBytes)+len(associatedData))
+	additionalData = append(additionalData, headerBytes...)
+
+	return append(additionalData, associatedData...)
+}
+
+// Get the AEAD of an envelope keyed with the secret
+func (h *envelopeHeader) newAEAD(s secret) (cipher.AEAD, error) {
+	if h.KDF.KDF == KDFNone {
+		if s.key == nil {
+			return nil, ErrKeyRequired
+		}
+		if len(s.key) != derivedKeyLength {
+			return nil, ErrInvalidKeyLength
+		}
+		return h.Cipher.newAEAD(s.key)
+	}
+	if s.key != nil {
+		return nil, ErrPassphraseRequired
+	}
+
+	key, err := h.KDF.deriveKey(s.passphrase, h.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.Cipher.newAEAD(key)
+}
+
+// Decrypt an envelope with the secret
+func _openEnvelope(header *envelopeHeader, additionalData []byte, ciphertext []byte, s secret) ([]byte, error) {
+	aead, err := header.newAEAD(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(header.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("%w: invalid nonce length %d", ErrInvalidHeader, len(header.Nonce))
+	}
+
+	return aead.Open(nil, header.Nonce, ciphertext, additionalData)
+}
+
+// Decrypt an envelope of any version with the secret
+func _decryptEnvelope(header *envelopeHeader, headerBytes []byte, ciphertext []byte, s secret, options *CryptographyOptions) ([]byte, error) {
+	additionalData := _additionalData(headerBytes, options.AssociatedData)
+	if header.streamed() {
+		r, err := _newDecryptReader(bufio.NewReader(bytes.NewReader(ciphertext)), header, additionalData, s)
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(r)
+	}
+
+	return _openEnvelope(header, additionalData, ciphertext, s)
+}
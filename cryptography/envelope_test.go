@@ -0,0 +1,302 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cryptography
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEnvelopeHeader checks the header written by Encrypt
+func TestEnvelopeHeader(t *testing.T) {
+	encrypted, err := Encrypt([]byte("data"), "iamthebest", WithPBKDF2Params(testPBKDF2Params))
+	assert.NoError(t, err)
+	assert.True(t, IsEncrypted(encrypted))
+
+	header, additionalData, ciphertext, err := unmarshalEnvelope(encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, byte(envelopeVersion4), header.Version)
+	assert.Equal(t, byte(0), header.flags())
+	assert.Equal(t, CipherAES256GCM, header.Cipher)
+	assert.Equal(t, KDFPBKDF2, header.KDF.KDF)
+	assert.Equal(t, testPBKDF2Params, header.KDF.PBKDF2Params)
+	assert.Len(t, header.Salt, saltLength)
+	assert.Len(t, header.Nonce, 12)
+	assert.Equal(t, header.marshal(), additionalData)
+	assert.Equal(t, encrypted[len(additionalData):], ciphertext)
+}
+
+// TestIsEncrypted checks the detection of encrypted data
+func TestIsEncrypted(t *testing.T) {
+	legacy, err := Encrypt([]byte("data"), "iamthebest", WithHashFunc(CreateHash))
+	assert.NoError(t, err)
+
+	cases := map[string]struct {
+		data   []byte
+		expect bool
+	}{
+		"nil":       {data: nil, expect: false},
+		"plaintext": {data: []byte("The quick brown fox jumps over the lazy dog"), expect: false},
+		"magic":     {data: envelopeMagic, expect: false},
+		"legacy":    {data: legacy, expect: false},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, c.expect, IsEncrypted(c.data))
+		})
+	}
+}
+
+// TestDecryptEarlierVersions decrypts data written with the version 2
+// and 3 headers, which are still read
+func TestDecryptEarlierVersions(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, DataKeyLength)
+	data := []byte("The quick brown fox jumps over the lazy dog")
+
+	cases := map[string]struct {
+		filename      string
+		expectVersion byte
+	}{
+		"version 2": {
+			filename:      filepath.Join("testdata", "envelope-v2.axol"),
+			expectVersion: envelopeVersion2,
+		},
+		"version 3": {
+			filename:      filepath.Join("testdata", "envelope-v3.axol"),
+			expectVersion: envelopeVersion3,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			encrypted, err := os.ReadFile(c.filename)
+			assert.NoError(t, err)
+			header, headerBytes, _, err := unmarshalEnvelope(encrypted)
+			assert.NoError(t, err)
+			assert.Equal(t, c.expectVersion, header.Version)
+			assert.Equal(t, headerBytes, header.marshal())
+
+			decrypted, err := DecryptWithKey(encrypted, key)
+			assert.NoError(t, err)
+			assert.Equal(t, data, decrypted)
+		})
+	}
+}
+
+// TestDecryptTamperedHeader checks that the header is authenticated
+func TestDecryptTamperedHeader(t *testing.T) {
+	encrypted, err := Encrypt([]byte("data"), "iamthebest", WithPBKDF2Params(testPBKDF2Params))
+	assert.NoError(t, err)
+	header, _, _, err := unmarshalEnvelope(encrypted)
+	assert.NoError(t, err)
+
+	// Change the number of iterations
+	offset := len(envelopeMagic) + 2 + 4
+	encrypted[offset]++
+	_, err = Decrypt(encrypted, "iamthebest")
+	assert.Error(t, err)
+	encrypted[offset]--
+
+	// Change the salt
+	offset = bytes.Index(encrypted, header.Salt)
+	encrypted[offset]++
+	_, err = Decrypt(encrypted, "iamthebest")
+	assert.Error(t, err)
+}
+
+// TestUnmarshalEnvelopeInvalid checks that malformed headers are rejected
+func TestUnmarshalEnvelopeInvalid(t *testing.T) {
+	header := func(fields ...byte) []byte {
+		return append(append([]byte{}, envelopeMagic...), fields...)
+	}
+	salt := append([]byte{saltLength}, make([]byte, saltLength)...)
+	nonce := append([]byte{12}, make([]byte, 12)...)
+	pbkdf2 := []byte{byte(KDFPBKDF2), 0, 0, 0, 1}
+
+	cases := map[string][]byte{
+		"no magic":         []byte("The quick brown fox jumps over the lazy dog"),
+		"no version":       header(),
+		"unknown version":  header(9),
+		"unknown cipher":   append(header(envelopeVersion2, 9), pbkdf2...),
+		"unknown kdf":      header(envelopeVersion2, byte(CipherAES256GCM), 9, 0, 0, 0, 1),
+		"zero iterations":  header(envelopeVersion2, byte(CipherAES256GCM), byte(KDFPBKDF2), 0, 0, 0, 0),
+		"scrypt n too big": header(envelopeVersion2, byte(CipherAES256GCM), byte(KDFScrypt), 0x80, 0, 0, 0, 0, 0, 0, 8, 0, 0, 0, 1),
+		"truncated salt":   append(append(header(envelopeVersion2, byte(CipherAES256GCM)), pbkdf2...), saltLength, 0),
+		"missing nonce":    append(append(header(envelopeVersion2, byte(CipherAES256GCM)), pbkdf2...), salt...),
+		"missing flags":    append(append(append(header(envelopeVersion4, byte(CipherAES256GCM)), pbkdf2...), salt...), nonce...),
+		"unknown flags":    append(append(append(append(header(envelopeVersion4, byte(CipherAES256GCM)), pbkdf2...), salt...), nonce...), 0x80),
+		"missing key id":   append(append(append(append(header(envelopeVersion4, byte(CipherAES256GCM)), pbkdf2...), salt...), nonce...), envelopeFlagKeyID),
+		"empty key id":     append(append(append(append(header(envelopeVersion4, byte(CipherAES256GCM)), pbkdf2...), salt...), nonce...), envelopeFlagKeyID, 0),
+		"zero chunk size":  append(append(append(append(header(envelopeVersion4, byte(CipherAES256GCM)), pbkdf2...), salt...), nonce...), envelopeFlagChunkSize, 0, 0, 0, 0),
+		"version 1":        append(append(append(header(1, byte(CipherAES256GCM)), pbkdf2...), salt...), nonce...),
+	}
+	// A complete header must be accepted
+	valid := append(append(append(header(envelopeVersion2, byte(CipherAES256GCM)), pbkdf2...), salt...), nonce...)
+	_, _, _, err := unmarshalEnvelope(valid)
+	assert.NoError(t, err)
+
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, _, _, err := unmarshalEnvelope(data)
+			assert.True(t, errors.Is(err, ErrInvalidHeader), "unmarshalEnvelope() = %v, want %v", err, ErrInvalidHeader)
+		})
+	}
+}
+
+// TestEncryptDecryptCipher encrypts and decrypts with every cipher
+func TestEncryptDecryptCipher(t *testing.T) {
+	data := bytes.Repeat([]byte("The quick brown fox jumps over the lazy dog. "), 10)
+
+	cases := map[string]struct {
+		cipher      Cipher
+		nonceLength int
+	}{
+		"aes-256-gcm": {
+			cipher:      CipherAES256GCM,
+			nonceLength: 12,
+		},
+		"chacha20-poly1305": {
+			cipher:      CipherChaCha20Poly1305,
+			nonceLength: 12,
+		},
+		"xchacha20-poly1305": {
+			cipher:      CipherXChaCha20Poly1305,
+			nonceLength: 24,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			parsed, err := ParseCipher(name)
+			assert.NoError(t, err)
+			assert.Equal(t, c.cipher, parsed)
+			assert.Equal(t, name, c.cipher.String())
+
+			encrypted, err := Encrypt(data, "iamthebest", WithCipher(c.cipher), WithPBKDF2Params(testPBKDF2Params))
+			assert.NoError(t, err)
+			header, _, _, err := unmarshalEnvelope(encrypted)
+			assert.NoError(t, err)
+			assert.Equal(t, c.cipher, header.Cipher)
+			assert.Len(t, header.Nonce, c.nonceLength)
+
+			decrypted, err := Decrypt(encrypted, "iamthebest")
+			assert.NoError(t, err)
+			assert.Equal(t, data, decrypted)
+
+			// Streams record the cipher too
+			var buffer bytes.Buffer
+			w, err := NewEncryptWriter(&buffer, "iamthebest", WithCipher(c.cipher), WithPBKDF2Params(testPBKDF2Params), WithChunkSize(testChunkSize))
+			assert.NoError(t, err)
+			_, err = w.Write(data)
+			assert.NoError(t, err)
+			assert.NoError(t, w.Close())
+
+			decrypted, err = Decrypt(buffer.Bytes(), "iamthebest")
+			assert.NoError(t, err)
+			assert.Equal(t, data, decrypted)
+		})
+	}
+}
+
+// TestCipherInvalid checks that unknown ciphers are rejected
+func TestCipherInvalid(t *testing.T) {
+	_, err := Encrypt([]byte("data"), "iamthebest", WithCipher(Cipher(9)))
+	assert.EqualError(t, err, "Fail to read cryptography options: Unsupported cipher 9")
+
+	_, err = ParseCipher("aes-128-cbc")
+	assert.EqualError(t, err, `Unknown cipher "aes-128-cbc"`)
+	assert.Equal(t, "cipher(9)", Cipher(9).String())
+}
+
+// TestEnvelopeKeyID records a key ID in envelopes of every kind and
+// reads it back
+func TestEnvelopeKeyID(t *testing.T) {
+	key, err := GenerateDataKey()
+	assert.NoError(t, err)
+
+	encrypt := map[string]struct {
+		fn       func(optFns ...CryptographyOptionsFunc) ([]byte, error)
+		streamed bool
+	}{
+		"passphrase": {
+			fn: func(optFns ...CryptographyOptionsFunc) ([]byte, error) {
+				return Encrypt([]byte("data"), "iamthebest", append(optFns, WithPBKDF2Params(testPBKDF2Params))...)
+			},
+		},
+		"key": {
+			fn: func(optFns ...CryptographyOptionsFunc) ([]byte, error) {
+				return EncryptWithKey([]byte("data"), key, optFns...)
+			},
+		},
+		"stream": {
+			fn: func(optFns ...CryptographyOptionsFunc) ([]byte, error) {
+				var buffer bytes.Buffer
+				w, err := NewEncryptWriterWithKey(&buffer, key, optFns...)
+				if err != nil {
+					return nil, err
+				}
+				w.Write([]byte("data"))
+				return buffer.Bytes(), w.Close()
+			},
+			streamed: true,
+		},
+	}
+	for name, tc := range encrypt {
+		t.Run(name, func(t *testing.T) {
+			encrypted, err := tc.fn()
+			assert.NoError(t, err)
+			keyID, err := EnvelopeKeyID(encrypted)
+			assert.NoError(t, err)
+			assert.Empty(t, keyID)
+
+			encrypted, err = tc.fn(WithKeyID("axolotl/2"))
+			assert.NoError(t, err)
+			header, _, _, err := unmarshalEnvelope(encrypted)
+			assert.NoError(t, err)
+			assert.Equal(t, byte(envelopeVersion4), header.Version)
+			assert.NotZero(t, header.flags()&envelopeFlagKeyID)
+			assert.Equal(t, tc.streamed, header.streamed())
+			keyID, err = EnvelopeKeyID(encrypted[:64])
+			assert.NoError(t, err)
+			assert.Equal(t, "axolotl/2", keyID)
+
+			// The key ID is authenticated
+			tampered := bytes.Replace(encrypted, []byte("axolotl/2"), []byte("axolotl/3"), 1)
+			_, err = DecryptWithKey(tampered, key)
+			assert.Error(t, err)
+			_, err = Decrypt(tampered, "iamthebest")
+			assert.Error(t, err)
+		})
+	}
+
+	_, err = EnvelopeKeyID([]byte("data"))
+	assert.ErrorIs(t, err, ErrInvalidHeader)
+	_, err = Encrypt([]byte("data"), "iamthebest", WithKeyID(string(bytes.Repeat([]byte("a"), 256))))
+	assert.Error(t, err)
+}
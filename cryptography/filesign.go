@@ -0,0 +1,288 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cryptography
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Version of the signature file format
+const fileSignatureVersion = 1
+
+// Default extension of signature files
+const SignatureFileExtension = ".sig"
+
+// Signature algorithms of signature files
+const (
+	SignatureAlgorithmRSAPSS      = "rsa-pss"
+	SignatureAlgorithmRSAPKCS1v15 = "rsa-pkcs1v15"
+	SignatureAlgorithmECDSA       = "ecdsa"
+	SignatureAlgorithmEd25519     = "ed25519"
+)
+
+// Magic bytes of the signed data, which keep a file signature from
+// being valid in any other context
+var fileSignatureMagic = []byte("AXSG")
+
+// Hashes allowed in signature files
+var fileSignatureHashes = []crypto.Hash{
+	crypto.SHA256,
+	crypto.SHA384,
+	crypto.SHA512,
+	crypto.SHA3_256,
+	crypto.SHA3_384,
+	crypto.SHA3_512,
+}
+
+// ErrInvalidSignatureFile is returned when a signature file cannot be parsed
+var ErrInvalidSignatureFile = errors.New("Invalid signature file")
+
+// ErrSignatureKeyMismatch is returned when a file is verified with
+// another key than it was signed with.
+var ErrSignatureKeyMismatch = errors.New("File was signed with another key")
+
+// ErrSignatureVerification is returned when a signature does not match the file
+var ErrSignatureVerification = errors.New("Signature does not match the file")
+
+// FileSignature is the content of a detached signature file, which is
+// stored as JSON. The signature covers the hash of the file and all
+// other fields, so none of them can be changed.
+type FileSignature struct {
+	Version   int       `json:"version"`
+	Algorithm string    `json:"algorithm"`
+	Hash      string    `json:"hash"`
+	KeyID     string    `json:"key_id"`
+	Timestamp time.Time `json:"timestamp"`
+	Signature []byte    `json:"signature"`
+}
+
+// Sign a file with a private key and write a detached signature to the
+// output filename, or to the file name followed by SignatureFileExtension.
+// The file is hashed as a stream with the hash of the options, SHA-256 by
+// default. The key can be an RSA, ECDSA or Ed25519 key, or any
+// crypto.Signer of those. RSA keys sign with the scheme of the options.
+func SignFile(filename string, privateKey crypto.Signer, optFns ...CryptographyOptionsFunc) error {
+	options := CryptographyOptions{Hash: crypto.SHA256, OutputFilename: filename + SignatureFileExtension}
+	if err := options.Merge(optFns...); err != nil {
+		return err
+	}
+	if _, err := _parseFileSignatureHash(options.Hash.String()); err != nil {
+		return err
+	}
+
+	var algorithm string
+	switch privateKey.Public().(type) {
+	case *rsa.PublicKey:
+		algorithm = SignatureAlgorithmRSAPSS
+		if options.SignatureScheme == SignatureSchemePKCS1v15 {
+			algorithm = SignatureAlgorithmRSAPKCS1v15
+		}
+	case *ecdsa.PublicKey:
+		algorithm = SignatureAlgorithmECDSA
+	case ed25519.PublicKey:
+		algorithm = SignatureAlgorithmEd25519
+	default:
+		return fmt.Errorf("Unsupported key type %T", privateKey.Public())
+	}
+
+	keyID, err := PublicKeyID(privateKey.Public())
+	if err != nil {
+		return err
+	}
+	signature := FileSignature{
+		Version:   fileSignatureVersion,
+		Algorithm: algorithm,
+		Hash:      options.Hash.String(),
+		KeyID:     hex.EncodeToString(keyID),
+		Timestamp: time.Now().UTC().Truncate(time.Second),
+	}
+
+	signedData, err := _fileSignedData(filename, &signature, options.Hash)
+	if err != nil {
+		return err
+	}
+
+	switch algorithm {
+	case SignatureAlgorithmEd25519:
+		signature.Signature, err = privateKey.Sign(rand.Reader, signedData, crypto.Hash(0))
+	case SignatureAlgorithmRSAPSS:
+		signature.Signature, err = privateKey.Sign(
+			rand.Reader,
+			_digest(options.Hash, signedData),
+			&rsa.PSSOptions{SaltLength: options.PSSSaltLength, Hash: options.Hash})
+	default:
+		signature.Signature, err = privateKey.Sign(rand.Reader, _digest(options.Hash, signedData), options.Hash)
+	}
+	if err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(signature, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(options.OutputFilename, append(content, '\n'), 0644)
+}
+
+// Read a signature file written by SignFile. The signature is not verified.
+func ReadSignatureFile(sigFilename string) (*FileSignature, error) {
+	content, err := os.ReadFile(sigFilename)
+	if err != nil {
+		return nil, err
+	}
+
+	var signature FileSignature
+	if err = json.Unmarshal(content, &signature); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignatureFile, err)
+	}
+	if signature.Version != fileSignatureVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidSignatureFile, signature.Version)
+	}
+
+	return &signature, nil
+}
+
+// Verify a file against a detached signature file written by SignFile.
+// The algorithm must suit the public key and the key must be the one
+// the file was signed with.
+func VerifyFile(filename string, sigFilename string, publicKey crypto.PublicKey) error {
+	signature, err := ReadSignatureFile(sigFilename)
+	if err != nil {
+		return err
+	}
+	h, err := _parseFileSignatureHash(signature.Hash)
+	if err != nil {
+		return err
+	}
+
+	keyID, err := PublicKeyID(publicKey)
+	if err != nil {
+		return err
+	}
+	if signature.KeyID != hex.EncodeToString(keyID) {
+		return ErrSignatureKeyMismatch
+	}
+
+	signedData, err := _fileSignedData(filename, signature, h)
+	if err != nil {
+		return err
+	}
+
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		switch signature.Algorithm {
+		case SignatureAlgorithmRSAPSS:
+			err = rsa.VerifyPSS(key, h, _digest(h, signedData), signature.Signature, nil)
+		case SignatureAlgorithmRSAPKCS1v15:
+			err = rsa.VerifyPKCS1v15(key, h, _digest(h, signedData), signature.Signature)
+		default:
+			return fmt.Errorf("%w: algorithm %s does not suit an RSA key", ErrInvalidSignatureFile, signature.Algorithm)
+		}
+		if err != nil {
+			return ErrSignatureVerification
+		}
+	case *ecdsa.PublicKey:
+		if signature.Algorithm != SignatureAlgorithmECDSA {
+			return fmt.Errorf("%w: algorithm %s does not suit an ECDSA key", ErrInvalidSignatureFile, signature.Algorithm)
+		}
+		if !ecdsa.VerifyASN1(key, _digest(h, signedData), signature.Signature) {
+			return ErrSignatureVerification
+		}
+	case ed25519.PublicKey:
+		if signature.Algorithm != SignatureAlgorithmEd25519 {
+			return fmt.Errorf("%w: algorithm %s does not suit an Ed25519 key", ErrInvalidSignatureFile, signature.Algorithm)
+		}
+		if !ed25519.Verify(key, signedData, signature.Signature) {
+			return ErrSignatureVerification
+		}
+	default:
+		return fmt.Errorf("Unsupported key type %T", publicKey)
+	}
+
+	return nil
+}
+
+// Get the hash of a signature file by its name
+func _parseFileSignatureHash(name string) (crypto.Hash, error) {
+	for _, h := range fileSignatureHashes {
+		if h.String() == name && h.Available() {
+			return h, nil
+		}
+	}
+
+	return 0, fmt.Errorf("Unsupported signature hash %s", name)
+}
+
+// Hash data
+func _digest(h crypto.Hash, data []byte) []byte {
+	digest := h.New()
+	digest.Write(data)
+
+	return digest.Sum(nil)
+}
+
+// Get the data a file signature is made of. It consists of the magic
+// bytes, the version, the length-prefixed algorithm, hash name and key
+// ID, the timestamp in Unix seconds and the hash of the file.
+func _fileSignedData(filename string, signature *FileSignature, h crypto.Hash) ([]byte, error) {
+	keyID, err := hex.DecodeString(signature.KeyID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignatureFile, err)
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	fileHash := h.New()
+	if _, err = io.Copy(fileHash, file); err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	buffer.Write(fileSignatureMagic)
+	buffer.WriteByte(byte(signature.Version))
+	for _, field := range [][]byte{[]byte(signature.Algorithm), []byte(signature.Hash), keyID} {
+		binary.Write(&buffer, binary.BigEndian, uint16(len(field)))
+		buffer.Write(field)
+	}
+	binary.Write(&buffer, binary.BigEndian, signature.Timestamp.Unix())
+	buffer.Write(fileHash.Sum(nil))
+
+	return buffer.Bytes(), nil
+}
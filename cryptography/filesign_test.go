@@ -0,0 +1,186 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cryptography
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Copy the story to a temporary directory, so that it can be changed
+func _copyStory(t *testing.T) string {
+	content, err := os.ReadFile(filepath.Join("testdata", "story.txt"))
+	assert.NoError(t, err)
+	filename := filepath.Join(t.TempDir(), "story.txt")
+	assert.NoError(t, os.WriteFile(filename, content, 0644))
+
+	return filename
+}
+
+// TestSignVerifyFile signs a file with every key type and verifies it
+func TestSignVerifyFile(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	cases := map[string]struct {
+		key             crypto.Signer
+		optFns          []CryptographyOptionsFunc
+		expectAlgorithm string
+		expectHash      string
+	}{
+		"rsa pss": {
+			key:             rsaKey,
+			expectAlgorithm: SignatureAlgorithmRSAPSS,
+			expectHash:      "SHA-256",
+		},
+		"rsa pkcs1v15 with sha512": {
+			key:             rsaKey,
+			optFns:          []CryptographyOptionsFunc{WithSignatureScheme(SignatureSchemePKCS1v15), WithHash(crypto.SHA512)},
+			expectAlgorithm: SignatureAlgorithmRSAPKCS1v15,
+			expectHash:      "SHA-512",
+		},
+		"ecdsa": {
+			key:             ecKey,
+			expectAlgorithm: SignatureAlgorithmECDSA,
+			expectHash:      "SHA-256",
+		},
+		"ed25519 with sha384": {
+			key:             edKey,
+			optFns:          []CryptographyOptionsFunc{WithHash(crypto.SHA384)},
+			expectAlgorithm: SignatureAlgorithmEd25519,
+			expectHash:      "SHA-384",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			filename := _copyStory(t)
+			sigFilename := filename + SignatureFileExtension
+
+			assert.NoError(t, SignFile(filename, c.key, c.optFns...))
+			signature, err := ReadSignatureFile(sigFilename)
+			assert.NoError(t, err)
+			assert.Equal(t, c.expectAlgorithm, signature.Algorithm)
+			assert.Equal(t, c.expectHash, signature.Hash)
+			assert.WithinDuration(t, time.Now(), signature.Timestamp, time.Minute)
+			assert.NoError(t, VerifyFile(filename, sigFilename, c.key.Public()))
+
+			// The file is changed
+			assert.NoError(t, os.WriteFile(filename, []byte("Once upon a time"), 0644))
+			assert.Equal(t, ErrSignatureVerification, VerifyFile(filename, sigFilename, c.key.Public()))
+		})
+	}
+}
+
+// TestVerifyFileInvalid checks that changed signature files and other
+// keys are rejected.
+func TestVerifyFileInvalid(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	filename := _copyStory(t)
+	sigFilename := filepath.Join(t.TempDir(), "story.sig")
+	assert.NoError(t, SignFile(filename, ecKey, WithOutputFilename(sigFilename)))
+
+	// Write the signature file with a changed field
+	change := func(fn func(*FileSignature)) string {
+		signature, err := ReadSignatureFile(sigFilename)
+		assert.NoError(t, err)
+		fn(signature)
+		content, err := json.Marshal(signature)
+		assert.NoError(t, err)
+		changedFilename := filepath.Join(t.TempDir(), "changed.sig")
+		assert.NoError(t, os.WriteFile(changedFilename, content, 0644))
+		return changedFilename
+	}
+
+	cases := map[string]struct {
+		sigFilename       string
+		publicKey         crypto.PublicKey
+		expectError       error
+		expectErrorString string
+	}{
+		"other key": {
+			sigFilename: sigFilename,
+			publicKey:   &otherKey.PublicKey,
+			expectError: ErrSignatureKeyMismatch,
+		},
+		"changed timestamp": {
+			sigFilename: change(func(s *FileSignature) { s.Timestamp = s.Timestamp.Add(time.Hour) }),
+			publicKey:   &ecKey.PublicKey,
+			expectError: ErrSignatureVerification,
+		},
+		"changed hash": {
+			sigFilename: change(func(s *FileSignature) { s.Hash = "SHA-512" }),
+			publicKey:   &ecKey.PublicKey,
+			expectError: ErrSignatureVerification,
+		},
+		"unsupported hash": {
+			sigFilename:       change(func(s *FileSignature) { s.Hash = "MD5" }),
+			publicKey:         &ecKey.PublicKey,
+			expectErrorString: "Unsupported signature hash MD5",
+		},
+		"changed algorithm": {
+			sigFilename:       change(func(s *FileSignature) { s.Algorithm = SignatureAlgorithmEd25519 }),
+			publicKey:         &ecKey.PublicKey,
+			expectErrorString: "Invalid signature file: algorithm ed25519 does not suit an ECDSA key",
+		},
+		"unsupported version": {
+			sigFilename:       change(func(s *FileSignature) { s.Version = 2 }),
+			publicKey:         &ecKey.PublicKey,
+			expectErrorString: "Invalid signature file: unsupported version 2",
+		},
+		"not a signature file": {
+			sigFilename: filename,
+			publicKey:   &ecKey.PublicKey,
+			expectError: ErrInvalidSignatureFile,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := VerifyFile(filename, c.sigFilename, c.publicKey)
+			if c.expectError != nil {
+				assert.ErrorIs(t, err, c.expectError)
+			} else {
+				assert.EqualError(t, err, c.expectErrorString)
+			}
+		})
+	}
+}
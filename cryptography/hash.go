@@ -0,0 +1,344 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cryptography
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	// Register SHA3 and BLAKE2b with crypto.Hash
+	_ "golang.org/x/crypto/blake2b"
+	_ "golang.org/x/crypto/sha3"
+)
+
+// Hash functions of HashReader, HashFile, HMAC and manifests
+var hashFunctions = []crypto.Hash{
+	crypto.SHA256,
+	crypto.SHA384,
+	crypto.SHA512,
+	crypto.SHA512_256,
+	crypto.SHA3_256,
+	crypto.SHA3_384,
+	crypto.SHA3_512,
+	crypto.BLAKE2b_256,
+	crypto.BLAKE2b_384,
+	crypto.BLAKE2b_512,
+}
+
+// ErrHMACVerification is returned when an HMAC does not match the data
+var ErrHMACVerification = errors.New("HMAC verification failed")
+
+// ErrChecksumMismatch is returned when a file does not match its
+// checksum in a manifest
+var ErrChecksumMismatch = errors.New("Checksum does not match")
+
+// ErrInvalidManifest is returned when a checksum manifest is malformed
+var ErrInvalidManifest = errors.New("Invalid checksum manifest")
+
+// Get a hash function by its name, such as "SHA-256", "sha3-512" or
+// "blake2b-256". Case and dashes are ignored.
+func ParseHash(name string) (crypto.Hash, error) {
+	normalize := func(s string) string {
+		return strings.ReplaceAll(strings.ToLower(s), "-", "")
+	}
+	for _, h := range hashFunctions {
+		if normalize(h.String()) == normalize(name) {
+			return h, nil
+		}
+	}
+
+	return 0, fmt.Errorf("Unsupported hash function %q", name)
+}
+
+// Read the options of hashing, SHA-256 by default
+func _hashOptions(optFns ...CryptographyOptionsFunc) (*CryptographyOptions, error) {
+	options := CryptographyOptions{Hash: crypto.SHA256}
+	if err := options.Merge(optFns...); err != nil {
+		return nil, err
+	}
+
+	return &options, nil
+}
+
+// Hash everything read from r. The hash function is set with WithHash,
+// SHA-256 by default. Returns the digest and an error if any.
+func HashReader(r io.Reader, optFns ...CryptographyOptionsFunc) ([]byte, error) {
+	options, err := _hashOptions(optFns...)
+	if err != nil {
+		return nil, err
+	}
+
+	return _hashReader(options.Hash, r)
+}
+
+// Hash everything read from r with the hash function
+func _hashReader(h crypto.Hash, r io.Reader) ([]byte, error) {
+	digest := h.New()
+	if _, err := io.Copy(digest, r); err != nil {
+		return nil, err
+	}
+
+	return digest.Sum(nil), nil
+}
+
+// Hash a file without reading it into memory, see HashReader.
+// Returns the digest and an error if any.
+func HashFile(filename string, optFns ...CryptographyOptionsFunc) ([]byte, error) {
+	options, err := _hashOptions(optFns...)
+	if err != nil {
+		return nil, err
+	}
+
+	return _hashFile(options.Hash, filename)
+}
+
+// Hash a file with the hash function
+func _hashFile(h crypto.Hash, filename string) ([]byte, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return _hashReader(h, file)
+}
+
+// Compute the HMAC of data with a key. The hash function is set with
+// WithHash, SHA-256 by default. Returns the HMAC and an error if any.
+func SignHMAC(data []byte, key []byte, optFns ...CryptographyOptionsFunc) ([]byte, error) {
+	options, err := _hashOptions(optFns...)
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(options.Hash.New, key)
+	mac.Write(data)
+
+	return mac.Sum(nil), nil
+}
+
+// Verify the HMAC of data with a key in constant time. The same options
+// as for SignHMAC must be given. Returns ErrHMACVerification if the
+// HMAC does not match.
+func VerifyHMAC(data []byte, key []byte, signature []byte, optFns ...CryptographyOptionsFunc) error {
+	expected, err := SignHMAC(data, key, optFns...)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(expected, signature) {
+		return ErrHMACVerification
+	}
+
+	return nil
+}
+
+// ManifestResult is the result of verifying one file of a manifest
+type ManifestResult struct {
+	Path string
+	Err  error
+}
+
+// Generate a checksum manifest of every regular file in a directory,
+// in the format of sha256sum and similar tools. The paths are relative
+// to the directory and sorted. The hash function is set with WithHash,
+// SHA-256 by default. If an output filename is set, the manifest is
+// written to it, the file itself is not listed and nil is returned.
+// Returns the manifest and an error if any.
+func GenerateManifest(dir string, optFns ...CryptographyOptionsFunc) ([]byte, error) {
+	options, err := _hashOptions(optFns...)
+	if err != nil {
+		return nil, err
+	}
+
+	var outputInfo fs.FileInfo
+	if options.OutputFilename != "" {
+		outputInfo, _ = os.Stat(options.OutputFilename)
+	}
+
+	var paths []string
+	err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() {
+			return err
+		}
+		if outputInfo != nil {
+			if info, err := entry.Info(); err == nil && os.SameFile(info, outputInfo) {
+				return nil
+			}
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var relativePaths []string
+	for _, path := range paths {
+		relativePath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return nil, err
+		}
+		relativePaths = append(relativePaths, filepath.ToSlash(relativePath))
+	}
+	sort.Strings(relativePaths)
+
+	var buffer bytes.Buffer
+	for _, relativePath := range relativePaths {
+		digest, err := _hashFile(options.Hash, filepath.Join(dir, filepath.FromSlash(relativePath)))
+		if err != nil {
+			return nil, err
+		}
+		_writeManifestLine(&buffer, digest, relativePath)
+	}
+
+	if options.OutputFilename != "" {
+		return nil, os.WriteFile(options.OutputFilename, buffer.Bytes(), 0644)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// Write a line of a manifest. Like sha256sum, a name with a backslash
+// or a newline is escaped and the line starts with a backslash.
+func _writeManifestLine(buffer *bytes.Buffer, digest []byte, name string) {
+	if strings.ContainsAny(name, "\\\n") {
+		buffer.WriteByte('\\')
+		name = strings.NewReplacer("\\", "\\\\", "\n", "\\n").Replace(name)
+	}
+	buffer.WriteString(hex.EncodeToString(digest))
+	buffer.WriteString("  ")
+	buffer.WriteString(name)
+	buffer.WriteByte('\n')
+}
+
+// Parse a line of a manifest. Returns the digest and the name.
+func _parseManifestLine(line string, digestLength int) ([]byte, string, error) {
+	escaped := strings.HasPrefix(line, "\\")
+	if escaped {
+		line = line[1:]
+	}
+
+	// The digest is followed by a space and a space or "*" for binary mode
+	hexLength := 2 * digestLength
+	if len(line) < hexLength+3 || line[hexLength] != ' ' || (line[hexLength+1] != ' ' && line[hexLength+1] != '*') {
+		return nil, "", ErrInvalidManifest
+	}
+	digest, err := hex.DecodeString(line[:hexLength])
+	if err != nil {
+		return nil, "", ErrInvalidManifest
+	}
+
+	name := line[hexLength+2:]
+	if escaped {
+		var unescaped strings.Builder
+		for i := 0; i < len(name); i++ {
+			if name[i] != '\\' {
+				unescaped.WriteByte(name[i])
+				continue
+			}
+			if i++; i == len(name) {
+				return nil, "", ErrInvalidManifest
+			}
+			switch name[i] {
+			case '\\':
+				unescaped.WriteByte('\\')
+			case 'n':
+				unescaped.WriteByte('\n')
+			default:
+				return nil, "", ErrInvalidManifest
+			}
+		}
+		name = unescaped.String()
+	}
+	if !_isLocalManifestName(name) {
+		return nil, "", ErrInvalidManifest
+	}
+
+	return digest, name, nil
+}
+
+// Tell whether a manifest name is a path inside the directory. Names
+// that are absolute or climb out of it with ".." are not.
+func _isLocalManifestName(name string) bool {
+	if name == "" || strings.IndexByte(name, 0) >= 0 {
+		return false
+	}
+	path := filepath.FromSlash(name)
+	if filepath.IsAbs(path) || filepath.VolumeName(path) != "" || strings.HasPrefix(name, "/") {
+		return false
+	}
+	path = filepath.Clean(path)
+
+	return path != "." && path != ".." && !strings.HasPrefix(path, ".."+string(filepath.Separator))
+}
+
+// Verify the files of a checksum manifest, such as one written by
+// GenerateManifest or sha256sum. The paths are relative to the directory.
+// The same hash function as for GenerateManifest must be given. Returns
+// the result of every file, whose error is ErrChecksumMismatch if the
+// file is changed, and an error if the manifest is malformed or names a
+// file outside the directory.
+func VerifyManifest(dir string, manifest []byte, optFns ...CryptographyOptionsFunc) ([]ManifestResult, error) {
+	options, err := _hashOptions(optFns...)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []ManifestResult
+	scanner := bufio.NewScanner(bytes.NewReader(manifest))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		digest, name, err := _parseManifestLine(line, options.Hash.Size())
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d", err, lineNumber)
+		}
+
+		result := ManifestResult{Path: name}
+		actual, err := _hashFile(options.Hash, filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			result.Err = err
+		} else if !bytes.Equal(actual, digest) {
+			result.Err = ErrChecksumMismatch
+		}
+		results = append(results, result)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
@@ -0,0 +1,218 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cryptography
+
+import (
+	"crypto"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHashReader hashes known data with every hash function
+func TestHashReader(t *testing.T) {
+	cases := map[string]struct {
+		hash   crypto.Hash
+		digest string
+	}{
+		"SHA-256": {
+			hash:   crypto.SHA256,
+			digest: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		},
+		"SHA-512": {
+			hash:   crypto.SHA512,
+			digest: "ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f",
+		},
+		"SHA3-256": {
+			hash:   crypto.SHA3_256,
+			digest: "3a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532",
+		},
+		"BLAKE2b-512": {
+			hash:   crypto.BLAKE2b_512,
+			digest: "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			h, err := ParseHash(name)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.hash, h)
+
+			digest, err := HashReader(strings.NewReader("abc"), WithHash(tc.hash))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.digest, hex.EncodeToString(digest))
+		})
+	}
+
+	digest, err := HashReader(strings.NewReader("abc"))
+	assert.NoError(t, err)
+	assert.Equal(t, cases["SHA-256"].digest, hex.EncodeToString(digest))
+
+	h, err := ParseHash("blake2b-256")
+	assert.NoError(t, err)
+	assert.Equal(t, crypto.BLAKE2b_256, h)
+	_, err = ParseHash("md5")
+	assert.Error(t, err)
+}
+
+// TestHashFile hashes a file
+func TestHashFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "axolotl.txt")
+	assert.NoError(t, os.WriteFile(filename, []byte("abc"), 0600))
+
+	digest, err := HashFile(filename, WithHash(crypto.SHA512))
+	assert.NoError(t, err)
+	expected, err := HashReader(strings.NewReader("abc"), WithHash(crypto.SHA512))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, digest)
+
+	_, err = HashFile(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+// TestSignVerifyHMAC computes and verifies HMACs
+func TestSignVerifyHMAC(t *testing.T) {
+	// RFC 4231 test case 2
+	data := []byte("what do ya want for nothing?")
+	key := []byte("Jefe")
+
+	mac, err := SignHMAC(data, key)
+	assert.NoError(t, err)
+	assert.Equal(t, "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843", hex.EncodeToString(mac))
+	assert.NoError(t, VerifyHMAC(data, key, mac))
+
+	mac512, err := SignHMAC(data, key, WithHash(crypto.SHA512))
+	assert.NoError(t, err)
+	assert.Len(t, mac512, 64)
+	assert.NoError(t, VerifyHMAC(data, key, mac512, WithHash(crypto.SHA512)))
+
+	assert.Equal(t, ErrHMACVerification, VerifyHMAC(data, key, mac512))
+	assert.Equal(t, ErrHMACVerification, VerifyHMAC(data, []byte("Jeff"), mac))
+	assert.Equal(t, ErrHMACVerification, VerifyHMAC([]byte("what do ya want?"), key, mac))
+	assert.Equal(t, ErrHMACVerification, VerifyHMAC(data, key, mac[:16]))
+}
+
+// TestGenerateVerifyManifest generates and verifies the checksums of
+// a directory
+func TestGenerateVerifyManifest(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0700))
+	files := map[string]string{
+		"b.txt":           "hello world",
+		"a.txt":           "abc",
+		"sub/c.txt":       "",
+		"sub/back\\slash": "escaped",
+	}
+	for name, content := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(content), 0600))
+	}
+
+	manifest, err := GenerateManifest(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, ""+
+		"ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad  a.txt\n"+
+		"b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9  b.txt\n"+
+		"\\044c5f4a04d6114914bde9e6ef5e5c8001e5b15101114d235aa61cdde7c6d718  sub/back\\\\slash\n"+
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  sub/c.txt\n",
+		string(manifest))
+
+	results, err := VerifyManifest(dir, manifest)
+	assert.NoError(t, err)
+	assert.Len(t, results, 4)
+	for _, result := range results {
+		assert.NoError(t, result.Err, result.Path)
+	}
+
+	// Changed and missing files are reported
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("abd"), 0600))
+	assert.NoError(t, os.Remove(filepath.Join(dir, "sub", "c.txt")))
+	results, err = VerifyManifest(dir, append(manifest, []byte("e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855 *b.txt\n")...))
+	assert.NoError(t, err)
+	assert.Len(t, results, 5)
+	assert.Equal(t, ErrChecksumMismatch, results[0].Err)
+	assert.NoError(t, results[1].Err)
+	assert.NoError(t, results[2].Err)
+	assert.True(t, os.IsNotExist(results[3].Err))
+	assert.Equal(t, "b.txt", results[4].Path)
+	assert.Equal(t, ErrChecksumMismatch, results[4].Err)
+
+	_, err = VerifyManifest(dir, []byte("abc  a.txt\n"))
+	assert.ErrorIs(t, err, ErrInvalidManifest)
+	_, err = VerifyManifest(dir, manifest, WithHash(crypto.SHA512))
+	assert.ErrorIs(t, err, ErrInvalidManifest)
+}
+
+// TestVerifyManifestNonLocal checks that names outside the directory
+// are rejected
+func TestVerifyManifestNonLocal(t *testing.T) {
+	dir := t.TempDir()
+	digest := "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+	cases := map[string]string{
+		"parent":           "../secret.txt",
+		"nested parent":    "sub/../../secret.txt",
+		"dot dot":          "..",
+		"absolute":         "/etc/passwd",
+		"directory itself": ".",
+	}
+
+	for name, path := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := VerifyManifest(dir, []byte(digest+"  "+path+"\n"))
+			assert.ErrorIs(t, err, ErrInvalidManifest, "VerifyManifest() with %q = %v", path, err)
+		})
+	}
+
+	// Names that only look like parents are local
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "..a"), nil, 0600))
+	results, err := VerifyManifest(dir, []byte(digest+"  sub/../..a\n"))
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.NoError(t, results[0].Err)
+}
+
+// TestGenerateManifestOutput writes a manifest into the directory it lists
+func TestGenerateManifestOutput(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("abc"), 0600))
+	outputFilename := filepath.Join(dir, "SHA512SUMS")
+	assert.NoError(t, os.WriteFile(outputFilename, []byte("old"), 0600))
+
+	manifest, err := GenerateManifest(dir, WithHash(crypto.SHA512), WithOutputFilename(outputFilename))
+	assert.NoError(t, err)
+	assert.Nil(t, manifest)
+
+	manifest, err = os.ReadFile(outputFilename)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(manifest), "\n"))
+	assert.True(t, strings.HasSuffix(string(manifest), "  a.txt\n"))
+	results, err := VerifyManifest(dir, manifest, WithHash(crypto.SHA512))
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.NoError(t, results[0].Err)
+}
@@ -0,0 +1,285 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cryptography
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// KDF identifies the function used to derive a key from a passphrase
+type KDF byte
+
+// Supported key derivation functions. The values are stored in the
+// ciphertext header and must not change.
+const (
+	// The key is given directly instead of being derived, see EncryptWithKey
+	KDFNone     KDF = 0
+	KDFArgon2id KDF = 1
+	KDFScrypt   KDF = 2
+	KDFPBKDF2   KDF = 3
+)
+
+// Length of the derived key, which selects AES-256
+const derivedKeyLength = 32
+
+// Argon2idParams are the cost parameters of Argon2id. Memory is in KiB.
+type Argon2idParams struct {
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+}
+
+// ScryptParams are the cost parameters of scrypt. N must be a power of two.
+type ScryptParams struct {
+	N uint32
+	R uint32
+	P uint32
+}
+
+// PBKDF2Params are the cost parameters of PBKDF2 with HMAC-SHA256
+type PBKDF2Params struct {
+	Iterations uint32
+}
+
+// Default parameters, following the recommendations of the
+// golang.org/x/crypto documentation and OWASP.
+var (
+	DefaultArgon2idParams = Argon2idParams{Time: 1, Memory: 64 * 1024, Threads: 4}
+	DefaultScryptParams   = ScryptParams{N: 1 << 15, R: 8, P: 1}
+	DefaultPBKDF2Params   = PBKDF2Params{Iterations: 600000}
+)
+
+// Upper bounds of the parameters accepted when encrypting and decrypting.
+// A header is read before the passphrase can be checked, so these limit
+// a crafted header to about 1 GiB of memory and a few seconds of CPU
+// per message. maxArgon2idMemory is in KiB like Argon2idParams.Memory,
+// maxScryptMemory bounds the 128·N·r·p bytes scrypt allocates.
+const (
+	maxArgon2idMemory = 1024 * 1024
+	maxArgon2idTime   = 10
+	maxScryptMemory   = 1 << 30
+	maxPBKDF2Rounds   = 10000000
+)
+
+// WithKDF is a helper function to construct functional options
+// that sets the key derivation function used by Encrypt.
+func WithKDF(kdf KDF) CryptographyOptionsFunc {
+	return func(o *CryptographyOptions) error {
+		if _, ok := kdfParamsLength[kdf]; !ok || kdf == KDFNone {
+			return fmt.Errorf("Unsupported key derivation function %d", kdf)
+		}
+		o.KDF = kdf
+		return nil
+	}
+}
+
+// WithArgon2idParams is a helper function to construct functional options
+// that sets the Argon2id parameters and selects Argon2id.
+func WithArgon2idParams(params Argon2idParams) CryptographyOptionsFunc {
+	return func(o *CryptographyOptions) error {
+		if err := params.validate(); err != nil {
+			return err
+		}
+		o.KDF = KDFArgon2id
+		o.Argon2idParams = params
+		return nil
+	}
+}
+
+// WithScryptParams is a helper function to construct functional options
+// that sets the scrypt parameters and selects scrypt.
+func WithScryptParams(params ScryptParams) CryptographyOptionsFunc {
+	return func(o *CryptographyOptions) error {
+		if err := params.validate(); err != nil {
+			return err
+		}
+		o.KDF = KDFScrypt
+		o.ScryptParams = params
+		return nil
+	}
+}
+
+// WithPBKDF2Params is a helper function to construct functional options
+// that sets the PBKDF2 parameters and selects PBKDF2.
+func WithPBKDF2Params(params PBKDF2Params) CryptographyOptionsFunc {
+	return func(o *CryptographyOptions) error {
+		if err := params.validate(); err != nil {
+			return err
+		}
+		o.KDF = KDFPBKDF2
+		o.PBKDF2Params = params
+		return nil
+	}
+}
+
+// Check the Argon2id parameters
+func (p Argon2idParams) validate() error {
+	if p.Time < 1 || p.Time > maxArgon2idTime || p.Threads < 1 ||
+		p.Memory < 8*uint32(p.Threads) || p.Memory > maxArgon2idMemory {
+		return fmt.Errorf("Invalid Argon2id parameters: %+v", p)
+	}
+
+	return nil
+}
+
+// Check the scrypt parameters
+func (p ScryptParams) validate() error {
+	if p.N < 2 || p.N&(p.N-1) != 0 || p.R < 1 || p.P < 1 ||
+		uint64(p.R)*uint64(p.P) > maxScryptMemory/(128*uint64(p.N)) {
+		return fmt.Errorf("Invalid scrypt parameters: %+v", p)
+	}
+
+	return nil
+}
+
+// Check the PBKDF2 parameters
+func (p PBKDF2Params) validate() error {
+	if p.Iterations < 1 || p.Iterations > maxPBKDF2Rounds {
+		return fmt.Errorf("Invalid PBKDF2 parameters: %+v", p)
+	}
+
+	return nil
+}
+
+// Length of the encoded parameters of each key derivation function
+var kdfParamsLength = map[KDF]int{
+	KDFNone:     0,
+	KDFArgon2id: 9,
+	KDFScrypt:   12,
+	KDFPBKDF2:   4,
+}
+
+// A key derivation function and its parameters. Only the parameters
+// of the selected function are stored in the header.
+type kdfParams struct {
+	KDF            KDF
+	Argon2idParams Argon2idParams
+	ScryptParams   ScryptParams
+	PBKDF2Params   PBKDF2Params
+}
+
+// Get the KDF selected by the options, filling in default parameters
+func newKDFParams(options *CryptographyOptions) kdfParams {
+	params := kdfParams{
+		KDF:            options.KDF,
+		Argon2idParams: options.Argon2idParams,
+		ScryptParams:   options.ScryptParams,
+		PBKDF2Params:   options.PBKDF2Params,
+	}
+	if params.KDF == 0 {
+		params.KDF = KDFArgon2id
+	}
+	if params.Argon2idParams == (Argon2idParams{}) {
+		params.Argon2idParams = DefaultArgon2idParams
+	}
+	if params.ScryptParams == (ScryptParams{}) {
+		params.ScryptParams = DefaultScryptParams
+	}
+	if params.PBKDF2Params == (PBKDF2Params{}) {
+		params.PBKDF2Params = DefaultPBKDF2Params
+	}
+
+	return params
+}
+
+// Derive a key from the passphrase and the salt
+func (k *kdfParams) deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	switch k.KDF {
+	case KDFArgon2id:
+		p := k.Argon2idParams
+		return argon2.IDKey([]byte(passphrase), salt, p.Time, p.Memory, p.Threads, derivedKeyLength), nil
+	case KDFScrypt:
+		p := k.ScryptParams
+		return scrypt.Key([]byte(passphrase), salt, int(p.N), int(p.R), int(p.P), derivedKeyLength)
+	case KDFPBKDF2:
+		return pbkdf2.Key([]byte(passphrase), salt, int(k.PBKDF2Params.Iterations), derivedKeyLength, sha256.New), nil
+	case KDFNone:
+		return nil, ErrKeyRequired
+	}
+
+	return nil, fmt.Errorf("Unsupported key derivation function %d", k.KDF)
+}
+
+// Write the KDF id and its parameters in big-endian order
+func (k *kdfParams) marshal(buffer *bytes.Buffer) {
+	buffer.WriteByte(byte(k.KDF))
+	switch k.KDF {
+	case KDFArgon2id:
+		binary.Write(buffer, binary.BigEndian, k.Argon2idParams.Time)
+		binary.Write(buffer, binary.BigEndian, k.Argon2idParams.Memory)
+		buffer.WriteByte(k.Argon2idParams.Threads)
+	case KDFScrypt:
+		binary.Write(buffer, binary.BigEndian, k.ScryptParams)
+	case KDFPBKDF2:
+		binary.Write(buffer, binary.BigEndian, k.PBKDF2Params.Iterations)
+	}
+}
+
+// Read the KDF id and its parameters. Returns the number of bytes read.
+func (k *kdfParams) unmarshal(data []byte) (int, error) {
+	if len(data) < 1 {
+		return 0, ErrInvalidHeader
+	}
+	k.KDF = KDF(data[0])
+	paramsLength, ok := kdfParamsLength[k.KDF]
+	if !ok {
+		return 0, fmt.Errorf("%w: unsupported key derivation function %d", ErrInvalidHeader, k.KDF)
+	}
+	if len(data) < 1+paramsLength {
+		return 0, ErrInvalidHeader
+	}
+
+	params := data[1 : 1+paramsLength]
+	var err error
+	switch k.KDF {
+	case KDFArgon2id:
+		k.Argon2idParams = Argon2idParams{
+			Time:    binary.BigEndian.Uint32(params[0:4]),
+			Memory:  binary.BigEndian.Uint32(params[4:8]),
+			Threads: params[8],
+		}
+		err = k.Argon2idParams.validate()
+	case KDFScrypt:
+		k.ScryptParams = ScryptParams{
+			N: binary.BigEndian.Uint32(params[0:4]),
+			R: binary.BigEndian.Uint32(params[4:8]),
+			P: binary.BigEndian.Uint32(params[8:12]),
+		}
+		err = k.ScryptParams.validate()
+	case KDFPBKDF2:
+		k.PBKDF2Params = PBKDF2Params{Iterations: binary.BigEndian.Uint32(params)}
+		err = k.PBKDF2Params.validate()
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidHeader, err)
+	}
+
+	return 1 + paramsLength, nil
+}
@@ -0,0 +1,176 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cryptography
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Cheap parameters so that the tests run quickly
+var (
+	testArgon2idParams = Argon2idParams{Time: 1, Memory: 64, Threads: 1}
+	testScryptParams   = ScryptParams{N: 1 << 4, R: 8, P: 1}
+	testPBKDF2Params   = PBKDF2Params{Iterations: 10}
+)
+
+// TestEncryptDecryptKDF encrypts and decrypts with every key derivation function
+func TestEncryptDecryptKDF(t *testing.T) {
+	data := []byte("The quick brown fox jumps over the lazy dog")
+	passphrase := "iamthebest"
+
+	cases := map[string]struct {
+		optFn     CryptographyOptionsFunc
+		expectKDF KDF
+	}{
+		"default": {
+			expectKDF: KDFArgon2id,
+		},
+		"argon2id": {
+			optFn:     WithArgon2idParams(testArgon2idParams),
+			expectKDF: KDFArgon2id,
+		},
+		"scrypt": {
+			optFn:     WithScryptParams(testScryptParams),
+			expectKDF: KDFScrypt,
+		},
+		"pbkdf2": {
+			optFn:     WithPBKDF2Params(testPBKDF2Params),
+			expectKDF: KDFPBKDF2,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			var optFns []CryptographyOptionsFunc
+			if c.optFn != nil {
+				optFns = append(optFns, c.optFn)
+			}
+
+			encrypted, err := Encrypt(data, passphrase, optFns...)
+			assert.NoError(t, err)
+			header, _, _, err := unmarshalEnvelope(encrypted)
+			assert.NoError(t, err)
+			assert.Equal(t, c.expectKDF, header.KDF.KDF)
+
+			// A new salt is used for every message
+			again, err := Encrypt(data, passphrase, optFns...)
+			assert.NoError(t, err)
+			againHeader, _, _, err := unmarshalEnvelope(again)
+			assert.NoError(t, err)
+			assert.NotEqual(t, header.Salt, againHeader.Salt)
+
+			decrypted, err := Decrypt(encrypted, passphrase)
+			assert.NoError(t, err)
+			assert.Equal(t, data, decrypted)
+
+			_, err = Decrypt(encrypted, "notthebest")
+			assert.Error(t, err)
+		})
+	}
+}
+
+// TestDecryptLegacy decrypts data encrypted with the hash of the passphrase
+func TestDecryptLegacy(t *testing.T) {
+	data := []byte("Have you ever seen a caterpillar eat an apple?")
+
+	encrypted, err := Encrypt(data, "iamthebest", WithHashFunc(CreateHash))
+	assert.NoError(t, err)
+	assert.False(t, IsEncrypted(encrypted))
+
+	decrypted, err := Decrypt(encrypted, "iamthebest")
+	assert.NoError(t, err)
+	assert.Equal(t, data, decrypted)
+}
+
+// TestKDFOptionsInvalid checks that invalid KDF options are rejected
+func TestKDFOptionsInvalid(t *testing.T) {
+	cases := map[string]CryptographyOptionsFunc{
+		"unknown kdf":       WithKDF(KDF(9)),
+		"argon2id threads":  WithArgon2idParams(Argon2idParams{Time: 1, Memory: 64}),
+		"scrypt n":          WithScryptParams(ScryptParams{N: 3, R: 8, P: 1}),
+		"pbkdf2 iterations": WithPBKDF2Params(PBKDF2Params{}),
+	}
+
+	for name, optFn := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := Encrypt([]byte("data"), "iamthebest", optFn)
+			assert.Error(t, err)
+		})
+	}
+}
+
+// TestDecryptKDFParamsTooLarge checks that a header asking for more
+// memory or time than the limits is rejected before the key is derived.
+// Deriving the key with any of these parameters would not finish.
+func TestDecryptKDFParamsTooLarge(t *testing.T) {
+	uint32s := func(values ...uint32) []byte {
+		var buffer bytes.Buffer
+		binary.Write(&buffer, binary.BigEndian, values)
+		return buffer.Bytes()
+	}
+
+	cases := map[string]struct {
+		optFn  CryptographyOptionsFunc
+		params []byte
+	}{
+		"argon2id memory": {
+			optFn:  WithArgon2idParams(testArgon2idParams),
+			params: append(uint32s(1, 4*1024*1024), 1),
+		},
+		"argon2id time": {
+			optFn:  WithArgon2idParams(testArgon2idParams),
+			params: append(uint32s(100, 64), 1),
+		},
+		"scrypt n and r": {
+			optFn:  WithScryptParams(testScryptParams),
+			params: uint32s(1<<22, 1024, 1),
+		},
+		"scrypt p": {
+			optFn:  WithScryptParams(testScryptParams),
+			params: uint32s(1<<15, 8, 1024),
+		},
+		"pbkdf2 iterations": {
+			optFn:  WithPBKDF2Params(testPBKDF2Params),
+			params: uint32s(100000000),
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			encrypted, err := Encrypt([]byte("data"), "iamthebest", c.optFn)
+			assert.NoError(t, err)
+
+			// The parameters follow the magic, the version, the cipher and the KDF id
+			copy(encrypted[len(envelopeMagic)+3:], c.params)
+
+			_, err = Decrypt(encrypted, "iamthebest")
+			assert.ErrorIs(t, err, ErrInvalidHeader)
+			_, err = NewDecryptReader(bytes.NewReader(encrypted), "iamthebest")
+			assert.ErrorIs(t, err, ErrInvalidHeader)
+		})
+	}
+}
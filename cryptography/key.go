@@ -0,0 +1,74 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cryptography
+
+import (
+	"crypto/rand"
+)
+
+// Length of a data key
+const DataKeyLength = derivedKeyLength
+
+// Generate a random 256-bit data key for EncryptWithKey
+func GenerateDataKey() ([]byte, error) {
+	key := make([]byte, DataKeyLength)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// Encrypt data with a 256-bit key instead of a passphrase. The output
+// is an envelope like the output of Encrypt, without KDF parameters.
+// Returns the encrypted data and an error if any.
+func EncryptWithKey(data []byte, key []byte, optFns ...CryptographyOptionsFunc) ([]byte, error) {
+	var options CryptographyOptions
+	if err := options.Merge(optFns...); err != nil {
+		return nil, err
+	}
+	if len(key) != DataKeyLength {
+		return nil, ErrInvalidKeyLength
+	}
+
+	return _sealEnvelope(data, secret{key: key}, &options)
+}
+
+// Decrypt data encrypted by EncryptWithKey. Returns the decrypted data
+// and an error if any.
+func DecryptWithKey(data []byte, key []byte, optFns ...CryptographyOptionsFunc) ([]byte, error) {
+	var options CryptographyOptions
+	if err := options.Merge(optFns...); err != nil {
+		return nil, err
+	}
+	if len(key) != DataKeyLength {
+		return nil, ErrInvalidKeyLength
+	}
+
+	header, headerBytes, ciphertext, err := unmarshalEnvelope(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return _decryptEnvelope(header, headerBytes, ciphertext, secret{key: key}, &options)
+}
@@ -0,0 +1,86 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cryptography
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEncryptDecryptWithKey encrypts and decrypts with a data key
+func TestEncryptDecryptWithKey(t *testing.T) {
+	data := []byte("The quick brown fox jumps over the lazy dog")
+
+	key, err := GenerateDataKey()
+	assert.NoError(t, err)
+	assert.Len(t, key, DataKeyLength)
+	otherKey, err := GenerateDataKey()
+	assert.NoError(t, err)
+	assert.NotEqual(t, key, otherKey)
+
+	for _, c := range []Cipher{CipherAES256GCM, CipherChaCha20Poly1305, CipherXChaCha20Poly1305} {
+		t.Run(c.String(), func(t *testing.T) {
+			encrypted, err := EncryptWithKey(data, key, WithCipher(c), WithAssociatedData([]byte("id")))
+			assert.NoError(t, err)
+			assert.True(t, IsEncrypted(encrypted))
+			header, _, _, err := unmarshalEnvelope(encrypted)
+			assert.NoError(t, err)
+			assert.Equal(t, KDFNone, header.KDF.KDF)
+			assert.Empty(t, header.Salt)
+
+			decrypted, err := DecryptWithKey(encrypted, key, WithAssociatedData([]byte("id")))
+			assert.NoError(t, err)
+			assert.Equal(t, data, decrypted)
+
+			_, err = DecryptWithKey(encrypted, otherKey, WithAssociatedData([]byte("id")))
+			assert.Error(t, err)
+			_, err = DecryptWithKey(encrypted, key)
+			assert.Error(t, err)
+		})
+	}
+}
+
+// TestEncryptWithKeyMismatch checks that keys and passphrases are not mixed up
+func TestEncryptWithKeyMismatch(t *testing.T) {
+	key, err := GenerateDataKey()
+	assert.NoError(t, err)
+
+	_, err = EncryptWithKey([]byte("data"), key[:16])
+	assert.Equal(t, ErrInvalidKeyLength, err)
+
+	encrypted, err := EncryptWithKey([]byte("data"), key)
+	assert.NoError(t, err)
+	_, err = Decrypt(encrypted, "iamthebest")
+	assert.Equal(t, ErrKeyRequired, err)
+	_, err = DecryptWithKey(encrypted, key[:16])
+	assert.Equal(t, ErrInvalidKeyLength, err)
+
+	encrypted, err = Encrypt([]byte("data"), "iamthebest", WithPBKDF2Params(testPBKDF2Params))
+	assert.NoError(t, err)
+	_, err = DecryptWithKey(encrypted, key)
+	assert.Equal(t, ErrPassphraseRequired, err)
+
+	_, err = DecryptWithKey([]byte("data"), key)
+	assert.Equal(t, ErrInvalidHeader, err)
+}
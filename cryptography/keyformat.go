@@ -0,0 +1,256 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cryptography
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// PEM block types
+const (
+	pemTypeRSAPrivateKey       = "RSA PRIVATE KEY"
+	pemTypeECPrivateKey        = "EC PRIVATE KEY"
+	pemTypePrivateKey          = "PRIVATE KEY"
+	pemTypeEncryptedPrivateKey = "ENCRYPTED PRIVATE KEY"
+	pemTypeRSAPublicKey        = "RSA PUBLIC KEY"
+	pemTypePublicKey           = "PUBLIC KEY"
+)
+
+// ErrKeyPassphraseRequired is returned when an encrypted private key
+// is parsed without a passphrase.
+var ErrKeyPassphraseRequired = errors.New("Private key is encrypted, a passphrase is required")
+
+// Get the ID of a public key, which is the SHA-256 hash of its
+// PKIX DER encoding.
+func PublicKeyID(publicKey crypto.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	id := sha256.Sum256(der)
+
+	return id[:], nil
+}
+
+// Serialize a private key as DER. The format is PKCS#8 by default and
+// can be set with WithPrivateKeyFormat. With WithKeyPassphrase the key
+// is encrypted, which requires PKCS#8.
+func MarshalPrivateKeyDER(privateKey crypto.PrivateKey, optFns ...CryptographyOptionsFunc) ([]byte, error) {
+	der, _, err := _marshalPrivateKey(privateKey, optFns)
+
+	return der, err
+}
+
+// Serialize a private key as PEM. The options are the same as for
+// MarshalPrivateKeyDER.
+func MarshalPrivateKeyPEM(privateKey crypto.PrivateKey, optFns ...CryptographyOptionsFunc) ([]byte, error) {
+	der, pemType, err := _marshalPrivateKey(privateKey, optFns)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: pemType, Bytes: der}), nil
+}
+
+// Serialize a private key. Returns the DER and its PEM type.
+func _marshalPrivateKey(privateKey crypto.PrivateKey, optFns []CryptographyOptionsFunc) ([]byte, string, error) {
+	options := CryptographyOptions{PrivateKeyFormat: KeyFormatPKCS8}
+	if err := options.Merge(optFns...); err != nil {
+		return nil, "", err
+	}
+	if options.PrivateKeyFormat != KeyFormatPKCS8 && options.KeyPassphrase != "" {
+		return nil, "", fmt.Errorf("Encrypted private keys must be in PKCS#8 format")
+	}
+
+	switch options.PrivateKeyFormat {
+	case KeyFormatPKCS1:
+		rsaKey, ok := privateKey.(*rsa.PrivateKey)
+		if !ok {
+			return nil, "", fmt.Errorf("PKCS#1 supports RSA keys only")
+		}
+		return x509.MarshalPKCS1PrivateKey(rsaKey), pemTypeRSAPrivateKey, nil
+	case KeyFormatSEC1:
+		ecKey, ok := privateKey.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, "", fmt.Errorf("SEC 1 supports ECDSA keys only")
+		}
+		der, err := x509.MarshalECPrivateKey(ecKey)
+		return der, pemTypeECPrivateKey, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, "", err
+	}
+	if options.KeyPassphrase == "" {
+		return der, pemTypePrivateKey, nil
+	}
+	encrypted, err := EncryptPKCS8PrivateKey(der, options.KeyPassphrase)
+
+	return encrypted, pemTypeEncryptedPrivateKey, err
+}
+
+// Serialize a public key as PEM. The format is PKIX by default, or
+// PKCS#1 for RSA keys with WithPublicKeyFormat(KeyFormatPKCS1).
+func MarshalPublicKeyPEM(publicKey crypto.PublicKey, optFns ...CryptographyOptionsFunc) ([]byte, error) {
+	var options CryptographyOptions
+	if err := options.Merge(optFns...); err != nil {
+		return nil, err
+	}
+
+	if options.PublicKeyFormat == KeyFormatPKCS1 {
+		rsaKey, ok := publicKey.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("PKCS#1 supports RSA keys only")
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: pemTypeRSAPublicKey, Bytes: x509.MarshalPKCS1PublicKey(rsaKey)}), nil
+	}
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: pemTypePublicKey, Bytes: der}), nil
+}
+
+// Parse a private key in PEM or DER, as PKCS#1, SEC 1, PKCS#8 or
+// encrypted PKCS#8. The format must match the PEM type. Encrypted keys
+// need WithKeyPassphrase. The key is an *rsa.PrivateKey,
+// *ecdsa.PrivateKey or ed25519.PrivateKey.
+func ParsePrivateKey(data []byte, optFns ...CryptographyOptionsFunc) (crypto.PrivateKey, error) {
+	var options CryptographyOptions
+	if err := options.Merge(optFns...); err != nil {
+		return nil, err
+	}
+
+	der := data
+	pemType := ""
+	if block, _ := pem.Decode(data); block != nil {
+		switch block.Type {
+		case pemTypeRSAPrivateKey, pemTypeECPrivateKey, pemTypePrivateKey, pemTypeEncryptedPrivateKey:
+		default:
+			return nil, fmt.Errorf("Unexpected PEM type %q for a private key", block.Type)
+		}
+		der = block.Bytes
+		pemType = block.Type
+	}
+
+	if pemType == "" || pemType == pemTypeRSAPrivateKey {
+		if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+			return key, nil
+		}
+	}
+	if pemType == "" || pemType == pemTypePrivateKey {
+		if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+			return key, nil
+		}
+	}
+	if pemType == "" || pemType == pemTypeECPrivateKey {
+		if key, err := x509.ParseECPrivateKey(der); err == nil {
+			return key, nil
+		}
+	}
+	if pemType != "" && pemType != pemTypeEncryptedPrivateKey {
+		return nil, fmt.Errorf("Cannot parse private key as %s", pemType)
+	}
+
+	// Try DER as an encrypted key too
+	if options.KeyPassphrase == "" {
+		if pemType == pemTypeEncryptedPrivateKey {
+			return nil, ErrKeyPassphraseRequired
+		}
+		return nil, fmt.Errorf("Cannot parse private key")
+	}
+	decrypted, err := DecryptPKCS8PrivateKey(der, options.KeyPassphrase)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(decrypted)
+	if err != nil {
+		return nil, ErrIncorrectPassphrase
+	}
+
+	return key, nil
+}
+
+// Parse a public key in PEM or DER, as PKIX or PKCS#1, or a line of
+// an OpenSSH authorized_keys file. The format must match the PEM type.
+// The key is an *rsa.PublicKey, *ecdsa.PublicKey or ed25519.PublicKey.
+func ParsePublicKey(data []byte) (crypto.PublicKey, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		switch block.Type {
+		case pemTypePublicKey:
+			if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+				return key, nil
+			}
+		case pemTypeRSAPublicKey:
+			if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+				return key, nil
+			}
+		default:
+			return nil, fmt.Errorf("Unexpected PEM type %q for a public key", block.Type)
+		}
+		return nil, fmt.Errorf("Cannot parse public key as %s", block.Type)
+	}
+
+	if key, err := x509.ParsePKIXPublicKey(data); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PublicKey(data); err == nil {
+		return key, nil
+	}
+	if key, _, _, _, err := ssh.ParseAuthorizedKey(data); err == nil {
+		cryptoKey, ok := key.(ssh.CryptoPublicKey)
+		if !ok {
+			return nil, fmt.Errorf("Unsupported SSH key type %s", key.Type())
+		}
+		return cryptoKey.CryptoPublicKey(), nil
+	}
+
+	return nil, fmt.Errorf("Cannot parse public key")
+}
+
+// Serialize a public key as a line of an OpenSSH authorized_keys file,
+// followed by the comment if it is not empty.
+func MarshalAuthorizedKey(publicKey crypto.PublicKey, comment string) ([]byte, error) {
+	sshKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	line := ssh.MarshalAuthorizedKey(sshKey)
+	if comment != "" {
+		// Replace the trailing newline with the comment
+		line = append(line[:len(line)-1], " "+comment+"\n"...)
+	}
+
+	return line, nil
+}
@@ -0,0 +1,174 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cryptography
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Key pairs of every supported type
+func _testKeyPairs(t *testing.T) map[string]crypto.Signer {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	return map[string]crypto.Signer{"ecdsa": ecKey, "ed25519": edKey}
+}
+
+// TestMarshalParsePrivateKey serializes private keys of every type and parses them
+func TestMarshalParsePrivateKey(t *testing.T) {
+	for name, key := range _testKeyPairs(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, passphrase := range []string{"", "iamthebest"} {
+				data, err := MarshalPrivateKeyPEM(key, WithKeyPassphrase(passphrase))
+				assert.NoError(t, err)
+				parsed, err := ParsePrivateKey(data, WithKeyPassphrase(passphrase))
+				assert.NoError(t, err)
+				assert.Equal(t, key, parsed)
+
+				der, err := MarshalPrivateKeyDER(key, WithKeyPassphrase(passphrase))
+				assert.NoError(t, err)
+				parsed, err = ParsePrivateKey(der, WithKeyPassphrase(passphrase))
+				assert.NoError(t, err)
+				assert.Equal(t, key, parsed)
+			}
+
+			data, err := MarshalPublicKeyPEM(key.Public())
+			assert.NoError(t, err)
+			parsed, err := ParsePublicKey(data)
+			assert.NoError(t, err)
+			assert.Equal(t, key.Public(), parsed)
+
+			line, err := MarshalAuthorizedKey(key.Public(), "axolotl")
+			assert.NoError(t, err)
+			parsed, err = ParsePublicKey(line)
+			assert.NoError(t, err)
+			assert.Equal(t, key.Public(), parsed)
+		})
+	}
+}
+
+// TestMarshalPrivateKeyFormat checks which key formats suit which keys
+func TestMarshalPrivateKeyFormat(t *testing.T) {
+	keys := _testKeyPairs(t)
+
+	data, err := MarshalPrivateKeyPEM(keys["ecdsa"], WithPrivateKeyFormat(KeyFormatSEC1))
+	assert.NoError(t, err)
+	block, _ := pem.Decode(data)
+	assert.Equal(t, "EC PRIVATE KEY", block.Type)
+	parsed, err := ParsePrivateKey(block.Bytes)
+	assert.NoError(t, err)
+	assert.Equal(t, keys["ecdsa"], parsed)
+
+	cases := map[string]struct {
+		key               crypto.PrivateKey
+		optFns            []CryptographyOptionsFunc
+		expectErrorString string
+	}{
+		"pkcs1 with ecdsa": {
+			key:               keys["ecdsa"],
+			optFns:            []CryptographyOptionsFunc{WithPrivateKeyFormat(KeyFormatPKCS1)},
+			expectErrorString: "PKCS#1 supports RSA keys only",
+		},
+		"sec1 with ed25519": {
+			key:               keys["ed25519"],
+			optFns:            []CryptographyOptionsFunc{WithPrivateKeyFormat(KeyFormatSEC1)},
+			expectErrorString: "SEC 1 supports ECDSA keys only",
+		},
+		"sec1 with passphrase": {
+			key:               keys["ecdsa"],
+			optFns:            []CryptographyOptionsFunc{WithPrivateKeyFormat(KeyFormatSEC1), WithKeyPassphrase("iamthebest")},
+			expectErrorString: "Encrypted private keys must be in PKCS#8 format",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := MarshalPrivateKeyPEM(c.key, c.optFns...)
+			assert.EqualError(t, err, c.expectErrorString)
+		})
+	}
+
+	_, err = MarshalPublicKeyPEM(keys["ecdsa"].Public(), WithPublicKeyFormat(KeyFormatPKCS1))
+	assert.EqualError(t, err, "PKCS#1 supports RSA keys only")
+	_, err = MarshalPublicKeyPEM(keys["ecdsa"].Public(), WithPublicKeyFormat(KeyFormatSEC1))
+	assert.Error(t, err, "SEC 1 is not a public key format")
+}
+
+// TestParseKeyPEMTypeMismatch checks that keys are only parsed in the
+// format their PEM type names
+func TestParseKeyPEMTypeMismatch(t *testing.T) {
+	keys := _testKeyPairs(t)
+	sec1, err := MarshalPrivateKeyDER(keys["ecdsa"], WithPrivateKeyFormat(KeyFormatSEC1))
+	assert.NoError(t, err)
+	pkcs8, err := MarshalPrivateKeyDER(keys["ed25519"])
+	assert.NoError(t, err)
+	pkix, err := MarshalPublicKeyPEM(keys["ecdsa"].Public())
+	assert.NoError(t, err)
+	block, _ := pem.Decode(pkix)
+
+	privateCases := map[string]*pem.Block{
+		"sec1 as pkcs1":  {Type: "RSA PRIVATE KEY", Bytes: sec1},
+		"sec1 as pkcs8":  {Type: "PRIVATE KEY", Bytes: sec1},
+		"pkcs8 as sec1":  {Type: "EC PRIVATE KEY", Bytes: pkcs8},
+		"pkcs8 as pkcs1": {Type: "RSA PRIVATE KEY", Bytes: pkcs8},
+	}
+	for name, b := range privateCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParsePrivateKey(pem.EncodeToMemory(b))
+			assert.EqualError(t, err, "Cannot parse private key as "+b.Type)
+		})
+	}
+
+	_, err = ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: block.Bytes}))
+	assert.EqualError(t, err, "Cannot parse public key as RSA PUBLIC KEY")
+
+	// DER has no type, so every format is tried
+	parsed, err := ParsePrivateKey(sec1)
+	assert.NoError(t, err)
+	assert.Equal(t, keys["ecdsa"], parsed)
+}
+
+// TestParsePrivateKeyPassphrase checks the handling of passphrases
+func TestParsePrivateKeyPassphrase(t *testing.T) {
+	data, err := MarshalPrivateKeyPEM(_testKeyPairs(t)["ed25519"], WithKeyPassphrase("iamthebest"))
+	assert.NoError(t, err)
+
+	_, err = ParsePrivateKey(data)
+	assert.Equal(t, ErrKeyPassphraseRequired, err)
+	_, err = ParsePrivateKey(data, WithKeyPassphrase("notthebest"))
+	assert.Equal(t, ErrIncorrectPassphrase, err)
+	_, err = ParsePrivateKey([]byte("hello world"))
+	assert.EqualError(t, err, "Cannot parse private key")
+	_, err = ParsePublicKey([]byte("hello world"))
+	assert.EqualError(t, err, "Cannot parse public key")
+}
@@ -0,0 +1,150 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package keystore
+
+import (
+	cecdsa "crypto/ecdsa"
+	ced25519 "crypto/ed25519"
+	crsa "crypto/rsa"
+	"fmt"
+
+	"github.com/tchiunam/axolgo-lib/cryptography"
+	"github.com/tchiunam/axolgo-lib/cryptography/ecdsa"
+	"github.com/tchiunam/axolgo-lib/cryptography/ed25519"
+	"github.com/tchiunam/axolgo-lib/cryptography/rsa"
+)
+
+// Encrypt data with the latest version of a key. Symmetric keys use
+// cryptography.EncryptWithKey and RSA keys rsa.EncryptRSA, which get
+// the options.
+func Encrypt(store KeyStore, id string, data []byte, optFns ...cryptography.CryptographyOptionsFunc) ([]byte, error) {
+	key, err := store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return _encryptWithKey(key, data, optFns)
+}
+
+// Decrypt data encrypted by Encrypt. The versions of the key are tried
+// from the latest to the first, so data encrypted before a rotation
+// can still be decrypted.
+func Decrypt(store KeyStore, id string, data []byte, optFns ...cryptography.CryptographyOptionsFunc) ([]byte, error) {
+	var decrypted []byte
+	err := _eachVersion(store, id, func(key *Key) (err error) {
+		decrypted, err = _decryptWithKey(key, data, optFns)
+		return err
+	})
+
+	return decrypted, err
+}
+
+// Sign data with the latest version of a key, with rsa.SignRSA,
+// ecdsa.SignECDSA or ed25519.SignEd25519 which get the options.
+func Sign(store KeyStore, id string, data []byte, optFns ...cryptography.CryptographyOptionsFunc) ([]byte, error) {
+	key, err := store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch privateKey := key.PrivateKey.(type) {
+	case *crsa.PrivateKey:
+		return rsa.SignRSA(data, privateKey, optFns...)
+	case *cecdsa.PrivateKey:
+		return ecdsa.SignECDSA(data, privateKey, optFns...)
+	case ced25519.PrivateKey:
+		return ed25519.SignEd25519(data, privateKey, optFns...)
+	}
+
+	return nil, fmt.Errorf("%w for signing: %s", ErrUnsupportedKeyType, key.Type())
+}
+
+// Verify a signature made by Sign. The versions of the key are tried
+// from the latest to the first.
+func Verify(
+	store KeyStore,
+	id string,
+	data []byte,
+	signature []byte,
+	optFns ...cryptography.CryptographyOptionsFunc) error {
+	return _eachVersion(store, id, func(key *Key) error {
+		switch privateKey := key.PrivateKey.(type) {
+		case *crsa.PrivateKey:
+			return rsa.VerifyRSA(data, &privateKey.PublicKey, signature, optFns...)
+		case *cecdsa.PrivateKey:
+			return ecdsa.VerifyECDSA(data, &privateKey.PublicKey, signature, optFns...)
+		case ced25519.PrivateKey:
+			return ed25519.VerifyEd25519(data, privateKey.Public().(ced25519.PublicKey), signature, optFns...)
+		}
+		return fmt.Errorf("%w for signing: %s", ErrUnsupportedKeyType, key.Type())
+	})
+}
+
+// Encrypt data with a key version
+func _encryptWithKey(key *Key, data []byte, optFns []cryptography.CryptographyOptionsFunc) ([]byte, error) {
+	switch key.Type() {
+	case KeyTypeSymmetric:
+		return cryptography.EncryptWithKey(data, key.Secret, optFns...)
+	case KeyTypeRSA:
+		return rsa.EncryptRSA(data, key.PrivateKey.(*crsa.PrivateKey).PublicKey, optFns...)
+	}
+
+	return nil, fmt.Errorf("%w for encryption: %s", ErrUnsupportedKeyType, key.Type())
+}
+
+// Decrypt data with a key version
+func _decryptWithKey(key *Key, data []byte, optFns []cryptography.CryptographyOptionsFunc) ([]byte, error) {
+	switch key.Type() {
+	case KeyTypeSymmetric:
+		return cryptography.DecryptWithKey(data, key.Secret, optFns...)
+	case KeyTypeRSA:
+		return rsa.DecryptRSA(data, key.PrivateKey.(*crsa.PrivateKey), optFns...)
+	}
+
+	return nil, fmt.Errorf("%w for encryption: %s", ErrUnsupportedKeyType, key.Type())
+}
+
+// Call fn with the versions of a key from the latest to the first
+// until it succeeds. Returns the error of the latest version otherwise.
+func _eachVersion(store KeyStore, id string, fn func(*Key) error) error {
+	latest, err := store.Get(id)
+	if err != nil {
+		return err
+	}
+
+	firstErr := fn(latest)
+	if firstErr == nil {
+		return nil
+	}
+	for version := latest.Version - 1; version >= 1; version-- {
+		key, err := store.GetVersion(id, version)
+		if err != nil {
+			return err
+		}
+		if err = fn(key); err == nil {
+			return nil
+		}
+	}
+
+	return firstErr
+}
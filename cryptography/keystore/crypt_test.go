@@ -0,0 +1,108 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package keystore
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tchiunam/axolgo-lib/cryptography"
+)
+
+// Create a key store with a key of every type
+func _testKeyStoreWithKeys(t *testing.T) KeyStore {
+	store := NewMemoryKeyStore()
+	assert.NoError(t, store.Put(_testRSAKey(t, "rsa")))
+	for _, keyType := range []KeyType{KeyTypeSymmetric, KeyTypeECDSA, KeyTypeEd25519} {
+		key, err := GenerateKey(string(keyType), keyType)
+		assert.NoError(t, err)
+		assert.NoError(t, store.Put(key))
+	}
+
+	return store
+}
+
+// TestEncryptDecrypt encrypts with keys by ID, before and after a rotation
+func TestEncryptDecrypt(t *testing.T) {
+	store := _testKeyStoreWithKeys(t)
+	data := bytes.Repeat([]byte("hello world "), 100)
+
+	for _, id := range []string{"symmetric", "rsa"} {
+		t.Run(id, func(t *testing.T) {
+			encrypted, err := Encrypt(store, id, data, cryptography.WithAssociatedData([]byte("axolotl")))
+			assert.NoError(t, err)
+
+			_, err = store.Rotate(id)
+			assert.NoError(t, err)
+			rotatedEncrypted, err := Encrypt(store, id, data)
+			assert.NoError(t, err)
+
+			// Data of both versions can be decrypted
+			decrypted, err := Decrypt(store, id, encrypted, cryptography.WithAssociatedData([]byte("axolotl")))
+			assert.NoError(t, err)
+			assert.Equal(t, data, decrypted)
+			decrypted, err = Decrypt(store, id, rotatedEncrypted)
+			assert.NoError(t, err)
+			assert.Equal(t, data, decrypted)
+
+			_, err = Decrypt(store, id, encrypted)
+			assert.Error(t, err)
+		})
+	}
+
+	for _, id := range []string{"ecdsa", "ed25519"} {
+		_, err := Encrypt(store, id, data)
+		assert.ErrorIs(t, err, ErrUnsupportedKeyType)
+		_, err = Decrypt(store, id, data)
+		assert.ErrorIs(t, err, ErrUnsupportedKeyType)
+	}
+	_, err := Encrypt(store, "missing", data)
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+// TestSignVerify signs with keys by ID, before and after a rotation
+func TestSignVerify(t *testing.T) {
+	store := _testKeyStoreWithKeys(t)
+	data := []byte("hello world")
+
+	for _, id := range []string{"rsa", "ecdsa", "ed25519"} {
+		t.Run(id, func(t *testing.T) {
+			signature, err := Sign(store, id, data)
+			assert.NoError(t, err)
+			_, err = store.Rotate(id)
+			assert.NoError(t, err)
+			rotatedSignature, err := Sign(store, id, data)
+			assert.NoError(t, err)
+
+			assert.NoError(t, Verify(store, id, data, signature))
+			assert.NoError(t, Verify(store, id, data, rotatedSignature))
+			assert.Error(t, Verify(store, id, []byte("hello axolotl"), signature))
+		})
+	}
+
+	_, err := Sign(store, "symmetric", data)
+	assert.ErrorIs(t, err, ErrUnsupportedKeyType)
+	assert.ErrorIs(t, Verify(store, "symmetric", data, nil), ErrUnsupportedKeyType)
+	assert.ErrorIs(t, Verify(store, "missing", data, nil), ErrKeyNotFound)
+}
@@ -0,0 +1,217 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package keystore
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tchiunam/axolgo-lib/cryptography"
+	"github.com/tchiunam/axolgo-lib/util"
+)
+
+// FileKeyStore is a KeyStore that keeps keys in a file encrypted with
+// a passphrase. Every change is written to the file atomically.
+type FileKeyStore struct {
+	mu         sync.RWMutex
+	ring       keyRing
+	filename   string
+	passphrase string
+	optFns     []cryptography.CryptographyOptionsFunc
+}
+
+// A key as stored in the file. The material is the secret of a
+// symmetric key or the PKCS#8 DER of a private key.
+type storedKey struct {
+	ID       string    `json:"id"`
+	Version  int       `json:"version"`
+	Created  time.Time `json:"created"`
+	Type     KeyType   `json:"type"`
+	Material []byte    `json:"material"`
+}
+
+// Open a key store in a file encrypted with the passphrase. The file
+// is created on the first change if it does not exist. The options
+// are passed to cryptography.Encrypt when the file is written.
+func NewFileKeyStore(
+	filename string,
+	passphrase string,
+	optFns ...cryptography.CryptographyOptionsFunc) (*FileKeyStore, error) {
+	s := &FileKeyStore{ring: keyRing{}, filename: filename, passphrase: passphrase, optFns: optFns}
+
+	encrypted, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	content, err := cryptography.Decrypt(encrypted, passphrase, optFns...)
+	if err != nil {
+		return nil, fmt.Errorf("Fail to decrypt key store %s: %w", filename, err)
+	}
+	var storedKeys []storedKey
+	if err = json.Unmarshal(content, &storedKeys); err != nil {
+		return nil, fmt.Errorf("Fail to read key store %s: %w", filename, err)
+	}
+	for _, stored := range storedKeys {
+		key, err := _loadKey(stored)
+		if err != nil {
+			return nil, err
+		}
+		// Versions are stored in order
+		if len(s.ring[key.ID])+1 != key.Version {
+			return nil, fmt.Errorf("Key %s version %d is out of order in key store %s", key.ID, key.Version, filename)
+		}
+		s.ring[key.ID] = append(s.ring[key.ID], key)
+	}
+
+	return s, nil
+}
+
+// Get the latest version of a key
+func (s *FileKeyStore) Get(id string) (*Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ring.get(id)
+}
+
+// Get a version of a key
+func (s *FileKeyStore) GetVersion(id string, version int) (*Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ring.getVersion(id, version)
+}
+
+// Add a key under a new ID as version 1
+func (s *FileKeyStore) Put(key *Key) error {
+	_, err := s._change(func(ring keyRing) (*Key, error) {
+		return nil, ring.put(key)
+	})
+
+	return err
+}
+
+// List the IDs of the keys in order
+func (s *FileKeyStore) List() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ring.list(), nil
+}
+
+// Add a new version of a key of the same type and return it
+func (s *FileKeyStore) Rotate(id string) (*Key, error) {
+	return s._change(func(ring keyRing) (*Key, error) {
+		return ring.rotate(id)
+	})
+}
+
+// Apply a change to a copy of the keys and write it to the file. The
+// keys are only replaced when the file is written.
+func (s *FileKeyStore) _change(fn func(keyRing) (*Key, error)) (*Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ring := s.ring.clone()
+	key, err := fn(ring)
+	if err != nil {
+		return nil, err
+	}
+	if err = s._save(ring); err != nil {
+		return nil, err
+	}
+	s.ring = ring
+
+	return key, nil
+}
+
+// Encrypt the keys and write them to the file atomically
+func (s *FileKeyStore) _save(ring keyRing) error {
+	var storedKeys []storedKey
+	for _, id := range ring.list() {
+		for _, key := range ring[id] {
+			stored, err := _storeKey(key)
+			if err != nil {
+				return err
+			}
+			storedKeys = append(storedKeys, stored)
+		}
+	}
+
+	content, err := json.Marshal(storedKeys)
+	if err != nil {
+		return err
+	}
+	encrypted, err := cryptography.Encrypt(content, s.passphrase, s.optFns...)
+	if err != nil {
+		return err
+	}
+
+	return util.WriteFileAtomic(s.filename, encrypted, 0600)
+}
+
+// Convert a key to the stored form
+func _storeKey(key *Key) (storedKey, error) {
+	stored := storedKey{ID: key.ID, Version: key.Version, Created: key.Created, Type: key.Type()}
+	if stored.Type == KeyTypeSymmetric {
+		stored.Material = key.Secret
+		return stored, nil
+	}
+
+	var err error
+	stored.Material, err = cryptography.MarshalPrivateKeyDER(key.PrivateKey)
+
+	return stored, err
+}
+
+// Convert a stored key back to a key
+func _loadKey(stored storedKey) (*Key, error) {
+	key := &Key{ID: stored.ID, Version: stored.Version, Created: stored.Created}
+	if stored.Type == KeyTypeSymmetric {
+		key.Secret = stored.Material
+	} else {
+		privateKey, err := cryptography.ParsePrivateKey(stored.Material)
+		if err != nil {
+			return nil, fmt.Errorf("Fail to read key %s version %d: %w", stored.ID, stored.Version, err)
+		}
+		signer, ok := privateKey.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("%w for key %s", ErrUnsupportedKeyType, stored.ID)
+		}
+		key.PrivateKey = signer
+	}
+	if key.Type() != stored.Type {
+		return nil, fmt.Errorf("Key %s version %d is not of type %s", stored.ID, stored.Version, stored.Type)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,117 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package keystore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tchiunam/axolgo-lib/cryptography"
+)
+
+// Fast key derivation for tests
+var testKDFOptions = []cryptography.CryptographyOptionsFunc{
+	cryptography.WithKDF(cryptography.KDFPBKDF2),
+	cryptography.WithPBKDF2Params(cryptography.PBKDF2Params{Iterations: 1000}),
+}
+
+// TestFileKeyStore exercises the file key store and reopens it
+func TestFileKeyStore(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "keys.axol")
+	store, err := NewFileKeyStore(filename, "iamthebest", testKDFOptions...)
+	assert.NoError(t, err)
+	_, err = os.Stat(filename)
+	assert.True(t, os.IsNotExist(err))
+
+	_testKeyStore(t, store)
+
+	info, err := os.Stat(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	content, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.True(t, cryptography.IsEncrypted(content))
+
+	reopened, err := NewFileKeyStore(filename, "iamthebest")
+	assert.NoError(t, err)
+	ids, err := reopened.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"rsa", "secret", "signing"}, ids)
+	for _, id := range ids {
+		for _, version := range []int{1, 2} {
+			key, err := store.GetVersion(id, version)
+			assert.NoError(t, err)
+			reopenedKey, err := reopened.GetVersion(id, version)
+			assert.NoError(t, err)
+			assert.Equal(t, key.Secret, reopenedKey.Secret)
+			assert.Equal(t, key.PrivateKey, reopenedKey.PrivateKey)
+			assert.True(t, key.Created.Equal(reopenedKey.Created))
+		}
+	}
+}
+
+// TestFileKeyStoreInvalid opens key stores that cannot be read
+func TestFileKeyStoreInvalid(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "keys.axol")
+	store, err := NewFileKeyStore(filename, "iamthebest", testKDFOptions...)
+	assert.NoError(t, err)
+	key, err := GenerateKey("secret", KeyTypeSymmetric)
+	assert.NoError(t, err)
+	assert.NoError(t, store.Put(key))
+
+	_, err = NewFileKeyStore(filename, "notthebest")
+	assert.ErrorContains(t, err, "Fail to decrypt key store")
+
+	notJSON, err := cryptography.Encrypt([]byte("hello world"), "iamthebest", testKDFOptions...)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filename, notJSON, 0600))
+	_, err = NewFileKeyStore(filename, "iamthebest")
+	assert.ErrorContains(t, err, "Fail to read key store")
+
+	outOfOrder, err := cryptography.Encrypt(
+		[]byte(`[{"id":"secret","version":2,"type":"symmetric","material":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}]`),
+		"iamthebest",
+		testKDFOptions...)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filename, outOfOrder, 0600))
+	_, err = NewFileKeyStore(filename, "iamthebest")
+	assert.ErrorContains(t, err, "out of order")
+
+	// An empty or truncated file is an error rather than a panic
+	for _, content := range [][]byte{{}, notJSON[:20]} {
+		assert.NoError(t, os.WriteFile(filename, content, 0600))
+		_, err = NewFileKeyStore(filename, "iamthebest")
+		assert.ErrorContains(t, err, "Fail to decrypt key store")
+	}
+
+	// A failed write leaves the keys unchanged
+	store, err = NewFileKeyStore(filepath.Join(dir, "missing", "keys.axol"), "iamthebest", testKDFOptions...)
+	assert.NoError(t, err)
+	assert.Error(t, store.Put(key))
+	ids, err := store.List()
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+}
@@ -0,0 +1,250 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package keystore
+
+import (
+	"crypto"
+	cecdsa "crypto/ecdsa"
+	ced25519 "crypto/ed25519"
+	"crypto/elliptic"
+	crsa "crypto/rsa"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/tchiunam/axolgo-lib/cryptography"
+	"github.com/tchiunam/axolgo-lib/cryptography/ecdsa"
+	"github.com/tchiunam/axolgo-lib/cryptography/ed25519"
+	"github.com/tchiunam/axolgo-lib/cryptography/rsa"
+)
+
+// Size of RSA keys generated by GenerateKey
+const DefaultRSABits = 3072
+
+// KeyType is the kind of key material of a key
+type KeyType string
+
+// Supported key types
+const (
+	KeyTypeSymmetric KeyType = "symmetric"
+	KeyTypeRSA       KeyType = "rsa"
+	KeyTypeECDSA     KeyType = "ecdsa"
+	KeyTypeEd25519   KeyType = "ed25519"
+)
+
+// ErrKeyNotFound is returned when a key store has no key with an ID or version
+var ErrKeyNotFound = errors.New("Key not found")
+
+// ErrKeyExists is returned when a key is put with an ID already in use
+var ErrKeyExists = errors.New("Key already exists")
+
+// ErrUnsupportedKeyType is returned when a key cannot be used for an operation
+var ErrUnsupportedKeyType = errors.New("Unsupported key type")
+
+// Key is one version of a key in a key store. A key has either a
+// secret for symmetric encryption or a private key. Key stores keep
+// their own copy of the secret, so changing the secret of a key that
+// was put or returned does not change the stored key.
+type Key struct {
+	ID         string
+	Version    int
+	Created    time.Time
+	Secret     []byte
+	PrivateKey crypto.Signer
+}
+
+// KeyStore keeps keys by ID. Rotating a key adds a new version, which
+// is used from then on, while the old versions stay available to
+// decrypt and verify.
+type KeyStore interface {
+	// Get the latest version of a key
+	Get(id string) (*Key, error)
+	// Get a version of a key
+	GetVersion(id string, version int) (*Key, error)
+	// Add a key under a new ID as version 1
+	Put(key *Key) error
+	// List the IDs of the keys in order
+	List() ([]string, error)
+	// Add a new version of a key of the same type and return it
+	Rotate(id string) (*Key, error)
+}
+
+// Get the type of the key material
+func (k *Key) Type() KeyType {
+	switch k.PrivateKey.(type) {
+	case nil:
+		if len(k.Secret) == cryptography.DataKeyLength {
+			return KeyTypeSymmetric
+		}
+	case *crsa.PrivateKey:
+		return KeyTypeRSA
+	case *cecdsa.PrivateKey:
+		return KeyTypeECDSA
+	case ced25519.PrivateKey:
+		return KeyTypeEd25519
+	}
+
+	return ""
+}
+
+// Copy the key with its own copy of the secret
+func (k *Key) clone() *Key {
+	c := *k
+	if k.Secret != nil {
+		c.Secret = append([]byte{}, k.Secret...)
+	}
+
+	return &c
+}
+
+// Generate a key of a type as version 1. RSA keys have DefaultRSABits
+// bits and ECDSA keys use the curve P-256.
+func GenerateKey(id string, keyType KeyType) (*Key, error) {
+	key := &Key{ID: id, Version: 1, Created: time.Now().UTC()}
+
+	var err error
+	switch keyType {
+	case KeyTypeSymmetric:
+		key.Secret, err = cryptography.GenerateDataKey()
+	case KeyTypeRSA:
+		key.PrivateKey, _, err = rsa.GenerateRSAKeyPair(DefaultRSABits)
+	case KeyTypeECDSA:
+		key.PrivateKey, _, err = ecdsa.GenerateECDSAKeyPair(elliptic.P256())
+	case KeyTypeEd25519:
+		key.PrivateKey, _, err = ed25519.GenerateEd25519KeyPair()
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnsupportedKeyType, keyType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// Generate the next version of a key with the same type and parameters
+func _nextVersion(key *Key) (*Key, error) {
+	next := &Key{ID: key.ID, Version: key.Version + 1, Created: time.Now().UTC()}
+
+	var err error
+	switch privateKey := key.PrivateKey.(type) {
+	case *crsa.PrivateKey:
+		next.PrivateKey, _, err = rsa.GenerateRSAKeyPair(privateKey.N.BitLen())
+	case *cecdsa.PrivateKey:
+		next.PrivateKey, _, err = ecdsa.GenerateECDSAKeyPair(privateKey.Curve)
+	case ced25519.PrivateKey:
+		next.PrivateKey, _, err = ed25519.GenerateEd25519KeyPair()
+	default:
+		next.Secret, err = cryptography.GenerateDataKey()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return next, nil
+}
+
+// The versions of keys by ID, without locking. Keys are never changed
+// once added, so a copy of the map can share them.
+type keyRing map[string][]*Key
+
+// Get the latest version of a key
+func (r keyRing) get(id string) (*Key, error) {
+	versions, ok := r[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+	}
+
+	return versions[len(versions)-1].clone(), nil
+}
+
+// Get a version of a key
+func (r keyRing) getVersion(id string, version int) (*Key, error) {
+	versions := r[id]
+	if version < 1 || version > len(versions) {
+		return nil, fmt.Errorf("%w: %s version %d", ErrKeyNotFound, id, version)
+	}
+
+	return versions[version-1].clone(), nil
+}
+
+// Add a key under a new ID as version 1
+func (r keyRing) put(key *Key) error {
+	if key.ID == "" {
+		return fmt.Errorf("Key ID must not be empty")
+	}
+	if _, ok := r[key.ID]; ok {
+		return fmt.Errorf("%w: %s", ErrKeyExists, key.ID)
+	}
+	if key.Type() == "" {
+		return fmt.Errorf("%w for key %s", ErrUnsupportedKeyType, key.ID)
+	}
+
+	stored := key.clone()
+	stored.Version = 1
+	if stored.Created.IsZero() {
+		stored.Created = time.Now().UTC()
+	}
+	r[key.ID] = []*Key{stored}
+
+	return nil
+}
+
+// List the IDs of the keys in order
+func (r keyRing) list() []string {
+	ids := make([]string, 0, len(r))
+	for id := range r {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+// Add a new version of a key
+func (r keyRing) rotate(id string) (*Key, error) {
+	latest, err := r.get(id)
+	if err != nil {
+		return nil, err
+	}
+	next, err := _nextVersion(latest)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]*Key, len(r[id]), len(r[id])+1)
+	copy(versions, r[id])
+	r[id] = append(versions, next)
+
+	return next.clone(), nil
+}
+
+// Copy the key ring, so that it can be changed without affecting this one
+func (r keyRing) clone() keyRing {
+	c := make(keyRing, len(r))
+	for id, versions := range r {
+		c[id] = versions
+	}
+
+	return c
+}
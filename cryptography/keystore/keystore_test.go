@@ -0,0 +1,117 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package keystore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tchiunam/axolgo-lib/cryptography/rsa"
+)
+
+// Generate an RSA key that is faster to make than one of DefaultRSABits
+func _testRSAKey(t *testing.T, id string) *Key {
+	privateKey, _, err := rsa.GenerateRSAKeyPair(2048)
+	assert.NoError(t, err)
+
+	return &Key{ID: id, PrivateKey: privateKey}
+}
+
+// Exercise a key store, which must be empty
+func _testKeyStore(t *testing.T, store KeyStore) {
+	ids, err := store.List()
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+
+	symmetricKey, err := GenerateKey("secret", KeyTypeSymmetric)
+	assert.NoError(t, err)
+	edKey, err := GenerateKey("signing", KeyTypeEd25519)
+	assert.NoError(t, err)
+	assert.NoError(t, store.Put(symmetricKey))
+	assert.NoError(t, store.Put(edKey))
+	assert.NoError(t, store.Put(_testRSAKey(t, "rsa")))
+	assert.ErrorIs(t, store.Put(edKey), ErrKeyExists)
+	assert.Error(t, store.Put(&Key{ID: "empty"}))
+	assert.Error(t, store.Put(&Key{Secret: symmetricKey.Secret}))
+
+	ids, err = store.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"rsa", "secret", "signing"}, ids)
+
+	key, err := store.Get("secret")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, key.Version)
+	assert.Equal(t, symmetricKey.Secret, key.Secret)
+
+	// The store keeps its own copy of the secret
+	secret := append([]byte{}, symmetricKey.Secret...)
+	symmetricKey.Secret[0] ^= 0xff
+	key.Secret[1] ^= 0xff
+	key, err = store.Get("secret")
+	assert.NoError(t, err)
+	assert.Equal(t, secret, key.Secret)
+	symmetricKey.Secret[0] ^= 0xff
+	_, err = store.Get("missing")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	for _, id := range ids {
+		rotated, err := store.Rotate(id)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, rotated.Version)
+
+		first, err := store.GetVersion(id, 1)
+		assert.NoError(t, err)
+		latest, err := store.Get(id)
+		assert.NoError(t, err)
+		assert.Equal(t, rotated, latest)
+		assert.Equal(t, first.Type(), latest.Type())
+		if first.Type() == KeyTypeSymmetric {
+			assert.NotEqual(t, first.Secret, latest.Secret)
+		} else {
+			assert.NotEqual(t, first.PrivateKey, latest.PrivateKey)
+		}
+	}
+	_, err = store.GetVersion("secret", 3)
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	_, err = store.Rotate("missing")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+// TestGenerateKey generates keys of every type
+func TestGenerateKey(t *testing.T) {
+	for _, keyType := range []KeyType{KeyTypeSymmetric, KeyTypeECDSA, KeyTypeEd25519} {
+		t.Run(string(keyType), func(t *testing.T) {
+			key, err := GenerateKey("axolotl", keyType)
+			assert.NoError(t, err)
+			assert.Equal(t, keyType, key.Type())
+			assert.Equal(t, "axolotl", key.ID)
+			assert.Equal(t, 1, key.Version)
+			assert.False(t, key.Created.IsZero())
+		})
+	}
+
+	_, err := GenerateKey("axolotl", "dsa")
+	assert.EqualError(t, err, `Unsupported key type "dsa"`)
+	assert.Equal(t, KeyTypeRSA, _testRSAKey(t, "axolotl").Type())
+	assert.Equal(t, KeyType(""), (&Key{Secret: []byte("too short")}).Type())
+}
@@ -0,0 +1,78 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package keystore
+
+import (
+	"sync"
+)
+
+// MemoryKeyStore is a KeyStore that keeps keys in memory only
+type MemoryKeyStore struct {
+	mu   sync.RWMutex
+	ring keyRing
+}
+
+// Create an empty key store in memory
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{ring: keyRing{}}
+}
+
+// Get the latest version of a key
+func (s *MemoryKeyStore) Get(id string) (*Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ring.get(id)
+}
+
+// Get a version of a key
+func (s *MemoryKeyStore) GetVersion(id string, version int) (*Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ring.getVersion(id, version)
+}
+
+// Add a key under a new ID as version 1
+func (s *MemoryKeyStore) Put(key *Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.ring.put(key)
+}
+
+// List the IDs of the keys in order
+func (s *MemoryKeyStore) List() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.ring.list(), nil
+}
+
+// Add a new version of a key of the same type and return it
+func (s *MemoryKeyStore) Rotate(id string) (*Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.ring.rotate(id)
+}
@@ -0,0 +1,106 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package keystore
+
+import (
+	"crypto"
+	"fmt"
+)
+
+// Signer is a crypto.Signer of a key in a key store, which also tells
+// the ID and version of the key
+type Signer interface {
+	crypto.Signer
+	KeyID() string
+	KeyVersion() int
+}
+
+// Decrypter is a crypto.Decrypter of a key in a key store, which also
+// tells the ID and version of the key
+type Decrypter interface {
+	crypto.Decrypter
+	KeyID() string
+	KeyVersion() int
+}
+
+// A signer of a key version
+type keySigner struct {
+	crypto.Signer
+	id      string
+	version int
+}
+
+// A decrypter of a key version
+type keyDecrypter struct {
+	crypto.Decrypter
+	id      string
+	version int
+}
+
+// Get the ID of the key
+func (s *keySigner) KeyID() string {
+	return s.id
+}
+
+// Get the version of the key
+func (s *keySigner) KeyVersion() int {
+	return s.version
+}
+
+// Get the ID of the key
+func (d *keyDecrypter) KeyID() string {
+	return d.id
+}
+
+// Get the version of the key
+func (d *keyDecrypter) KeyVersion() int {
+	return d.version
+}
+
+// Get a signer of the latest version of a key. The key must have a
+// private key, so symmetric keys cannot sign.
+func NewSigner(store KeyStore, id string) (Signer, error) {
+	key, err := store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if key.PrivateKey == nil {
+		return nil, fmt.Errorf("%w for signing: %s", ErrUnsupportedKeyType, key.Type())
+	}
+
+	return &keySigner{Signer: key.PrivateKey, id: key.ID, version: key.Version}, nil
+}
+
+// Get a decrypter of a version of a key. Only RSA keys can decrypt.
+func NewDecrypter(store KeyStore, id string, version int) (Decrypter, error) {
+	key, err := store.GetVersion(id, version)
+	if err != nil {
+		return nil, err
+	}
+	decrypter, ok := key.PrivateKey.(crypto.Decrypter)
+	if !ok {
+		return nil, fmt.Errorf("%w for decryption: %s", ErrUnsupportedKeyType, key.Type())
+	}
+
+	return &keyDecrypter{Decrypter: decrypter, id: key.ID, version: key.Version}, nil
+}
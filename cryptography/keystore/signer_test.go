@@ -0,0 +1,84 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package keystore
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNewSigner signs with keys of a key store through crypto.Signer
+func TestNewSigner(t *testing.T) {
+	store := NewMemoryKeyStore()
+	assert.NoError(t, store.Put(_testRSAKey(t, "rsa")))
+	secret, err := GenerateKey("secret", KeyTypeSymmetric)
+	assert.NoError(t, err)
+	assert.NoError(t, store.Put(secret))
+	_, err = store.Rotate("rsa")
+	assert.NoError(t, err)
+
+	signer, err := NewSigner(store, "rsa")
+	assert.NoError(t, err)
+	assert.Equal(t, "rsa", signer.KeyID())
+	assert.Equal(t, 2, signer.KeyVersion())
+
+	digest := sha256.Sum256([]byte("hello world"))
+	signature, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	assert.NoError(t, err)
+	assert.NoError(t, rsa.VerifyPKCS1v15(signer.Public().(*rsa.PublicKey), crypto.SHA256, digest[:], signature))
+
+	_, err = NewSigner(store, "secret")
+	assert.ErrorIs(t, err, ErrUnsupportedKeyType)
+	_, err = NewSigner(store, "missing")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+// TestNewDecrypter decrypts with keys of a key store through crypto.Decrypter
+func TestNewDecrypter(t *testing.T) {
+	store := NewMemoryKeyStore()
+	assert.NoError(t, store.Put(_testRSAKey(t, "rsa")))
+	edKey, err := GenerateKey("ed25519", KeyTypeEd25519)
+	assert.NoError(t, err)
+	assert.NoError(t, store.Put(edKey))
+
+	decrypter, err := NewDecrypter(store, "rsa", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "rsa", decrypter.KeyID())
+	assert.Equal(t, 1, decrypter.KeyVersion())
+
+	encrypted, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, decrypter.Public().(*rsa.PublicKey), []byte("hello world"), nil)
+	assert.NoError(t, err)
+	decrypted, err := decrypter.Decrypt(rand.Reader, encrypted, &rsa.OAEPOptions{Hash: crypto.SHA256})
+	assert.NoError(t, err)
+	assert.Equal(t, "hello world", string(decrypted))
+
+	_, err = NewDecrypter(store, "ed25519", 1)
+	assert.ErrorIs(t, err, ErrUnsupportedKeyType)
+	_, err = NewDecrypter(store, "rsa", 2)
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
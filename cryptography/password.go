@@ -0,0 +1,353 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cryptography
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// CharacterClass is a set of classes of characters in a password
+type CharacterClass byte
+
+// Character classes
+const (
+	ClassUpper CharacterClass = 1 << iota
+	ClassLower
+	ClassDigit
+	ClassSymbol
+	ClassAll = ClassUpper | ClassLower | ClassDigit | ClassSymbol
+	// Characters outside printable ASCII, only used to estimate entropy
+	classOther CharacterClass = ClassSymbol << 1
+)
+
+// Characters of the classes
+const (
+	upperCharacters  = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	lowerCharacters  = "abcdefghijklmnopqrstuvwxyz"
+	digitCharacters  = "0123456789"
+	symbolCharacters = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+)
+
+// Characters that are easily mistaken for one another
+const ambiguousCharacters = "0Oo1Il|`'\""
+
+// Default length of a password
+const DefaultPasswordLength = 20
+
+// Default number of words of a passphrase, about 77 bits of entropy
+const DefaultPassphraseWords = 6
+
+// ErrPasswordPolicy is returned when no password satisfies the policy
+var ErrPasswordPolicy = errors.New("No password satisfies the policy")
+
+// The EFF large wordlist of 7776 words, one line of five dice rolls
+// and a word each, by the Electronic Frontier Foundation under CC BY
+// 3.0 US. See https://www.eff.org/dice.
+//
+//go:embed wordlist/eff_large_wordlist.txt
+var effLargeWordlist string
+
+var (
+	dicewareWords     []string
+	dicewareWordSet   map[string]bool
+	dicewareWordsOnce sync.Once
+)
+
+// PasswordOptionsFunc is a type alias for PasswordOptions functional option
+type PasswordOptionsFunc func(*PasswordOptions) error
+
+// PasswordOptions are the policy of generated passwords and passphrases
+type PasswordOptions struct {
+	Length           int
+	Classes          CharacterClass
+	ExcludeAmbiguous bool
+	Words            int
+	Separator        string
+}
+
+// WithPasswordLength is a helper function to construct functional options
+// that sets the number of characters of a password.
+func WithPasswordLength(length int) PasswordOptionsFunc {
+	return func(o *PasswordOptions) error {
+		if length < 1 {
+			return fmt.Errorf("Invalid password length %d", length)
+		}
+		o.Length = length
+		return nil
+	}
+}
+
+// WithCharacterClasses is a helper function to construct functional options
+// that sets the classes of characters of a password. Every class
+// appears at least once.
+func WithCharacterClasses(classes CharacterClass) PasswordOptionsFunc {
+	return func(o *PasswordOptions) error {
+		if classes == 0 || classes&^ClassAll != 0 {
+			return fmt.Errorf("Invalid character classes %d", classes)
+		}
+		o.Classes = classes
+		return nil
+	}
+}
+
+// WithExcludeAmbiguous is a helper function to construct functional options
+// that excludes characters that are easily mistaken for one another,
+// such as 0 and O, or 1, l and I.
+func WithExcludeAmbiguous(v bool) PasswordOptionsFunc {
+	return func(o *PasswordOptions) error {
+		o.ExcludeAmbiguous = v
+		return nil
+	}
+}
+
+// WithPassphraseWords is a helper function to construct functional options
+// that sets the number of words of a passphrase.
+func WithPassphraseWords(words int) PasswordOptionsFunc {
+	return func(o *PasswordOptions) error {
+		if words < 1 {
+			return fmt.Errorf("Invalid number of words %d", words)
+		}
+		o.Words = words
+		return nil
+	}
+}
+
+// WithPassphraseSeparator is a helper function to construct functional options
+// that sets the separator between the words of a passphrase.
+func WithPassphraseSeparator(separator string) PasswordOptionsFunc {
+	return func(o *PasswordOptions) error {
+		o.Separator = separator
+		return nil
+	}
+}
+
+// Evaluate the functional options and set the options in the PasswordOptions struct
+func (options *PasswordOptions) Merge(optFns ...PasswordOptionsFunc) error {
+	for _, optFn := range optFns {
+		if err := optFn(options); err != nil {
+			return fmt.Errorf("Fail to read password options: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// Get the characters of every class in the set
+func (c CharacterClass) characters(excludeAmbiguous bool) []string {
+	var sets []string
+	for i, characters := range []string{upperCharacters, lowerCharacters, digitCharacters, symbolCharacters} {
+		if c&(1<<i) == 0 {
+			continue
+		}
+		if excludeAmbiguous {
+			characters = strings.Map(func(r rune) rune {
+				if strings.ContainsRune(ambiguousCharacters, r) {
+					return -1
+				}
+				return r
+			}, characters)
+		}
+		sets = append(sets, characters)
+	}
+
+	return sets
+}
+
+// Get a uniformly random number in [0, n)
+func _randomInt(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+
+	return int(i.Int64()), nil
+}
+
+// Generate a random password. By default it has 20 characters of
+// every class. Passwords are drawn uniformly from all passwords that
+// contain every class. Returns the password and an error if any.
+func GeneratePassword(optFns ...PasswordOptionsFunc) (string, error) {
+	options := PasswordOptions{Length: DefaultPasswordLength, Classes: ClassAll}
+	if err := options.Merge(optFns...); err != nil {
+		return "", err
+	}
+
+	sets := options.Classes.characters(options.ExcludeAmbiguous)
+	if options.Length < len(sets) {
+		return "", ErrPasswordPolicy
+	}
+	alphabet := strings.Join(sets, "")
+
+	password := make([]byte, options.Length)
+	for {
+		for i := range password {
+			n, err := _randomInt(len(alphabet))
+			if err != nil {
+				return "", err
+			}
+			password[i] = alphabet[n]
+		}
+		// Retry until every class appears, so that the policy does not
+		// make some passwords more likely than others
+		if _containsEveryClass(string(password), sets) {
+			return string(password), nil
+		}
+	}
+}
+
+// Tell whether the password has a character of every set
+func _containsEveryClass(password string, sets []string) bool {
+	for _, characters := range sets {
+		if !strings.ContainsAny(password, characters) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Load the words of the embedded wordlist
+func _loadDicewareWords() {
+	dicewareWordsOnce.Do(func() {
+		lines := strings.Split(strings.TrimSpace(effLargeWordlist), "\n")
+		dicewareWords = make([]string, 0, len(lines))
+		dicewareWordSet = make(map[string]bool, len(lines))
+		for _, line := range lines {
+			fields := strings.Fields(line)
+			word := fields[len(fields)-1]
+			dicewareWords = append(dicewareWords, word)
+			dicewareWordSet[word] = true
+		}
+	})
+}
+
+// Generate a diceware passphrase of random words from the EFF large
+// wordlist. By default it has 6 words separated by "-". Every word
+// adds about 12.9 bits of entropy. Returns the passphrase and an error if any.
+func GenerateDicewarePassphrase(optFns ...PasswordOptionsFunc) (string, error) {
+	options := PasswordOptions{Words: DefaultPassphraseWords, Separator: "-"}
+	if err := options.Merge(optFns...); err != nil {
+		return "", err
+	}
+
+	_loadDicewareWords()
+	words := make([]string, options.Words)
+	for i := range words {
+		n, err := _randomInt(len(dicewareWords))
+		if err != nil {
+			return "", err
+		}
+		words[i] = dicewareWords[n]
+	}
+
+	return strings.Join(words, options.Separator), nil
+}
+
+// Estimate the entropy of a passphrase in bits. A passphrase of words
+// from the EFF large wordlist counts as randomly chosen words. Otherwise
+// every character counts as randomly chosen from the classes that
+// appear, except that a character equal or next to the one before it,
+// as in "aaa" or "abc", counts as one bit. The estimate is an
+// upper bound: a passphrase made by a person is much easier to guess.
+func EstimateEntropy(passphrase string) float64 {
+	if words := _dicewareWordCount(passphrase); words > 0 {
+		return float64(words) * math.Log2(float64(len(dicewareWords)))
+	}
+
+	runes := []rune(passphrase)
+	pool := 0
+	var seen CharacterClass
+	for _, r := range runes {
+		class, size := _characterClass(r)
+		if seen&class == 0 {
+			seen |= class
+			pool += size
+		}
+	}
+
+	entropy := 0.0
+	for i, r := range runes {
+		if i > 0 && r-runes[i-1] >= -1 && r-runes[i-1] <= 1 {
+			entropy++
+		} else {
+			entropy += math.Log2(float64(pool))
+		}
+	}
+
+	return entropy
+}
+
+// Get the class of a character and the size of the class. Characters
+// that are not printable ASCII count as a class of their own.
+func _characterClass(r rune) (CharacterClass, int) {
+	switch {
+	case strings.ContainsRune(upperCharacters, r):
+		return ClassUpper, len(upperCharacters)
+	case strings.ContainsRune(lowerCharacters, r):
+		return ClassLower, len(lowerCharacters)
+	case strings.ContainsRune(digitCharacters, r):
+		return ClassDigit, len(digitCharacters)
+	case strings.ContainsRune(symbolCharacters, r) || r == ' ':
+		return ClassSymbol, len(symbolCharacters) + 1
+	}
+
+	return classOther, 100
+}
+
+// Get the number of words of a passphrase made of words of the
+// wordlist and a separator, or 0 if it is not one. The separator is the
+// first character that is not a letter, "-" by default.
+func _dicewareWordCount(passphrase string) int {
+	_loadDicewareWords()
+
+	lower := strings.ToLower(passphrase)
+	separator := "-"
+	if i := strings.IndexFunc(lower, func(r rune) bool { return !unicode.IsLetter(r) && r != '-' }); i >= 0 {
+		separator = string([]rune(lower[i:])[0])
+	}
+
+	count := 0
+	parts := strings.Split(lower, separator)
+	for i := 0; i < len(parts); i++ {
+		// A few words of the wordlist contain "-", such as "t-shirt"
+		if separator == "-" && i+1 < len(parts) && dicewareWordSet[parts[i]+"-"+parts[i+1]] {
+			i++
+		} else if !dicewareWordSet[parts[i]] {
+			return 0
+		}
+		count++
+	}
+	if count < 2 {
+		return 0
+	}
+
+	return count
+}
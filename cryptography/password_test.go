@@ -0,0 +1,149 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cryptography
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGeneratePassword generates passwords with policies
+func TestGeneratePassword(t *testing.T) {
+	cases := map[string]struct {
+		optFns  []PasswordOptionsFunc
+		length  int
+		classes []string
+		absent  string
+	}{
+		"default": {
+			length:  DefaultPasswordLength,
+			classes: []string{upperCharacters, lowerCharacters, digitCharacters, symbolCharacters},
+		},
+		"digits": {
+			optFns:  []PasswordOptionsFunc{WithPasswordLength(8), WithCharacterClasses(ClassDigit)},
+			length:  8,
+			classes: []string{digitCharacters},
+			absent:  upperCharacters + lowerCharacters + symbolCharacters,
+		},
+		"no ambiguous": {
+			optFns:  []PasswordOptionsFunc{WithPasswordLength(64), WithExcludeAmbiguous(true)},
+			length:  64,
+			classes: []string{upperCharacters, lowerCharacters, digitCharacters, symbolCharacters},
+			absent:  ambiguousCharacters,
+		},
+		"shortest": {
+			optFns:  []PasswordOptionsFunc{WithPasswordLength(2), WithCharacterClasses(ClassUpper | ClassLower)},
+			length:  2,
+			classes: []string{upperCharacters, lowerCharacters},
+			absent:  digitCharacters + symbolCharacters,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				password, err := GeneratePassword(tc.optFns...)
+				assert.NoError(t, err)
+				assert.Len(t, password, tc.length)
+				for _, characters := range tc.classes {
+					assert.True(t, strings.ContainsAny(password, characters), password)
+				}
+				if tc.absent != "" {
+					assert.False(t, strings.ContainsAny(password, tc.absent), password)
+				}
+			}
+		})
+	}
+
+	first, err := GeneratePassword()
+	assert.NoError(t, err)
+	second, err := GeneratePassword()
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, second)
+}
+
+// TestGeneratePasswordInvalid checks invalid policies
+func TestGeneratePasswordInvalid(t *testing.T) {
+	_, err := GeneratePassword(WithPasswordLength(3))
+	assert.Equal(t, ErrPasswordPolicy, err)
+	_, err = GeneratePassword(WithPasswordLength(0))
+	assert.Error(t, err)
+	_, err = GeneratePassword(WithCharacterClasses(0))
+	assert.Error(t, err)
+	_, err = GeneratePassword(WithCharacterClasses(classOther))
+	assert.Error(t, err)
+}
+
+// TestGenerateDicewarePassphrase generates passphrases of words of
+// the wordlist
+func TestGenerateDicewarePassphrase(t *testing.T) {
+	_loadDicewareWords()
+	assert.Len(t, dicewareWords, 7776)
+	assert.Equal(t, "abacus", dicewareWords[0])
+	assert.Equal(t, "zoom", dicewareWords[len(dicewareWords)-1])
+
+	passphrase, err := GenerateDicewarePassphrase()
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultPassphraseWords, _dicewareWordCount(passphrase), passphrase)
+
+	passphrase, err = GenerateDicewarePassphrase(WithPassphraseWords(4), WithPassphraseSeparator(" "))
+	assert.NoError(t, err)
+	words := strings.Split(passphrase, " ")
+	assert.Len(t, words, 4)
+	for _, word := range words {
+		assert.True(t, dicewareWordSet[word], word)
+	}
+
+	_, err = GenerateDicewarePassphrase(WithPassphraseWords(0))
+	assert.Error(t, err)
+}
+
+// TestEstimateEntropy estimates the entropy of passphrases
+func TestEstimateEntropy(t *testing.T) {
+	wordBits := math.Log2(7776)
+	cases := map[string]struct {
+		passphrase string
+		entropy    float64
+	}{
+		"empty":           {passphrase: "", entropy: 0},
+		"digits":          {passphrase: "2580", entropy: 4 * math.Log2(10)},
+		"lower and upper": {passphrase: "aXolotl", entropy: 7 * math.Log2(52)},
+		"repeated":        {passphrase: "zzzz", entropy: math.Log2(26) + 3},
+		"sequence":        {passphrase: "abcd9", entropy: 2*math.Log2(36) + 3},
+		"symbols":         {passphrase: "a!", entropy: 2 * math.Log2(26+33)},
+		"words":           {passphrase: "correct-pyramid-battery-staple", entropy: 4 * wordBits},
+		"spaced words":    {passphrase: "Correct Pyramid Battery", entropy: 3 * wordBits},
+		"hyphen word":     {passphrase: "yo-yo-t-shirt-abacus", entropy: 3 * wordBits},
+		"one word":        {passphrase: "abacus", entropy: 4*math.Log2(26) + 2},
+		"not words":       {passphrase: "pyramid-axolotl", entropy: 15 * math.Log2(26+33)},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.InDelta(t, tc.entropy, EstimateEntropy(tc.passphrase), 1e-9)
+		})
+	}
+}
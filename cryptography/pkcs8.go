@@ -0,0 +1,257 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cryptography
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// KeyFormat is the encoding of a serialized private or public key
+type KeyFormat int
+
+// Supported key formats. The zero value selects the default of the
+// function, which is PKCS#8 for private keys and PKIX for public keys.
+// PKCS#1 applies to RSA keys and SEC 1 to ECDSA private keys only.
+// PKIX applies to public keys only.
+const (
+	KeyFormatPKCS1 KeyFormat = 1
+	KeyFormatPKCS8 KeyFormat = 2
+	KeyFormatSEC1  KeyFormat = 3
+	KeyFormatPKIX  KeyFormat = 4
+)
+
+// PBKDF2 iterations used to encrypt private keys
+const pkcs8Iterations = 600000
+
+// ErrIncorrectPassphrase is returned when an encrypted private key
+// cannot be decrypted with the passphrase.
+var ErrIncorrectPassphrase = errors.New("Incorrect passphrase for private key")
+
+// Object identifiers of PKCS#5 PBES2
+var (
+	oidPBES2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidAES128CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+// EncryptedPrivateKeyInfo of PKCS#8
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+// PBES2-params of PKCS#5
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+// PBKDF2-params of PKCS#5
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// WithPrivateKeyFormat is a helper function to construct functional options
+// that sets the format of serialized private keys.
+func WithPrivateKeyFormat(format KeyFormat) CryptographyOptionsFunc {
+	return func(o *CryptographyOptions) error {
+		if format != KeyFormatPKCS1 && format != KeyFormatPKCS8 && format != KeyFormatSEC1 {
+			return fmt.Errorf("Unsupported key format %d", format)
+		}
+		o.PrivateKeyFormat = format
+		return nil
+	}
+}
+
+// WithPublicKeyFormat is a helper function to construct functional options
+// that sets the format of serialized public keys.
+func WithPublicKeyFormat(format KeyFormat) CryptographyOptionsFunc {
+	return func(o *CryptographyOptions) error {
+		if format != KeyFormatPKIX && format != KeyFormatPKCS1 {
+			return fmt.Errorf("Unsupported public key format %d", format)
+		}
+		o.PublicKeyFormat = format
+		return nil
+	}
+}
+
+// WithKeyPassphrase is a helper function to construct functional options
+// that sets the passphrase private keys are encrypted with.
+func WithKeyPassphrase(passphrase string) CryptographyOptionsFunc {
+	return func(o *CryptographyOptions) error {
+		o.KeyPassphrase = passphrase
+		return nil
+	}
+}
+
+// Encrypt a PKCS#8 private key with a passphrase using PBES2 with
+// PBKDF2-HMAC-SHA256 and AES-256-CBC, as OpenSSL does. Returns the DER
+// of an EncryptedPrivateKeyInfo, whose PEM type is "ENCRYPTED PRIVATE KEY".
+func EncryptPKCS8PrivateKey(der []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, saltLength)
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+
+	kdfParams, err := asn1.Marshal(pbkdf2Params{
+		Salt:           salt,
+		IterationCount: pkcs8Iterations,
+		PRF:            pkix.AlgorithmIdentifier{Algorithm: oidHMACWithSHA256, Parameters: asn1.NullRawValue},
+	})
+	if err != nil {
+		return nil, err
+	}
+	ivParams, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, err
+	}
+	params, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: kdfParams}},
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: ivParams}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	key := pbkdf2.Key([]byte(passphrase), salt, pkcs8Iterations, 32, sha256.New)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	// PKCS#7 padding
+	padding := aes.BlockSize - len(der)%aes.BlockSize
+	encrypted := append(append([]byte{}, der...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, encrypted)
+
+	return asn1.Marshal(encryptedPrivateKeyInfo{
+		Algorithm:     pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: params}},
+		EncryptedData: encrypted,
+	})
+}
+
+// Decrypt the DER of a PKCS#8 EncryptedPrivateKeyInfo with a passphrase.
+// PBES2 with PBKDF2-HMAC-SHA1 or SHA256 and AES-CBC is supported.
+// Returns the DER of the PKCS#8 private key.
+func DecryptPKCS8PrivateKey(der []byte, passphrase string) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	if rest, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, err
+	} else if len(rest) > 0 {
+		return nil, errors.New("Trailing data after encrypted private key")
+	}
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("Unsupported private key encryption %v", info.Algorithm.Algorithm)
+	}
+
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, err
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, fmt.Errorf("Unsupported key derivation function %v", params.KeyDerivationFunc.Algorithm)
+	}
+	var kdfParams pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdfParams); err != nil {
+		return nil, err
+	}
+	if kdfParams.IterationCount < 1 || kdfParams.IterationCount > maxPBKDF2Rounds {
+		return nil, fmt.Errorf("Invalid PBKDF2 iteration count %d", kdfParams.IterationCount)
+	}
+
+	var prf func() hash.Hash
+	switch {
+	case len(kdfParams.PRF.Algorithm) == 0, kdfParams.PRF.Algorithm.Equal(oidHMACWithSHA1):
+		prf = sha1.New
+	case kdfParams.PRF.Algorithm.Equal(oidHMACWithSHA256):
+		prf = sha256.New
+	default:
+		return nil, fmt.Errorf("Unsupported PBKDF2 function %v", kdfParams.PRF.Algorithm)
+	}
+
+	var keyLength int
+	switch {
+	case params.EncryptionScheme.Algorithm.Equal(oidAES128CBC):
+		keyLength = 16
+	case params.EncryptionScheme.Algorithm.Equal(oidAES192CBC):
+		keyLength = 24
+	case params.EncryptionScheme.Algorithm.Equal(oidAES256CBC):
+		keyLength = 32
+	default:
+		return nil, fmt.Errorf("Unsupported private key cipher %v", params.EncryptionScheme.Algorithm)
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, err
+	}
+	if len(iv) != aes.BlockSize || len(info.EncryptedData) == 0 || len(info.EncryptedData)%aes.BlockSize != 0 {
+		return nil, ErrIncorrectPassphrase
+	}
+
+	key := pbkdf2.Key([]byte(passphrase), kdfParams.Salt, kdfParams.IterationCount, keyLength, prf)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	decrypted := make([]byte, len(info.EncryptedData))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(decrypted, info.EncryptedData)
+
+	// A wrong passphrase almost always leaves invalid padding
+	padding := int(decrypted[len(decrypted)-1])
+	if padding < 1 || padding > aes.BlockSize ||
+		subtle.ConstantTimeCompare(decrypted[len(decrypted)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) != 1 {
+		return nil, ErrIncorrectPassphrase
+	}
+
+	// Valid padding by chance still has to decode as a single DER sequence
+	decrypted = decrypted[:len(decrypted)-padding]
+	var raw asn1.RawValue
+	if rest, err := asn1.Unmarshal(decrypted, &raw); err != nil || len(rest) != 0 || raw.Tag != asn1.TagSequence {
+		return nil, ErrIncorrectPassphrase
+	}
+
+	return decrypted, nil
+}
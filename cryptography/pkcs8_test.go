@@ -0,0 +1,73 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cryptography
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEncryptDecryptPKCS8PrivateKey encrypts a private key and decrypts it
+func TestEncryptDecryptPKCS8PrivateKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+
+	encrypted, err := EncryptPKCS8PrivateKey(der, "iamthebest")
+	assert.NoError(t, err)
+	assert.NotEqual(t, der, encrypted)
+
+	cases := map[string]struct {
+		passphrase  string
+		expectError error
+	}{
+		"correct passphrase": {
+			passphrase: "iamthebest",
+		},
+		"incorrect passphrase": {
+			passphrase:  "notthebest",
+			expectError: ErrIncorrectPassphrase,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			decrypted, err := DecryptPKCS8PrivateKey(encrypted, c.passphrase)
+			if c.expectError != nil {
+				assert.Equal(t, c.expectError, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, der, decrypted)
+		})
+	}
+
+	_, err = DecryptPKCS8PrivateKey([]byte("hello world"), "iamthebest")
+	assert.Error(t, err)
+}
@@ -0,0 +1,230 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cryptography
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/tchiunam/axolgo-lib/util"
+)
+
+// Number of bytes read to find the key ID of a file
+const rotateKeyIDPrefixLength = 64 * 1024
+
+// RotateOptionsFunc is a type alias for RotateOptions functional option
+type RotateOptionsFunc func(*RotateOptions) error
+
+// RotateOptions are options of Rotate
+type RotateOptions struct {
+	// Tell whether a file found in a directory is rotated
+	Filter func(path string) bool
+	// Called after every file
+	Progress func(done int, total int, result RotateResult)
+}
+
+// RotateResult is the result of rotating one file
+type RotateResult struct {
+	Path string
+	// The file already records the key ID of the new key
+	Skipped bool
+	Err     error
+}
+
+// RotateReport is the result of rotating every file
+type RotateReport struct {
+	Results []RotateResult
+}
+
+// WithRotateFilter is a helper function to construct functional options
+// that sets which files found in directories are rotated. Files that
+// are given explicitly are always rotated.
+func WithRotateFilter(fn func(path string) bool) RotateOptionsFunc {
+	return func(o *RotateOptions) error {
+		o.Filter = fn
+		return nil
+	}
+}
+
+// WithRotateProgress is a helper function to construct functional options
+// that sets a function called after every file with the number of
+// files done, the total number of files and the result of the file.
+func WithRotateProgress(fn func(done int, total int, result RotateResult)) RotateOptionsFunc {
+	return func(o *RotateOptions) error {
+		o.Progress = fn
+		return nil
+	}
+}
+
+// Evaluate the functional options and set the options in the RotateOptions struct
+func (options *RotateOptions) Merge(optFns ...RotateOptionsFunc) error {
+	for _, optFn := range optFns {
+		if err := optFn(options); err != nil {
+			return fmt.Errorf("Fail to read rotate options: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// Get the number of files rotated
+func (r *RotateReport) Rotated() int {
+	rotated := 0
+	for _, result := range r.Results {
+		if result.Err == nil && !result.Skipped {
+			rotated++
+		}
+	}
+
+	return rotated
+}
+
+// Get the results of the files that failed
+func (r *RotateReport) Failed() []RotateResult {
+	var failed []RotateResult
+	for _, result := range r.Results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+
+	return failed
+}
+
+// Re-encrypt files with a new key. Every path is a file or a directory
+// that is walked recursively. Every file is decrypted with from and
+// encrypted with to, and replaced atomically with its mode kept, so that
+// a file that fails is left as it is. Files that record the key ID of to
+// are skipped, so an interrupted rotation can be run again. A failure
+// does not stop the rotation; it is reported in the result of the file.
+func Rotate(paths []string, from Crypter, to Crypter, optFns ...RotateOptionsFunc) (*RotateReport, error) {
+	var options RotateOptions
+	if err := options.Merge(optFns...); err != nil {
+		return nil, err
+	}
+
+	var report RotateReport
+	var files []string
+	for _, path := range paths {
+		found, err := _findFiles(path, options.Filter)
+		if err != nil {
+			report.Results = append(report.Results, RotateResult{Path: path, Err: err})
+		}
+		files = append(files, found...)
+	}
+
+	total := len(files) + len(report.Results)
+	for i, result := range report.Results {
+		if options.Progress != nil {
+			options.Progress(i+1, total, result)
+		}
+	}
+	for _, file := range files {
+		result := RotateResult{Path: file}
+		result.Skipped, result.Err = _rotateFile(file, from, to)
+		report.Results = append(report.Results, result)
+		if options.Progress != nil {
+			options.Progress(len(report.Results), total, result)
+		}
+	}
+
+	return &report, nil
+}
+
+// Get the regular files of a path. The files found before an error
+// are returned with it.
+func _findFiles(path string, filter func(string) bool) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type().IsRegular() && (filter == nil || filter(file)) {
+			files = append(files, file)
+		}
+		return nil
+	})
+
+	return files, err
+}
+
+// Re-encrypt a file. Returns whether it is skipped and an error if any.
+func _rotateFile(filename string, from Crypter, to Crypter) (bool, error) {
+	in, err := os.Open(filename)
+	if err != nil {
+		return false, err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	r := bufio.NewReaderSize(in, rotateKeyIDPrefixLength)
+	prefix, err := r.Peek(rotateKeyIDPrefixLength)
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	if keyID := to.KeyID(); keyID != "" {
+		if recorded, err := to.ReadKeyID(prefix); err == nil && recorded == keyID {
+			return true, nil
+		}
+	}
+
+	return false, util.WriteFileAtomicFunc(filename, info.Mode().Perm(), func(w io.Writer) error {
+		return _recrypt(w, r, from, to)
+	})
+}
+
+// Decrypt r with from and encrypt it with to into w. The plaintext is
+// passed through a pipe, so that it is never held in full.
+func _recrypt(w io.Writer, r io.Reader, from Crypter, to Crypter) error {
+	pr, pw := io.Pipe()
+	decrypted := make(chan error, 1)
+	go func() {
+		err := from.DecryptStream(pw, r)
+		pw.CloseWithError(err)
+		decrypted <- err
+	}()
+
+	err := to.EncryptStream(w, pr)
+	// Stop the decryption if the encryption failed
+	pr.CloseWithError(err)
+	if decryptErr := <-decrypted; decryptErr != nil && decryptErr != io.ErrClosedPipe {
+		return decryptErr
+	}
+
+	return err
+}
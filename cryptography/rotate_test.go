@@ -0,0 +1,116 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cryptography
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRotate re-encrypts a directory with a new key, checking the
+// content, the key ID, the progress and the failures
+func TestRotate(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0700))
+
+	oldCrypter, err := NewPassphraseCrypter("iamthebest", WithPBKDF2Params(testPBKDF2Params))
+	assert.NoError(t, err)
+	key, err := GenerateDataKey()
+	assert.NoError(t, err)
+	newCrypter, err := NewKeyCrypter(key, WithKeyID("axolotl/2"))
+	assert.NoError(t, err)
+
+	contents := map[string]string{
+		"a.enc":         "hello world",
+		"sub/b.enc":     "hello axolotl",
+		"sub/empty.enc": "",
+	}
+	for name, content := range contents {
+		filename := filepath.Join(dir, name)
+		out, err := os.Create(filename)
+		assert.NoError(t, err)
+		assert.NoError(t, oldCrypter.EncryptStream(out, strings.NewReader(content)))
+		assert.NoError(t, out.Close())
+	}
+	// Files of another key fail, files that are filtered out are kept
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "c.enc"), []byte("not encrypted"), 0640))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("notes"), 0600))
+
+	var progress []int
+	filter := WithRotateFilter(func(path string) bool { return filepath.Ext(path) == ".enc" })
+	report, err := Rotate([]string{dir, filepath.Join(dir, "missing.enc")}, oldCrypter, newCrypter, filter,
+		WithRotateProgress(func(done int, total int, result RotateResult) {
+			assert.Equal(t, 5, total)
+			progress = append(progress, done)
+		}))
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, progress)
+	assert.Len(t, report.Results, 5)
+	assert.Equal(t, 3, report.Rotated())
+	failed := report.Failed()
+	if assert.Len(t, failed, 2) {
+		assert.Equal(t, filepath.Join(dir, "missing.enc"), failed[0].Path)
+		assert.True(t, os.IsNotExist(failed[0].Err))
+		assert.Equal(t, filepath.Join(dir, "sub", "c.enc"), failed[1].Path)
+	}
+
+	for name, content := range contents {
+		filename := filepath.Join(dir, name)
+		encrypted, err := os.ReadFile(filename)
+		assert.NoError(t, err)
+		keyID, err := EnvelopeKeyID(encrypted)
+		assert.NoError(t, err)
+		assert.Equal(t, "axolotl/2", keyID)
+		decrypted, err := DecryptWithKey(encrypted, key)
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(decrypted))
+	}
+	// Failed files are left as they are, with their mode
+	data, err := os.ReadFile(filepath.Join(dir, "sub", "c.enc"))
+	assert.NoError(t, err)
+	assert.Equal(t, "not encrypted", string(data))
+	info, err := os.Stat(filepath.Join(dir, "sub", "c.enc"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+	entries, err := os.ReadDir(filepath.Join(dir, "sub"))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3)
+
+	// Running again skips the files that are rotated
+	report, err = Rotate([]string{filepath.Join(dir, "a.enc"), filepath.Join(dir, "sub", "b.enc")}, oldCrypter, newCrypter)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, report.Rotated())
+	assert.Empty(t, report.Failed())
+	for _, result := range report.Results {
+		assert.True(t, result.Skipped)
+	}
+
+	_, err = Rotate([]string{dir}, oldCrypter, newCrypter, func(o *RotateOptions) error {
+		return os.ErrInvalid
+	})
+	assert.Error(t, err)
+}
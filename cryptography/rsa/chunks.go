@@ -0,0 +1,126 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package rsa
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/tchiunam/axolgo-lib/cryptography"
+)
+
+// Magic bytes at the start of chunked data
+var chunksMagic = []byte("AXRC")
+
+// Version of the chunked data header
+const chunksVersion = 1
+
+// Length of the chunked data header, which is the magic bytes, the
+// version and the length of the message
+const chunksHeaderLength = 4 + 1 + 8
+
+// ErrInvalidChunks is returned when chunked data is malformed
+var ErrInvalidChunks = errors.New("Invalid chunked RSA data")
+
+// Encrypt a message of any length in chunks of RSA-OAEP blocks. The
+// header holds the length of the message. Every block is labelled with
+// the header and its index, so blocks cannot be reordered, dropped or
+// moved to other data.
+func _encryptChunks(data []byte, publicKey *rsa.PublicKey, options *cryptography.CryptographyOptions) ([]byte, error) {
+	chunkLength := _maxOAEPLength(publicKey, options)
+	if chunkLength < 1 {
+		return nil, ErrMessageTooLong
+	}
+
+	header := make([]byte, chunksHeaderLength)
+	copy(header, chunksMagic)
+	header[len(chunksMagic)] = chunksVersion
+	binary.BigEndian.PutUint64(header[len(chunksMagic)+1:], uint64(len(data)))
+
+	chunkCount := (len(data) + chunkLength - 1) / chunkLength
+	encrypted := make([]byte, 0, len(header)+chunkCount*publicKey.Size())
+	encrypted = append(encrypted, header...)
+	for i := 0; i < chunkCount; i++ {
+		end := (i + 1) * chunkLength
+		if end > len(data) {
+			end = len(data)
+		}
+		block, err := _encryptOAEP(data[i*chunkLength:end], publicKey, options, _chunkLabel(header, i))
+		if err != nil {
+			return nil, err
+		}
+		encrypted = append(encrypted, block...)
+	}
+
+	return encrypted, nil
+}
+
+// Decrypt data encrypted by _encryptChunks
+func _decryptChunks(data []byte, privateKey *rsa.PrivateKey, options *cryptography.CryptographyOptions) ([]byte, error) {
+	if len(data) < chunksHeaderLength || !bytes.HasPrefix(data, chunksMagic) {
+		return nil, ErrInvalidChunks
+	}
+	if version := data[len(chunksMagic)]; version != chunksVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidChunks, version)
+	}
+	header := data[:chunksHeaderLength]
+	blocks := data[chunksHeaderLength:]
+
+	chunkLength := _maxOAEPLength(&privateKey.PublicKey, options)
+	length := binary.BigEndian.Uint64(header[len(chunksMagic)+1:])
+	// The message is shorter than the blocks, which also rules out overflows
+	if chunkLength < 1 || length > uint64(len(blocks)) {
+		return nil, ErrInvalidChunks
+	}
+	chunkCount := (int(length) + chunkLength - 1) / chunkLength
+	if len(blocks) != chunkCount*privateKey.Size() {
+		return nil, ErrInvalidChunks
+	}
+
+	decrypted := make([]byte, 0, length)
+	for i := 0; i < chunkCount; i++ {
+		block := blocks[i*privateKey.Size() : (i+1)*privateKey.Size()]
+		chunk, err := _decryptOAEP(block, privateKey, options, _chunkLabel(header, i))
+		if err != nil {
+			return nil, err
+		}
+		decrypted = append(decrypted, chunk...)
+	}
+	if uint64(len(decrypted)) != length {
+		return nil, ErrInvalidChunks
+	}
+
+	return decrypted, nil
+}
+
+// Get the OAEP label of a block
+func _chunkLabel(header []byte, index int) []byte {
+	label := make([]byte, len(header)+4)
+	copy(label, header)
+	binary.BigEndian.PutUint32(label[len(header):], uint32(index))
+
+	return label
+}
@@ -0,0 +1,115 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package rsa
+
+import (
+	"bytes"
+	"crypto"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tchiunam/axolgo-lib/cryptography"
+)
+
+// TestEncryptDecryptChunks encrypts messages around the chunk length
+func TestEncryptDecryptChunks(t *testing.T) {
+	options := cryptography.CryptographyOptions{Hash: crypto.SHA256}
+	chunkLength := _maxOAEPLength(&testPrivateKey.PublicKey, &options)
+
+	for _, length := range []int{1, chunkLength - 1, chunkLength, chunkLength + 1, 3 * chunkLength} {
+		data := bytes.Repeat([]byte{'a'}, length)
+		encrypted, err := _encryptChunks(data, &testPrivateKey.PublicKey, &options)
+		assert.NoError(t, err)
+		assert.Len(t, encrypted, chunksHeaderLength+(length+chunkLength-1)/chunkLength*testPrivateKey.Size())
+		decrypted, err := _decryptChunks(encrypted, testPrivateKey, &options)
+		assert.NoError(t, err)
+		assert.Equal(t, data, decrypted)
+	}
+}
+
+// TestDecryptChunksInvalid checks that changed chunked data is rejected
+func TestDecryptChunksInvalid(t *testing.T) {
+	options := cryptography.CryptographyOptions{Hash: crypto.SHA256}
+	chunkLength := _maxOAEPLength(&testPrivateKey.PublicKey, &options)
+	data := bytes.Repeat([]byte{'a'}, 2*chunkLength)
+	encrypted, err := _encryptChunks(data, &testPrivateKey.PublicKey, &options)
+	assert.NoError(t, err)
+	size := testPrivateKey.Size()
+
+	// Apply a change to a copy of the encrypted data
+	change := func(fn func([]byte) []byte) []byte {
+		return fn(append([]byte{}, encrypted...))
+	}
+
+	cases := map[string]struct {
+		data              []byte
+		expectErrorString string
+	}{
+		"swapped blocks": {
+			data: change(func(b []byte) []byte {
+				blocks := b[chunksHeaderLength:]
+				first := append([]byte{}, blocks[:size]...)
+				copy(blocks, blocks[size:])
+				copy(blocks[size:], first)
+				return b
+			}),
+			expectErrorString: "crypto/rsa: decryption error",
+		},
+		"dropped block": {
+			data:              change(func(b []byte) []byte { return b[:len(b)-size] }),
+			expectErrorString: "Invalid chunked RSA data",
+		},
+		"changed length": {
+			data: change(func(b []byte) []byte {
+				b[chunksHeaderLength-1]--
+				return b
+			}),
+			expectErrorString: "crypto/rsa: decryption error",
+		},
+		"huge length": {
+			data: change(func(b []byte) []byte {
+				b[len(chunksMagic)+1] = 0xff
+				return b
+			}),
+			expectErrorString: "Invalid chunked RSA data",
+		},
+		"unsupported version": {
+			data: change(func(b []byte) []byte {
+				b[len(chunksMagic)] = 2
+				return b
+			}),
+			expectErrorString: "Invalid chunked RSA data: unsupported version 2",
+		},
+		"too short": {
+			data:              encrypted[:chunksHeaderLength-1],
+			expectErrorString: "Invalid chunked RSA data",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := _decryptChunks(c.data, testPrivateKey, &options)
+			assert.EqualError(t, err, c.expectErrorString)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package rsa
+
+import (
+	"crypto/rsa"
+	"encoding/hex"
+	"io"
+
+	"github.com/tchiunam/axolgo-lib/cryptography"
+)
+
+// A crypter with an RSA key pair
+type crypter struct {
+	privateKey *rsa.PrivateKey
+	keyID      string
+	optFns     []cryptography.CryptographyOptionsFunc
+}
+
+// Create a crypter that encrypts data in an envelope for the public
+// key of the private key, see SealEnvelope, and decrypts data of any
+// mode, see DecryptRSA. The data is held in memory. The key ID is the
+// hex encoded ID of the public key unless it is set with WithKeyID.
+func NewCrypter(privateKey *rsa.PrivateKey, optFns ...cryptography.CryptographyOptionsFunc) (cryptography.Crypter, error) {
+	options := cryptography.CryptographyOptions{}
+	if err := options.Merge(optFns...); err != nil {
+		return nil, err
+	}
+
+	c := crypter{privateKey: privateKey, keyID: options.KeyID}
+	if c.keyID == "" {
+		keyID, err := PublicKeyID(&privateKey.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		c.keyID = hex.EncodeToString(keyID)
+	}
+	c.optFns = append(append([]cryptography.CryptographyOptionsFunc{}, optFns...), cryptography.WithKeyID(c.keyID))
+
+	return &c, nil
+}
+
+// Read the stream in full and seal it in an envelope for the public key
+func (c *crypter) EncryptStream(w io.Writer, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	encrypted, err := SealEnvelope(data, []*rsa.PublicKey{&c.privateKey.PublicKey}, c.optFns...)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(encrypted)
+
+	return err
+}
+
+// Read the stream in full and decrypt it with the private key
+func (c *crypter) DecryptStream(w io.Writer, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	decrypted, err := DecryptRSA(data, c.privateKey, c.optFns...)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(decrypted)
+
+	return err
+}
+
+// Get the key ID set with cryptography.WithKeyID or derived from the public key
+func (c *crypter) KeyID() string {
+	return c.keyID
+}
+
+// Get the key ID in the envelope header, see EnvelopeKeyID
+func (c *crypter) ReadKeyID(prefix []byte) (string, error) {
+	return EnvelopeKeyID(prefix)
+}
@@ -0,0 +1,94 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package rsa
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tchiunam/axolgo-lib/cryptography"
+)
+
+// TestCrypter encrypts and decrypts streams with an RSA crypter
+func TestCrypter(t *testing.T) {
+	data := []byte("The quick brown fox jumps over the lazy dog")
+
+	c, err := NewCrypter(testPrivateKey)
+	assert.NoError(t, err)
+	keyID, err := PublicKeyID(&testPrivateKey.PublicKey)
+	assert.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(keyID), c.KeyID())
+
+	var encrypted bytes.Buffer
+	assert.NoError(t, c.EncryptStream(&encrypted, bytes.NewReader(data)))
+	recorded, err := c.ReadKeyID(encrypted.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, c.KeyID(), recorded)
+
+	var decrypted bytes.Buffer
+	assert.NoError(t, c.DecryptStream(&decrypted, bytes.NewReader(encrypted.Bytes())))
+	assert.Equal(t, data, decrypted.Bytes())
+
+	// Data encrypted in another mode is decrypted too
+	raw, err := EncryptRSA(data, testPrivateKey.PublicKey)
+	assert.NoError(t, err)
+	decrypted.Reset()
+	assert.NoError(t, c.DecryptStream(&decrypted, bytes.NewReader(raw)))
+	assert.Equal(t, data, decrypted.Bytes())
+
+	c, err = NewCrypter(testPrivateKey, cryptography.WithKeyID("axolotl"))
+	assert.NoError(t, err)
+	assert.Equal(t, "axolotl", c.KeyID())
+}
+
+// TestRotateToRSA rotates a file encrypted with a passphrase to an RSA key
+func TestRotateToRSA(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "axolotl.enc")
+	from, err := cryptography.NewPassphraseCrypter("iamthebest",
+		cryptography.WithKDF(cryptography.KDFPBKDF2), cryptography.WithPBKDF2Params(cryptography.PBKDF2Params{Iterations: 1000}))
+	assert.NoError(t, err)
+	out, err := os.Create(filename)
+	assert.NoError(t, err)
+	assert.NoError(t, from.EncryptStream(out, bytes.NewReader([]byte("hello world"))))
+	assert.NoError(t, out.Close())
+
+	to, err := NewCrypter(testPrivateKey)
+	assert.NoError(t, err)
+	report, err := cryptography.Rotate([]string{filename}, from, to)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Rotated())
+
+	encrypted, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	decrypted, err := DecryptRSA(encrypted, testPrivateKey)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello world", string(decrypted))
+
+	report, err = cryptography.Rotate([]string{filename}, from, to)
+	assert.NoError(t, err)
+	assert.True(t, report.Results[0].Skipped)
+}
@@ -0,0 +1,201 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package rsa
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/tchiunam/axolgo-lib/cryptography"
+)
+
+// Magic bytes at the start of an envelope
+var envelopeMagic = []byte("AXRE")
+
+// Version of the envelope header
+const envelopeVersion = 1
+
+// Length of a key ID
+const keyIDLength = sha256.Size
+
+// ErrInvalidEnvelope is returned when an envelope is malformed
+var ErrInvalidEnvelope = errors.New("Invalid RSA envelope")
+
+// ErrNotRecipient is returned when an envelope has no data key
+// wrapped for the private key.
+var ErrNotRecipient = errors.New("Private key is not a recipient of the envelope")
+
+// A data key wrapped for one recipient
+type envelopeRecipient struct {
+	KeyID      []byte
+	WrappedKey []byte
+}
+
+// Get the ID of a public key, which is the SHA-256 hash of its
+// PKIX DER encoding.
+func PublicKeyID(publicKey *rsa.PublicKey) ([]byte, error) {
+	return cryptography.PublicKeyID(publicKey)
+}
+
+// Encrypt data for one or more recipients. The data is encrypted with
+// a random data key, see cryptography.EncryptWithKey, and the data key
+// is wrapped with RSA-OAEP for every recipient. Options such as the
+// cipher, the OAEP hash and associated data are passed on.
+//
+// The layout is the magic bytes, the version, the number of recipients
+// and, for every recipient, the key ID and the length-prefixed wrapped
+// key, followed by the encrypted data. The header is authenticated as
+// associated data of the encrypted data.
+func SealEnvelope(data []byte, recipients []*rsa.PublicKey, optFns ...cryptography.CryptographyOptionsFunc) ([]byte, error) {
+	options := cryptography.CryptographyOptions{Hash: crypto.SHA256}
+	if err := options.Merge(optFns...); err != nil {
+		return nil, err
+	}
+	if len(recipients) == 0 || len(recipients) > 0xffff {
+		return nil, fmt.Errorf("Invalid number of recipients %d", len(recipients))
+	}
+
+	dataKey, err := cryptography.GenerateDataKey()
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	buffer.Write(envelopeMagic)
+	buffer.WriteByte(envelopeVersion)
+	binary.Write(&buffer, binary.BigEndian, uint16(len(recipients)))
+	for _, publicKey := range recipients {
+		keyID, err := PublicKeyID(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		wrappedKey, err := _encryptOAEP(dataKey, publicKey, &options, nil)
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(keyID)
+		binary.Write(&buffer, binary.BigEndian, uint16(len(wrappedKey)))
+		buffer.Write(wrappedKey)
+	}
+	header := buffer.Bytes()
+
+	encrypted, err := cryptography.EncryptWithKey(data, dataKey, _envelopeOptions(header, &options, optFns)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(header, encrypted...), nil
+}
+
+// Decrypt an envelope with the private key of one of its recipients.
+// The same options as for SealEnvelope must be given.
+func OpenEnvelope(data []byte, privateKey *rsa.PrivateKey, optFns ...cryptography.CryptographyOptionsFunc) ([]byte, error) {
+	options := cryptography.CryptographyOptions{Hash: crypto.SHA256}
+	if err := options.Merge(optFns...); err != nil {
+		return nil, err
+	}
+
+	recipients, headerLength, err := _unmarshalEnvelopeHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	keyID, err := PublicKeyID(&privateKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, recipient := range recipients {
+		if !bytes.Equal(recipient.KeyID, keyID) {
+			continue
+		}
+		dataKey, err := _decryptOAEP(recipient.WrappedKey, privateKey, &options, nil)
+		if err != nil {
+			return nil, err
+		}
+		header := data[:headerLength]
+		return cryptography.DecryptWithKey(data[headerLength:], dataKey, _envelopeOptions(header, &options, optFns)...)
+	}
+
+	return nil, ErrNotRecipient
+}
+
+// Get the ID of the key recorded in the encrypted data of an envelope,
+// see cryptography.EnvelopeKeyID. Only the header is read, so the start
+// of the envelope is enough.
+func EnvelopeKeyID(data []byte) (string, error) {
+	_, headerLength, err := _unmarshalEnvelopeHeader(data)
+	if err != nil {
+		return "", err
+	}
+
+	return cryptography.EnvelopeKeyID(data[headerLength:])
+}
+
+// Get the options of the encrypted data, which authenticate the
+// envelope header followed by the associated data of the caller.
+func _envelopeOptions(
+	header []byte,
+	options *cryptography.CryptographyOptions,
+	optFns []cryptography.CryptographyOptionsFunc) []cryptography.CryptographyOptionsFunc {
+	associatedData := make([]byte, 0, len(header)+len(options.AssociatedData))
+	associatedData = append(associatedData, header...)
+	associatedData = append(associatedData, options.AssociatedData...)
+
+	return append(append([]cryptography.CryptographyOptionsFunc{}, optFns...), cryptography.WithAssociatedData(associatedData))
+}
+
+// Decode the envelope header. Returns the recipients and the length
+// of the header.
+func _unmarshalEnvelopeHeader(data []byte) ([]envelopeRecipient, int, error) {
+	offset := len(envelopeMagic) + 3
+	if len(data) < offset || !bytes.HasPrefix(data, envelopeMagic) {
+		return nil, 0, ErrInvalidEnvelope
+	}
+	if version := data[len(envelopeMagic)]; version != envelopeVersion {
+		return nil, 0, fmt.Errorf("%w: unsupported version %d", ErrInvalidEnvelope, version)
+	}
+
+	count := int(binary.BigEndian.Uint16(data[len(envelopeMagic)+1:]))
+	recipients := make([]envelopeRecipient, 0, count)
+	for i := 0; i < count; i++ {
+		if len(data) < offset+keyIDLength+2 {
+			return nil, 0, ErrInvalidEnvelope
+		}
+		keyID := data[offset : offset+keyIDLength]
+		offset += keyIDLength
+		length := int(binary.BigEndian.Uint16(data[offset:]))
+		offset += 2
+		if len(data) < offset+length {
+			return nil, 0, ErrInvalidEnvelope
+		}
+		recipients = append(recipients, envelopeRecipient{KeyID: keyID, WrappedKey: data[offset : offset+length]})
+		offset += length
+	}
+
+	return recipients, offset, nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package rsa
+
+import (
+	"crypto/rsa"
+	"crypto/sha512"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tchiunam/axolgo-lib/cryptography"
+)
+
+// TestSealOpenEnvelope encrypts data for several recipients
+func TestSealOpenEnvelope(t *testing.T) {
+	alice, _, err := GenerateRSAKeyPair(2048)
+	assert.NoError(t, err)
+	bob, _, err := GenerateRSAKeyPair(2048)
+	assert.NoError(t, err)
+	eve, _, err := GenerateRSAKeyPair(2048)
+	assert.NoError(t, err)
+
+	// Larger than what RSA can encrypt directly
+	data := make([]byte, 4096)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	cases := map[string]struct {
+		optFns []cryptography.CryptographyOptionsFunc
+	}{
+		"default": {},
+		"options": {
+			optFns: []cryptography.CryptographyOptionsFunc{
+				cryptography.WithCipher(cryptography.CipherXChaCha20Poly1305),
+				cryptography.WithOAEPHashFunc(sha512.New()),
+				cryptography.WithAssociatedData([]byte("report.pdf")),
+			},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			sealed, err := SealEnvelope(data, []*rsa.PublicKey{&alice.PublicKey, &bob.PublicKey}, c.optFns...)
+			assert.NoError(t, err)
+
+			for _, privateKey := range []*rsa.PrivateKey{alice, bob} {
+				opened, err := OpenEnvelope(sealed, privateKey, c.optFns...)
+				assert.NoError(t, err)
+				assert.Equal(t, data, opened)
+			}
+
+			_, err = OpenEnvelope(sealed, eve, c.optFns...)
+			assert.Equal(t, ErrNotRecipient, err)
+		})
+	}
+}
+
+// TestOpenEnvelopeInvalid checks that malformed or modified envelopes are rejected
+func TestOpenEnvelopeInvalid(t *testing.T) {
+	privateKey, _, err := GenerateRSAKeyPair(2048)
+	assert.NoError(t, err)
+	other, _, err := GenerateRSAKeyPair(2048)
+	assert.NoError(t, err)
+
+	sealed, err := SealEnvelope([]byte("hello world"), []*rsa.PublicKey{&privateKey.PublicKey, &other.PublicKey})
+	assert.NoError(t, err)
+	_, headerLength, err := _unmarshalEnvelopeHeader(sealed)
+	assert.NoError(t, err)
+
+	// Dropping the other recipient changes the authenticated header
+	single := append(append([]byte{}, sealed[:len(envelopeMagic)+1]...), 0, 1)
+	single = append(single, sealed[len(envelopeMagic)+3:len(envelopeMagic)+3+keyIDLength+2+256]...)
+	single = append(single, sealed[headerLength:]...)
+	_, err = OpenEnvelope(single, privateKey)
+	assert.Error(t, err)
+
+	cases := map[string][]byte{
+		"no magic":        []byte("hello world"),
+		"unknown version": append(append([]byte{}, envelopeMagic...), 9, 0, 1),
+		"truncated":       sealed[:len(envelopeMagic)+3+keyIDLength],
+	}
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := OpenEnvelope(data, privateKey)
+			assert.True(t, errors.Is(err, ErrInvalidEnvelope), "OpenEnvelope() = %v, want %v", err, ErrInvalidEnvelope)
+		})
+	}
+
+	_, err = SealEnvelope([]byte("hello world"), nil)
+	assert.EqualError(t, err, "Invalid number of recipients 0")
+}
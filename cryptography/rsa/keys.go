@@ -0,0 +1,168 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package rsa
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/tchiunam/axolgo-lib/cryptography"
+)
+
+// ErrNotRSAKey is returned when a parsed key is not an RSA key
+var ErrNotRSAKey = errors.New("Key is not an RSA key")
+
+// ErrPassphraseRequired is returned when an encrypted private key is
+// parsed without a passphrase.
+var ErrPassphraseRequired = cryptography.ErrKeyPassphraseRequired
+
+// A JSON Web Key of RFC 7517
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	D   string `json:"d,omitempty"`
+	P   string `json:"p,omitempty"`
+	Q   string `json:"q,omitempty"`
+	DP  string `json:"dp,omitempty"`
+	DQ  string `json:"dq,omitempty"`
+	QI  string `json:"qi,omitempty"`
+}
+
+// Serialize a private key as DER. The format is PKCS#8 by default and
+// can be set with cryptography.WithPrivateKeyFormat. With
+// cryptography.WithKeyPassphrase the key is encrypted, which requires PKCS#8.
+func MarshalPrivateKeyDER(privateKey *rsa.PrivateKey, optFns ...cryptography.CryptographyOptionsFunc) ([]byte, error) {
+	return cryptography.MarshalPrivateKeyDER(privateKey, optFns...)
+}
+
+// Serialize a private key as PEM. The options are the same as for
+// MarshalPrivateKeyDER.
+func MarshalPrivateKeyPEM(privateKey *rsa.PrivateKey, optFns ...cryptography.CryptographyOptionsFunc) ([]byte, error) {
+	return cryptography.MarshalPrivateKeyPEM(privateKey, optFns...)
+}
+
+// Serialize a public key as PEM. The format is PKIX by default, or
+// PKCS#1 with cryptography.WithPublicKeyFormat(cryptography.KeyFormatPKCS1).
+func MarshalPublicKeyPEM(publicKey *rsa.PublicKey, optFns ...cryptography.CryptographyOptionsFunc) ([]byte, error) {
+	return cryptography.MarshalPublicKeyPEM(publicKey, optFns...)
+}
+
+// Parse a private key in PEM or DER, as PKCS#1, PKCS#8 or encrypted
+// PKCS#8. Encrypted keys need cryptography.WithKeyPassphrase.
+func ParsePrivateKey(data []byte, optFns ...cryptography.CryptographyOptionsFunc) (*rsa.PrivateKey, error) {
+	key, err := cryptography.ParsePrivateKey(data, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, ErrNotRSAKey
+	}
+
+	return rsaKey, nil
+}
+
+// Parse a public key in PEM or DER, as PKIX or PKCS#1, or a line of
+// an OpenSSH authorized_keys file.
+func ParsePublicKey(data []byte) (*rsa.PublicKey, error) {
+	key, err := cryptography.ParsePublicKey(data)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, ErrNotRSAKey
+	}
+
+	return rsaKey, nil
+}
+
+// Encode a big integer in unpadded base64url as JWK requires
+func _jwkInt(n *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(n.Bytes())
+}
+
+// Get the JWK thumbprint of RFC 7638 of a public key, which is used as its key ID
+func JWKThumbprint(publicKey *rsa.PublicKey) string {
+	// The members must be in lexicographic order without whitespace
+	canonical := fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`,
+		_jwkInt(big.NewInt(int64(publicKey.E))), _jwkInt(publicKey.N))
+	sum := sha256.Sum256([]byte(canonical))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Serialize a public key as a JSON Web Key. The key ID is the JWK thumbprint.
+func MarshalPublicKeyJWK(publicKey *rsa.PublicKey) ([]byte, error) {
+	return json.Marshal(jsonWebKey{
+		Kty: "RSA",
+		Kid: JWKThumbprint(publicKey),
+		N:   _jwkInt(publicKey.N),
+		E:   _jwkInt(big.NewInt(int64(publicKey.E))),
+	})
+}
+
+// Serialize a private key as a JSON Web Key. The key ID is the JWK
+// thumbprint of the public key.
+func MarshalPrivateKeyJWK(privateKey *rsa.PrivateKey) ([]byte, error) {
+	if len(privateKey.Primes) != 2 {
+		return nil, fmt.Errorf("JWK output supports keys with two primes only")
+	}
+
+	// The CRT values are computed here instead of with Precompute, which
+	// would modify the key of the caller
+	p, q := privateKey.Primes[0], privateKey.Primes[1]
+	one := big.NewInt(1)
+	dp := new(big.Int).Mod(privateKey.D, new(big.Int).Sub(p, one))
+	dq := new(big.Int).Mod(privateKey.D, new(big.Int).Sub(q, one))
+	qi := new(big.Int).ModInverse(q, p)
+	if qi == nil {
+		return nil, fmt.Errorf("Invalid RSA private key: primes are not coprime")
+	}
+
+	return json.Marshal(jsonWebKey{
+		Kty: "RSA",
+		Kid: JWKThumbprint(&privateKey.PublicKey),
+		N:   _jwkInt(privateKey.N),
+		E:   _jwkInt(big.NewInt(int64(privateKey.E))),
+		D:   _jwkInt(privateKey.D),
+		P:   _jwkInt(p),
+		Q:   _jwkInt(q),
+		DP:  _jwkInt(dp),
+		DQ:  _jwkInt(dq),
+		QI:  _jwkInt(qi),
+	})
+}
+
+// Serialize a public key as a line of an OpenSSH authorized_keys file,
+// followed by the comment if it is not empty.
+func MarshalAuthorizedKey(publicKey *rsa.PublicKey, comment string) ([]byte, error) {
+	return cryptography.MarshalAuthorizedKey(publicKey, comment)
+}
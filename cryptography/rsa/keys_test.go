@@ -0,0 +1,241 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package rsa
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tchiunam/axolgo-lib/cryptography"
+)
+
+// A key shared by the tests, as generating keys is slow
+var testPrivateKey, _, _ = GenerateRSAKeyPair(2048)
+
+// TestMarshalParsePrivateKey serializes and parses private keys in every format
+func TestMarshalParsePrivateKey(t *testing.T) {
+	cases := map[string]struct {
+		optFns     []cryptography.CryptographyOptionsFunc
+		expectType string
+	}{
+		"default": {
+			expectType: "PRIVATE KEY",
+		},
+		"pkcs1": {
+			optFns:     []cryptography.CryptographyOptionsFunc{cryptography.WithPrivateKeyFormat(cryptography.KeyFormatPKCS1)},
+			expectType: "RSA PRIVATE KEY",
+		},
+		"pkcs8": {
+			optFns:     []cryptography.CryptographyOptionsFunc{cryptography.WithPrivateKeyFormat(cryptography.KeyFormatPKCS8)},
+			expectType: "PRIVATE KEY",
+		},
+		"encrypted": {
+			optFns:     []cryptography.CryptographyOptionsFunc{cryptography.WithKeyPassphrase("iamthebest")},
+			expectType: "ENCRYPTED PRIVATE KEY",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			data, err := MarshalPrivateKeyPEM(testPrivateKey, c.optFns...)
+			assert.NoError(t, err)
+			block, _ := pem.Decode(data)
+			assert.Equal(t, c.expectType, block.Type)
+
+			// PEM
+			key, err := ParsePrivateKey(data, c.optFns...)
+			assert.NoError(t, err)
+			assert.True(t, testPrivateKey.Equal(key))
+
+			// DER
+			key, err = ParsePrivateKey(block.Bytes, c.optFns...)
+			assert.NoError(t, err)
+			assert.True(t, testPrivateKey.Equal(key))
+		})
+	}
+}
+
+// TestParsePrivateKeyEncrypted checks the handling of passphrases
+func TestParsePrivateKeyEncrypted(t *testing.T) {
+	data, err := MarshalPrivateKeyPEM(testPrivateKey, cryptography.WithKeyPassphrase("iamthebest"))
+	assert.NoError(t, err)
+
+	_, err = ParsePrivateKey(data)
+	assert.Equal(t, ErrPassphraseRequired, err)
+	_, err = ParsePrivateKey(data, cryptography.WithKeyPassphrase("notthebest"))
+	assert.Equal(t, cryptography.ErrIncorrectPassphrase, err)
+
+	_, err = MarshalPrivateKeyPEM(
+		testPrivateKey,
+		cryptography.WithPrivateKeyFormat(cryptography.KeyFormatPKCS1),
+		cryptography.WithKeyPassphrase("iamthebest"))
+	assert.EqualError(t, err, "Encrypted private keys must be in PKCS#8 format")
+
+	// A key encrypted by OpenSSL
+	data, err = os.ReadFile(filepath.Join("testdata", "openssl_encrypted.pem"))
+	assert.NoError(t, err)
+	key, err := ParsePrivateKey(data, cryptography.WithKeyPassphrase("iamthebest"))
+	assert.NoError(t, err)
+	data, err = os.ReadFile(filepath.Join("testdata", "openssl_public.pem"))
+	assert.NoError(t, err)
+	publicKey, err := ParsePublicKey(data)
+	assert.NoError(t, err)
+	assert.True(t, publicKey.Equal(&key.PublicKey))
+}
+
+// TestParseKeyInvalid checks that other data is rejected
+func TestParseKeyInvalid(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	ecDER, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	assert.NoError(t, err)
+	ecPublicDER, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	assert.NoError(t, err)
+	publicPEM, err := MarshalPublicKeyPEM(&testPrivateKey.PublicKey)
+	assert.NoError(t, err)
+
+	_, err = ParsePrivateKey(ecDER)
+	assert.Equal(t, ErrNotRSAKey, err)
+	_, err = ParsePrivateKey([]byte("hello world"))
+	assert.EqualError(t, err, "Cannot parse private key")
+	_, err = ParsePrivateKey(publicPEM)
+	assert.EqualError(t, err, `Unexpected PEM type "PUBLIC KEY" for a private key`)
+
+	_, err = ParsePublicKey(ecPublicDER)
+	assert.Equal(t, ErrNotRSAKey, err)
+	_, err = ParsePublicKey([]byte("hello world"))
+	assert.EqualError(t, err, "Cannot parse public key")
+}
+
+// TestMarshalParsePublicKey serializes and parses public keys
+func TestMarshalParsePublicKey(t *testing.T) {
+	publicKey := &testPrivateKey.PublicKey
+
+	cases := map[string]struct {
+		optFns     []cryptography.CryptographyOptionsFunc
+		expectType string
+	}{
+		"pkix": {
+			expectType: "PUBLIC KEY",
+		},
+		"pkcs1": {
+			optFns:     []cryptography.CryptographyOptionsFunc{cryptography.WithPublicKeyFormat(cryptography.KeyFormatPKCS1)},
+			expectType: "RSA PUBLIC KEY",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			data, err := MarshalPublicKeyPEM(publicKey, c.optFns...)
+			assert.NoError(t, err)
+			block, _ := pem.Decode(data)
+			assert.Equal(t, c.expectType, block.Type)
+
+			key, err := ParsePublicKey(data)
+			assert.NoError(t, err)
+			assert.True(t, publicKey.Equal(key))
+			key, err = ParsePublicKey(block.Bytes)
+			assert.NoError(t, err)
+			assert.True(t, publicKey.Equal(key))
+		})
+	}
+}
+
+// TestMarshalAuthorizedKey serializes a public key for OpenSSH
+func TestMarshalAuthorizedKey(t *testing.T) {
+	publicKey := &testPrivateKey.PublicKey
+
+	line, err := MarshalAuthorizedKey(publicKey, "axolotl@example.com")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(line), "ssh-rsa AAAA"))
+	assert.True(t, strings.HasSuffix(string(line), " axolotl@example.com\n"))
+
+	key, err := ParsePublicKey(line)
+	assert.NoError(t, err)
+	assert.True(t, publicKey.Equal(key))
+
+	line, err = MarshalAuthorizedKey(publicKey, "")
+	assert.NoError(t, err)
+	assert.Len(t, strings.Fields(string(line)), 2)
+}
+
+// Decode a JWK integer
+func _decodeJWKInt(t *testing.T, v string) *big.Int {
+	b, err := base64.RawURLEncoding.DecodeString(v)
+	assert.NoError(t, err)
+
+	return new(big.Int).SetBytes(b)
+}
+
+// TestMarshalJWK serializes keys as JSON Web Keys
+func TestMarshalJWK(t *testing.T) {
+	var publicJWK, privateJWK map[string]string
+
+	data, err := MarshalPublicKeyJWK(&testPrivateKey.PublicKey)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(data, &publicJWK))
+	assert.Equal(t, "RSA", publicJWK["kty"])
+	assert.Equal(t, "AQAB", publicJWK["e"])
+	assert.Equal(t, testPrivateKey.N, _decodeJWKInt(t, publicJWK["n"]))
+	assert.Equal(t, JWKThumbprint(&testPrivateKey.PublicKey), publicJWK["kid"])
+	assert.NotContains(t, publicJWK, "d")
+
+	data, err = MarshalPrivateKeyJWK(testPrivateKey)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(data, &privateJWK))
+	assert.Equal(t, publicJWK["kid"], privateJWK["kid"])
+	assert.Equal(t, testPrivateKey.D, _decodeJWKInt(t, privateJWK["d"]))
+	assert.Equal(t, testPrivateKey.Primes[0], _decodeJWKInt(t, privateJWK["p"]))
+	assert.Equal(t, testPrivateKey.Precomputed.Qinv, _decodeJWKInt(t, privateJWK["qi"]))
+
+	// A key without precomputed values is not modified
+	key := &rsa.PrivateKey{PublicKey: testPrivateKey.PublicKey, D: testPrivateKey.D, Primes: testPrivateKey.Primes}
+	data, err = MarshalPrivateKeyJWK(key)
+	assert.NoError(t, err)
+	assert.Nil(t, key.Precomputed.Dp, "MarshalPrivateKeyJWK() should not modify the key")
+	privateJWK = nil
+	assert.NoError(t, json.Unmarshal(data, &privateJWK))
+	assert.Equal(t, testPrivateKey.Precomputed.Dp, _decodeJWKInt(t, privateJWK["dp"]))
+	assert.Equal(t, testPrivateKey.Precomputed.Dq, _decodeJWKInt(t, privateJWK["dq"]))
+	assert.Equal(t, testPrivateKey.Precomputed.Qinv, _decodeJWKInt(t, privateJWK["qi"]))
+}
+
+// TestJWKThumbprint checks the thumbprint against the example of RFC 7638
+func TestJWKThumbprint(t *testing.T) {
+	n := "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw"
+	publicKey := &rsa.PublicKey{N: _decodeJWKInt(t, n), E: 65537}
+
+	assert.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", JWKThumbprint(publicKey))
+}
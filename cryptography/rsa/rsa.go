@@ -23,10 +23,12 @@ THE SOFTWARE.
 package rsa
 
 import (
+	"bytes"
 	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
-	"crypto/sha256"
+	"errors"
+	"hash"
 
 	"github.com/tchiunam/axolgo-lib/cryptography"
 )
@@ -41,78 +43,156 @@ func GenerateRSAKeyPair(bits int) (*rsa.PrivateKey, *rsa.PublicKey, error) {
 	return privateKey, &privateKey.PublicKey, nil
 }
 
-// Encrypt a message using RSA public key
+// ErrMessageTooLong is returned when a message does not fit in one
+// RSA-OAEP block in the raw mode.
+var ErrMessageTooLong = errors.New("Message is too long for RSA-OAEP with the key, use the hybrid or chunked mode")
+
+// Encrypt a message using RSA public key. By default a message that
+// fits in one RSA-OAEP block is encrypted as it is and a longer one
+// in the hybrid mode, see SealEnvelope. cryptography.WithRSAMode
+// chooses a mode explicitly.
 func EncryptRSA(data []byte, publicKey rsa.PublicKey, optFns ...cryptography.CryptographyOptionsFunc) ([]byte, error) {
-	options := cryptography.CryptographyOptions{OAEPHashFunc: sha256.New()}
+	options := cryptography.CryptographyOptions{Hash: crypto.SHA256}
 	if err := options.Merge(optFns...); err != nil {
 		return nil, err
 	}
 
-	if encryptedBytes, err := rsa.EncryptOAEP(
-		options.OAEPHashFunc,
-		rand.Reader,
-		&publicKey,
-		data,
-		nil); err == nil {
-		return encryptedBytes, nil
-	} else {
-		return nil, err
+	fits := len(data) <= _maxOAEPLength(&publicKey, &options)
+	switch options.RSAMode {
+	case cryptography.RSAModeRaw:
+		if !fits {
+			return nil, ErrMessageTooLong
+		}
+	case cryptography.RSAModeHybrid:
+		return SealEnvelope(data, []*rsa.PublicKey{&publicKey}, optFns...)
+	case cryptography.RSAModeChunked:
+		return _encryptChunks(data, &publicKey, &options)
+	default:
+		if !fits {
+			return SealEnvelope(data, []*rsa.PublicKey{&publicKey}, optFns...)
+		}
 	}
+
+	return _encryptOAEP(data, &publicKey, &options, nil)
 }
 
-// Decrypt a message using RSA public key
+// Decrypt a message using RSA public key. By default the mode is
+// detected from the data: data as long as the key is raw RSA-OAEP,
+// otherwise it is a hybrid envelope or chunked data.
 func DecryptRSA(data []byte, privateKey *rsa.PrivateKey, optFns ...cryptography.CryptographyOptionsFunc) ([]byte, error) {
-	options := cryptography.CryptographyOptions{OAEPHashFunc: sha256.New()}
+	options := cryptography.CryptographyOptions{Hash: crypto.SHA256}
 	if err := options.Merge(optFns...); err != nil {
 		return nil, err
 	}
 
-	if decryptedBytes, err := rsa.DecryptOAEP(
-		options.OAEPHashFunc,
-		rand.Reader,
-		privateKey,
-		data,
-		nil); err == nil {
-		return decryptedBytes, nil
-	} else {
-		return nil, err
+	mode := options.RSAMode
+	if mode == cryptography.RSAModeAuto {
+		switch {
+		case len(data) == privateKey.Size():
+			mode = cryptography.RSAModeRaw
+		case bytes.HasPrefix(data, envelopeMagic):
+			mode = cryptography.RSAModeHybrid
+		case bytes.HasPrefix(data, chunksMagic):
+			mode = cryptography.RSAModeChunked
+		}
+	}
+
+	switch mode {
+	case cryptography.RSAModeHybrid:
+		return OpenEnvelope(data, privateKey, optFns...)
+	case cryptography.RSAModeChunked:
+		return _decryptChunks(data, privateKey, &options)
 	}
+
+	return _decryptOAEP(data, privateKey, &options, nil)
+}
+
+// Get the longest message RSA-OAEP can encrypt in one block
+func _maxOAEPLength(publicKey *rsa.PublicKey, options *cryptography.CryptographyOptions) int {
+	return publicKey.Size() - 2*_oaepHash(options).Size() - 2
+}
+
+// Encrypt one block with RSA-OAEP
+func _encryptOAEP(
+	data []byte,
+	publicKey *rsa.PublicKey,
+	options *cryptography.CryptographyOptions,
+	label []byte) ([]byte, error) {
+	return rsa.EncryptOAEP(_oaepHash(options), rand.Reader, publicKey, data, label)
 }
 
-// Sign a message using RSA private key
+// Decrypt one block with RSA-OAEP
+func _decryptOAEP(
+	data []byte,
+	privateKey *rsa.PrivateKey,
+	options *cryptography.CryptographyOptions,
+	label []byte) ([]byte, error) {
+	return rsa.DecryptOAEP(_oaepHash(options), rand.Reader, privateKey, data, label)
+}
+
+// Sign a message using RSA private key. The message is hashed with
+// the hash of the options, SHA-256 by default, and signed with PSS
+// unless another scheme is chosen.
 func SignRSA(data []byte, privateKey *rsa.PrivateKey, optFns ...cryptography.CryptographyOptionsFunc) ([]byte, error) {
-	options := cryptography.CryptographyOptions{OAEPHashFunc: sha256.New()}
+	options := cryptography.CryptographyOptions{Hash: crypto.SHA256}
 	if err := options.Merge(optFns...); err != nil {
 		return nil, err
 	}
 
-	msgHash := options.OAEPHashFunc
-	_, err := msgHash.Write(data)
-	if err != nil {
+	msgHash := options.Hash.New()
+	if _, err := msgHash.Write(data); err != nil {
 		return nil, err
 	}
 	msgHashSum := msgHash.Sum(nil)
 
-	return rsa.SignPSS(rand.Reader, privateKey, crypto.SHA256, msgHashSum, nil)
+	if options.SignatureScheme == cryptography.SignatureSchemePKCS1v15 {
+		return rsa.SignPKCS1v15(rand.Reader, privateKey, options.Hash, msgHashSum)
+	}
+
+	return rsa.SignPSS(
+		rand.Reader,
+		privateKey,
+		options.Hash,
+		msgHashSum,
+		&rsa.PSSOptions{SaltLength: options.PSSSaltLength})
 }
 
-// Verify a message using RSA private key
+// Verify a message using RSA public key. The same options as for
+// SignRSA must be given.
 func VerifyRSA(
 	data []byte,
 	publicKey *rsa.PublicKey,
 	signature []byte,
 	optFns ...cryptography.CryptographyOptionsFunc) error {
-	options := cryptography.CryptographyOptions{OAEPHashFunc: sha256.New()}
+	options := cryptography.CryptographyOptions{Hash: crypto.SHA256}
 	if err := options.Merge(optFns...); err != nil {
 		return err
 	}
 
-	msgHash := options.OAEPHashFunc
-	_, err := msgHash.Write(data)
-	if err != nil {
+	msgHash := options.Hash.New()
+	if _, err := msgHash.Write(data); err != nil {
 		return err
 	}
 	msgHashSum := msgHash.Sum(nil)
 
-	return rsa.VerifyPSS(publicKey, crypto.SHA256, msgHashSum, signature, nil)
+	if options.SignatureScheme == cryptography.SignatureSchemePKCS1v15 {
+		return rsa.VerifyPKCS1v15(publicKey, options.Hash, msgHashSum, signature)
+	}
+
+	return rsa.VerifyPSS(
+		publicKey,
+		options.Hash,
+		msgHashSum,
+		signature,
+		&rsa.PSSOptions{SaltLength: options.PSSSaltLength})
+}
+
+// Create a new OAEP hash. The OAEP hash of the options takes precedence
+// over the hash, which is also used for signatures.
+func _oaepHash(options *cryptography.CryptographyOptions) hash.Hash {
+	if options.OAEPHash != 0 {
+		return options.OAEPHash.New()
+	}
+
+	return options.Hash.New()
 }
@@ -23,9 +23,13 @@ THE SOFTWARE.
 package rsa
 
 import (
+	"bytes"
+	"crypto"
 	"crypto/rsa"
 	"crypto/sha256"
+	"crypto/sha512"
 	"fmt"
+	"hash/fnv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -240,3 +244,237 @@ func TestSignVerifyRSAInvalid(t *testing.T) {
 		})
 	}
 }
+
+// TestSignVerifyRSASchemes signs messages with every scheme and hash
+// and checks the signatures with the standard library.
+func TestSignVerifyRSASchemes(t *testing.T) {
+	data := []byte("hello world")
+
+	cases := map[string]struct {
+		optFns []cryptography.CryptographyOptionsFunc
+		hash   crypto.Hash
+	}{
+		"pss with default hash": {
+			hash: crypto.SHA256,
+		},
+		"pss with sha512": {
+			optFns: []cryptography.CryptographyOptionsFunc{cryptography.WithHash(crypto.SHA512)},
+			hash:   crypto.SHA512,
+		},
+		"pss with salt length of hash": {
+			optFns: []cryptography.CryptographyOptionsFunc{
+				cryptography.WithHash(crypto.SHA384),
+				cryptography.WithPSSSaltLength(cryptography.PSSSaltLengthEqualsHash),
+			},
+			hash: crypto.SHA384,
+		},
+		"pss with salt length of 20 bytes": {
+			optFns: []cryptography.CryptographyOptionsFunc{
+				cryptography.WithSignatureScheme(cryptography.SignatureSchemePSS),
+				cryptography.WithPSSSaltLength(20),
+			},
+			hash: crypto.SHA256,
+		},
+		"pkcs1v15 with default hash": {
+			optFns: []cryptography.CryptographyOptionsFunc{cryptography.WithSignatureScheme(cryptography.SignatureSchemePKCS1v15)},
+			hash:   crypto.SHA256,
+		},
+		"pkcs1v15 with sha512": {
+			optFns: []cryptography.CryptographyOptionsFunc{
+				cryptography.WithSignatureScheme(cryptography.SignatureSchemePKCS1v15),
+				cryptography.WithHash(crypto.SHA512),
+			},
+			hash: crypto.SHA512,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			signature, err := SignRSA(data, testPrivateKey, c.optFns...)
+			assert.NoError(t, err)
+			assert.NoError(t, VerifyRSA(data, &testPrivateKey.PublicKey, signature, c.optFns...))
+			assert.Error(t, VerifyRSA([]byte("hello axolotl"), &testPrivateKey.PublicKey, signature, c.optFns...))
+
+			// The signature must be standard for interoperability
+			var options cryptography.CryptographyOptions
+			assert.NoError(t, options.Merge(c.optFns...))
+			msgHash := c.hash.New()
+			msgHash.Write(data)
+			if options.SignatureScheme == cryptography.SignatureSchemePKCS1v15 {
+				assert.NoError(t, rsa.VerifyPKCS1v15(&testPrivateKey.PublicKey, c.hash, msgHash.Sum(nil), signature))
+			} else {
+				assert.NoError(t, rsa.VerifyPSS(&testPrivateKey.PublicKey, c.hash, msgHash.Sum(nil), signature, nil))
+			}
+		})
+	}
+}
+
+// TestSignVerifyRSAMismatch calls the VerifyRSA function with
+// other options than the signature was made with.
+func TestSignVerifyRSAMismatch(t *testing.T) {
+	data := []byte("hello world")
+
+	cases := map[string]struct {
+		signOptFns   []cryptography.CryptographyOptionsFunc
+		verifyOptFns []cryptography.CryptographyOptionsFunc
+	}{
+		"hash": {
+			signOptFns:   []cryptography.CryptographyOptionsFunc{cryptography.WithHash(crypto.SHA512)},
+			verifyOptFns: []cryptography.CryptographyOptionsFunc{cryptography.WithHash(crypto.SHA256)},
+		},
+		"scheme": {
+			signOptFns:   []cryptography.CryptographyOptionsFunc{cryptography.WithSignatureScheme(cryptography.SignatureSchemePKCS1v15)},
+			verifyOptFns: []cryptography.CryptographyOptionsFunc{cryptography.WithSignatureScheme(cryptography.SignatureSchemePSS)},
+		},
+		"salt length": {
+			signOptFns:   []cryptography.CryptographyOptionsFunc{cryptography.WithPSSSaltLength(20)},
+			verifyOptFns: []cryptography.CryptographyOptionsFunc{cryptography.WithPSSSaltLength(32)},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			signature, err := SignRSA(data, testPrivateKey, c.signOptFns...)
+			assert.NoError(t, err)
+			assert.Error(t, VerifyRSA(data, &testPrivateKey.PublicKey, signature, c.verifyOptFns...))
+		})
+	}
+}
+
+// TestRSAHashShared uses the same options concurrently, which must
+// not share a hash between calls.
+func TestRSAHashShared(t *testing.T) {
+	data := []byte("hello world")
+	optFns := []cryptography.CryptographyOptionsFunc{cryptography.WithHash(crypto.SHA512)}
+
+	errs := make(chan error, 8)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			signature, err := SignRSA(data, testPrivateKey, optFns...)
+			if err == nil {
+				err = VerifyRSA(data, &testPrivateKey.PublicKey, signature, optFns...)
+			}
+			errs <- err
+		}()
+	}
+	for i := 0; i < cap(errs); i++ {
+		assert.NoError(t, <-errs)
+	}
+
+	// OAEP follows the hash unless a hash function is set
+	encrypted, err := EncryptRSA(data, testPrivateKey.PublicKey, optFns...)
+	assert.NoError(t, err)
+	decrypted, err := DecryptRSA(encrypted, testPrivateKey, cryptography.WithOAEPHashFunc(sha512.New()))
+	assert.NoError(t, err)
+	assert.Equal(t, data, decrypted)
+	_, err = DecryptRSA(encrypted, testPrivateKey, cryptography.WithOAEPHashFunc(sha256.New()))
+	assert.Error(t, err)
+}
+
+// TestOAEPHash checks that the OAEP hash can be set apart from the
+// signature hash and that a deprecated hash function is mapped to it
+func TestOAEPHash(t *testing.T) {
+	data := []byte("hello world")
+
+	encrypted, err := EncryptRSA(data, testPrivateKey.PublicKey, cryptography.WithOAEPHash(crypto.SHA384), cryptography.WithHash(crypto.SHA512))
+	assert.NoError(t, err)
+	decrypted, err := DecryptRSA(encrypted, testPrivateKey, cryptography.WithOAEPHash(crypto.SHA384))
+	assert.NoError(t, err)
+	assert.Equal(t, data, decrypted)
+	decrypted, err = DecryptRSA(encrypted, testPrivateKey, cryptography.WithOAEPHashFunc(sha512.New384()))
+	assert.NoError(t, err)
+	assert.Equal(t, data, decrypted)
+	_, err = DecryptRSA(encrypted, testPrivateKey, cryptography.WithHash(crypto.SHA384))
+	assert.NoError(t, err, "The hash is used for OAEP if the OAEP hash is not set")
+
+	// A hash function that already holds data is reset
+	used := sha256.New()
+	used.Write(data)
+	encrypted, err = EncryptRSA(data, testPrivateKey.PublicKey, cryptography.WithOAEPHashFunc(used))
+	assert.NoError(t, err)
+	_, err = DecryptRSA(encrypted, testPrivateKey, cryptography.WithOAEPHash(crypto.SHA256))
+	assert.NoError(t, err)
+
+	_, err = EncryptRSA(data, testPrivateKey.PublicKey, cryptography.WithOAEPHashFunc(fnv.New64()))
+	assert.ErrorContains(t, err, "Unsupported OAEP hash function")
+	_, err = EncryptRSA(data, testPrivateKey.PublicKey, cryptography.WithOAEPHash(crypto.MD4))
+	assert.ErrorContains(t, err, "Unavailable OAEP hash")
+}
+
+// TestEncryptDecryptRSAModes encrypts short and long messages in every mode
+func TestEncryptDecryptRSAModes(t *testing.T) {
+	short := []byte("hello world")
+	long := bytes.Repeat([]byte("hello axolotl "), 100)
+
+	cases := map[string]struct {
+		data         []byte
+		mode         cryptography.RSAMode
+		expectLength int
+		expectPrefix []byte
+		expectError  error
+	}{
+		"auto with short message": {
+			data:         short,
+			mode:         cryptography.RSAModeAuto,
+			expectLength: testPrivateKey.Size(),
+		},
+		"auto with long message": {
+			data:         long,
+			mode:         cryptography.RSAModeAuto,
+			expectPrefix: envelopeMagic,
+		},
+		"raw with short message": {
+			data:         short,
+			mode:         cryptography.RSAModeRaw,
+			expectLength: testPrivateKey.Size(),
+		},
+		"raw with long message": {
+			data:        long,
+			mode:        cryptography.RSAModeRaw,
+			expectError: ErrMessageTooLong,
+		},
+		"hybrid with short message": {
+			data:         short,
+			mode:         cryptography.RSAModeHybrid,
+			expectPrefix: envelopeMagic,
+		},
+		"chunked with long message": {
+			data:         long,
+			mode:         cryptography.RSAModeChunked,
+			expectLength: chunksHeaderLength + 8*testPrivateKey.Size(),
+		},
+		"chunked with empty message": {
+			data:         []byte{},
+			mode:         cryptography.RSAModeChunked,
+			expectLength: chunksHeaderLength,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			encrypted, err := EncryptRSA(c.data, testPrivateKey.PublicKey, cryptography.WithRSAMode(c.mode))
+			if c.expectError != nil {
+				assert.Equal(t, c.expectError, err)
+				return
+			}
+			assert.NoError(t, err)
+			if c.expectLength != 0 {
+				assert.Len(t, encrypted, c.expectLength)
+			}
+			if c.expectPrefix != nil {
+				assert.True(t, bytes.HasPrefix(encrypted, c.expectPrefix))
+			}
+
+			// The mode is detected
+			decrypted, err := DecryptRSA(encrypted, testPrivateKey)
+			assert.NoError(t, err)
+			assert.Equal(t, c.data, decrypted)
+			decrypted, err = DecryptRSA(encrypted, testPrivateKey, cryptography.WithRSAMode(c.mode))
+			assert.NoError(t, err)
+			assert.Equal(t, c.data, decrypted)
+		})
+	}
+
+	_, err := EncryptRSA(short, testPrivateKey.PublicKey, cryptography.WithRSAMode(9))
+	assert.EqualError(t, err, "Fail to read cryptography options: Unsupported RSA mode 9")
+}
@@ -0,0 +1,176 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+// Package shamir splits a secret, such as a passphrase or a key, into
+// shares so that any threshold number of them recovers the secret and
+// fewer reveal nothing about it.
+package shamir
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// Greatest number of shares of a secret
+const MaxShares = 255
+
+// ErrNotEnoughShares is returned when fewer shares than the threshold
+// are combined
+var ErrNotEnoughShares = errors.New("Not enough shares to recover the secret")
+
+// ErrShareMismatch is returned when shares of different splits are combined
+var ErrShareMismatch = errors.New("Shares are not of the same secret")
+
+// Split a secret into n shares, any threshold of which recover it.
+// Every byte of the secret is the constant term of a random polynomial
+// of degree threshold-1 over GF(256), and share i holds the values of
+// the polynomials at i. Returns the shares and an error if any.
+func Split(secret []byte, n int, threshold int) ([]*Share, error) {
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("Secret is empty")
+	}
+	if threshold < 2 || threshold > n || n > MaxShares {
+		return nil, fmt.Errorf("Invalid threshold %d of %d shares", threshold, n)
+	}
+
+	id := make([]byte, ShareIDLength)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	shares := make([]*Share, n)
+	for i := range shares {
+		shares[i] = &Share{
+			ID:        id,
+			Threshold: threshold,
+			Index:     i + 1,
+			Value:     make([]byte, len(secret)),
+		}
+	}
+
+	coefficients := make([]byte, threshold)
+	defer _zero(coefficients)
+	for i, b := range secret {
+		coefficients[0] = b
+		if _, err := rand.Read(coefficients[1:]); err != nil {
+			return nil, err
+		}
+		for _, share := range shares {
+			share.Value[i] = _evaluate(coefficients, byte(share.Index))
+		}
+	}
+
+	return shares, nil
+}
+
+// Recover a secret from at least threshold shares of the same split.
+// Shares that are not of the split cannot always be detected, the
+// secret is then wrong. Returns the secret and an error if any.
+func Combine(shares []*Share) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, ErrNotEnoughShares
+	}
+	first := shares[0]
+	if first.Threshold < 2 || first.Threshold > MaxShares {
+		return nil, fmt.Errorf("Invalid threshold %d", first.Threshold)
+	}
+	if len(shares) < first.Threshold {
+		return nil, ErrNotEnoughShares
+	}
+
+	seen := make(map[int]bool, len(shares))
+	for _, share := range shares {
+		if string(share.ID) != string(first.ID) || share.Threshold != first.Threshold || len(share.Value) != len(first.Value) {
+			return nil, ErrShareMismatch
+		}
+		if share.Index < 1 || share.Index > MaxShares {
+			return nil, fmt.Errorf("Invalid share index %d", share.Index)
+		}
+		if seen[share.Index] {
+			return nil, fmt.Errorf("Duplicate share %d", share.Index)
+		}
+		seen[share.Index] = true
+	}
+
+	// Lagrange interpolation at 0 with the first threshold shares
+	shares = shares[:first.Threshold]
+	secret := make([]byte, len(first.Value))
+	for i, share := range shares {
+		xi := byte(share.Index)
+		// The basis polynomial of the share at 0 is the product of
+		// xj / (xj - xi) over the other shares; subtraction is XOR
+		basis := byte(1)
+		for j, other := range shares {
+			if i != j {
+				xj := byte(other.Index)
+				basis = _mul(basis, _mul(xj, _inverse(xj^xi)))
+			}
+		}
+		for k, y := range share.Value {
+			secret[k] ^= _mul(y, basis)
+		}
+	}
+
+	return secret, nil
+}
+
+// Evaluate a polynomial at x with Horner's method
+func _evaluate(coefficients []byte, x byte) byte {
+	result := byte(0)
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		result = _mul(result, x) ^ coefficients[i]
+	}
+
+	return result
+}
+
+// Multiply in GF(256) with the AES polynomial x^8 + x^4 + x^3 + x + 1.
+// There are no branches or table lookups on the values, so the time
+// does not depend on the secret.
+func _mul(a byte, b byte) byte {
+	result := byte(0)
+	for i := 0; i < 8; i++ {
+		result ^= a & -(b & 1)
+		b >>= 1
+		a = (a << 1) ^ (0x1b & -(a >> 7))
+	}
+
+	return result
+}
+
+// Get the multiplicative inverse in GF(256), which is a^254
+func _inverse(a byte) byte {
+	result := byte(1)
+	for i := 0; i < 7; i++ {
+		a = _mul(a, a)
+		result = _mul(result, a)
+	}
+
+	return result
+}
+
+// Clear a buffer that held secret data
+func _zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,190 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package shamir
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tchiunam/axolgo-lib/cryptography"
+)
+
+// Get every subset of size k of the shares
+func _subsets(shares []*Share, k int) [][]*Share {
+	if k == 0 {
+		return [][]*Share{{}}
+	}
+	var subsets [][]*Share
+	for i := 0; i+k <= len(shares); i++ {
+		for _, rest := range _subsets(shares[i+1:], k-1) {
+			subsets = append(subsets, append([]*Share{shares[i]}, rest...))
+		}
+	}
+
+	return subsets
+}
+
+// Get the number of combinations of k of n
+func _binomial(n int, k int) int {
+	result := 1
+	for i := 1; i <= k; i++ {
+		result = result * (n - k + i) / i
+	}
+
+	return result
+}
+
+// TestSplitCombine recovers secrets from every subset of threshold shares
+func TestSplitCombine(t *testing.T) {
+	secret := []byte("The quick brown fox jumps over the lazy dog")
+
+	for _, tc := range []struct{ n, k int }{{2, 2}, {3, 2}, {5, 3}, {6, 4}, {7, 7}} {
+		t.Run(fmt.Sprintf("%d of %d", tc.k, tc.n), func(t *testing.T) {
+			shares, err := Split(secret, tc.n, tc.k)
+			assert.NoError(t, err)
+			assert.Len(t, shares, tc.n)
+			for i, share := range shares {
+				assert.Equal(t, shares[0].ID, share.ID)
+				assert.Equal(t, tc.k, share.Threshold)
+				assert.Equal(t, i+1, share.Index)
+				assert.Len(t, share.Value, len(secret))
+			}
+
+			subsets := _subsets(shares, tc.k)
+			assert.Len(t, subsets, _binomial(tc.n, tc.k))
+			for _, subset := range subsets {
+				combined, err := Combine(subset)
+				assert.NoError(t, err)
+				assert.Equal(t, secret, combined)
+
+				// The order of the shares does not matter
+				reversed := make([]*Share, len(subset))
+				for i, share := range subset {
+					reversed[len(subset)-1-i] = share
+				}
+				combined, err = Combine(reversed)
+				assert.NoError(t, err)
+				assert.Equal(t, secret, combined)
+			}
+
+			// Fewer shares than the threshold are refused
+			for _, subset := range _subsets(shares, tc.k-1) {
+				_, err := Combine(subset)
+				assert.Equal(t, ErrNotEnoughShares, err)
+			}
+
+			// More shares than the threshold work as well
+			combined, err := Combine(shares)
+			assert.NoError(t, err)
+			assert.Equal(t, secret, combined)
+		})
+	}
+}
+
+// TestSplitRandom checks that splits of the same secret differ
+func TestSplitRandom(t *testing.T) {
+	secret := []byte{0, 0, 0, 0}
+	first, err := Split(secret, 3, 2)
+	assert.NoError(t, err)
+	second, err := Split(secret, 3, 2)
+	assert.NoError(t, err)
+
+	assert.NotEqual(t, first[0].ID, second[0].ID)
+	assert.NotEqual(t, first[0].Value, second[0].Value)
+	assert.NotEqual(t, secret, first[0].Value)
+}
+
+// TestSplitCombineInvalid checks invalid splits and shares
+func TestSplitCombineInvalid(t *testing.T) {
+	cases := map[string]struct {
+		secret    []byte
+		n         int
+		threshold int
+	}{
+		"empty secret":      {secret: nil, n: 3, threshold: 2},
+		"threshold of one":  {secret: []byte("secret"), n: 3, threshold: 1},
+		"threshold above n": {secret: []byte("secret"), n: 3, threshold: 4},
+		"too many shares":   {secret: []byte("secret"), n: 256, threshold: 2},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := Split(tc.secret, tc.n, tc.threshold)
+			assert.Error(t, err)
+		})
+	}
+
+	shares, err := Split([]byte("secret"), 3, 2)
+	assert.NoError(t, err)
+	others, err := Split([]byte("secret"), 3, 2)
+	assert.NoError(t, err)
+
+	_, err = Combine(nil)
+	assert.Equal(t, ErrNotEnoughShares, err)
+	_, err = Combine([]*Share{shares[0], others[1]})
+	assert.Equal(t, ErrShareMismatch, err)
+	_, err = Combine([]*Share{shares[0], shares[0]})
+	assert.Error(t, err)
+	_, err = Combine([]*Share{shares[0], {ID: shares[0].ID, Threshold: 2, Index: 2, Value: []byte("short")}})
+	assert.Equal(t, ErrShareMismatch, err)
+}
+
+// TestSplitPassphrase splits the passphrase of encrypted data among operators
+func TestSplitPassphrase(t *testing.T) {
+	passphrase, err := cryptography.GeneratePassphrase(32)
+	assert.NoError(t, err)
+	encrypted, err := cryptography.Encrypt([]byte("hello world"), passphrase,
+		cryptography.WithPBKDF2Params(cryptography.PBKDF2Params{Iterations: 1000}))
+	assert.NoError(t, err)
+
+	shares, err := Split([]byte(passphrase), 5, 3)
+	assert.NoError(t, err)
+	var encoded []string
+	for _, share := range shares {
+		s, err := share.Encode()
+		assert.NoError(t, err)
+		encoded = append(encoded, s)
+	}
+
+	var parsed []*Share
+	for _, s := range []string{encoded[4], encoded[0], encoded[2]} {
+		share, err := ParseShare(s)
+		assert.NoError(t, err)
+		parsed = append(parsed, share)
+	}
+	combined, err := Combine(parsed)
+	assert.NoError(t, err)
+	decrypted, err := cryptography.Decrypt(encrypted, string(combined))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello world", string(decrypted))
+}
+
+// TestGF256 checks the field arithmetic
+func TestGF256(t *testing.T) {
+	assert.Equal(t, byte(0xc1), _mul(0x57, 0x83))
+	assert.Equal(t, byte(0), _mul(0, 0x83))
+	for a := 1; a < 256; a++ {
+		assert.Equal(t, byte(1), _mul(byte(a), _inverse(byte(a))), "inverse of %d", a)
+		assert.Equal(t, byte(a), _mul(byte(a), 1))
+	}
+}
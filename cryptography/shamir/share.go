@@ -0,0 +1,117 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package shamir
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/tchiunam/axolgo-lib/util"
+)
+
+// Length of the identifier of a split
+const ShareIDLength = 8
+
+// Version of the share encoding
+const shareVersion = 1
+
+// Length of the checksum of an encoded share
+const shareChecksumLength = 4
+
+// ErrInvalidShare is returned when an encoded share is malformed
+var ErrInvalidShare = errors.New("Invalid share")
+
+// ErrShareChecksum is returned when an encoded share is mistyped or corrupted
+var ErrShareChecksum = errors.New("Share checksum does not match")
+
+// Share is one share of a secret
+type Share struct {
+	// Random identifier of the split, the same for all its shares
+	ID []byte
+	// Number of shares needed to recover the secret
+	Threshold int
+	// Index of the share, from 1
+	Index int
+	Value []byte
+}
+
+// Get the identifier of the split in hex, which tells apart shares of
+// different secrets
+func (s *Share) IDString() string {
+	return hex.EncodeToString(s.ID)
+}
+
+// Encode the share as base58 text to hand to an operator. The encoding
+// has the version, the identifier, the threshold, the index and the
+// value, followed by the first 4 bytes of their SHA-256 hash as checksum.
+func (s *Share) Encode() (string, error) {
+	if len(s.ID) != ShareIDLength || s.Threshold < 2 || s.Threshold > MaxShares || s.Index < 1 || s.Index > MaxShares {
+		return "", ErrInvalidShare
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteByte(shareVersion)
+	buffer.Write(s.ID)
+	buffer.WriteByte(byte(s.Threshold))
+	buffer.WriteByte(byte(s.Index))
+	buffer.Write(s.Value)
+	checksum := sha256.Sum256(buffer.Bytes())
+	buffer.Write(checksum[:shareChecksumLength])
+
+	return string(util.Base58Encode(buffer.Bytes())), nil
+}
+
+// Decode a share encoded by Encode. Returns ErrShareChecksum if the
+// share is mistyped.
+func ParseShare(encoded string) (*Share, error) {
+	data, err := util.Base58Decode([]byte(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidShare, err)
+	}
+	if len(data) < 1+ShareIDLength+2+1+shareChecksumLength {
+		return nil, ErrInvalidShare
+	}
+	payload, checksum := data[:len(data)-shareChecksumLength], data[len(data)-shareChecksumLength:]
+	expected := sha256.Sum256(payload)
+	if !bytes.Equal(checksum, expected[:shareChecksumLength]) {
+		return nil, ErrShareChecksum
+	}
+	if payload[0] != shareVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidShare, payload[0])
+	}
+
+	share := Share{
+		ID:        payload[1 : 1+ShareIDLength],
+		Threshold: int(payload[1+ShareIDLength]),
+		Index:     int(payload[2+ShareIDLength]),
+		Value:     payload[3+ShareIDLength:],
+	}
+	if share.Threshold < 2 || share.Index < 1 {
+		return nil, ErrInvalidShare
+	}
+
+	return &share, nil
+}
@@ -0,0 +1,80 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package shamir
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tchiunam/axolgo-lib/util"
+)
+
+// TestEncodeParseShare encodes and parses shares
+func TestEncodeParseShare(t *testing.T) {
+	shares, err := Split([]byte("iamthebest"), 3, 2)
+	assert.NoError(t, err)
+
+	for _, share := range shares {
+		encoded, err := share.Encode()
+		assert.NoError(t, err)
+		parsed, err := ParseShare(encoded)
+		assert.NoError(t, err)
+		assert.Equal(t, share, parsed)
+		assert.Len(t, parsed.IDString(), 2*ShareIDLength)
+	}
+}
+
+// TestParseShareInvalid checks mistyped and malformed shares
+func TestParseShareInvalid(t *testing.T) {
+	shares, err := Split([]byte("iamthebest"), 3, 2)
+	assert.NoError(t, err)
+	encoded, err := shares[0].Encode()
+	assert.NoError(t, err)
+
+	// Change one character, keeping it in the base58 alphabet
+	mistyped := []byte(encoded)
+	if mistyped[10] == 'a' {
+		mistyped[10] = 'b'
+	} else {
+		mistyped[10] = 'a'
+	}
+	_, err = ParseShare(string(mistyped))
+	assert.Equal(t, ErrShareChecksum, err)
+
+	cases := map[string]string{
+		"not base58": "0OIl",
+		"too short":  string(util.Base58Encode([]byte{1, 2, 3})),
+		"empty":      "",
+	}
+	for name, s := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseShare(s)
+			assert.ErrorIs(t, err, ErrInvalidShare)
+		})
+	}
+
+	_, err = (&Share{ID: []byte("short"), Threshold: 2, Index: 1, Value: []byte("a")}).Encode()
+	assert.Equal(t, ErrInvalidShare, err)
+	_, err = (&Share{ID: shares[0].ID, Threshold: 2, Index: 0, Value: []byte("a")}).Encode()
+	assert.Equal(t, ErrInvalidShare, err)
+}
@@ -0,0 +1,110 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cryptography
+
+import (
+	"crypto"
+	"fmt"
+)
+
+// SignatureScheme is the padding scheme of RSA signatures
+type SignatureScheme byte
+
+// Supported signature schemes. The zero value selects PSS.
+const (
+	SignatureSchemePSS      SignatureScheme = 1
+	SignatureSchemePKCS1v15 SignatureScheme = 2
+)
+
+// Salt lengths of PSS signatures besides an explicit number of bytes
+const (
+	// The salt is as long as possible when signing and detected when verifying
+	PSSSaltLengthAuto = 0
+	// The salt is as long as the hash
+	PSSSaltLengthEqualsHash = -1
+)
+
+// Names of the signature schemes
+var signatureSchemeNames = map[SignatureScheme]string{
+	SignatureSchemePSS:      "pss",
+	SignatureSchemePKCS1v15: "pkcs1v15",
+}
+
+// Get the name of the signature scheme
+func (s SignatureScheme) String() string {
+	if name, ok := signatureSchemeNames[s]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("SignatureScheme(%d)", byte(s))
+}
+
+// ParseSignatureScheme returns the signature scheme with the given name
+func ParseSignatureScheme(name string) (SignatureScheme, error) {
+	for scheme, schemeName := range signatureSchemeNames {
+		if schemeName == name {
+			return scheme, nil
+		}
+	}
+
+	return 0, fmt.Errorf("Unsupported signature scheme %q", name)
+}
+
+// WithSignatureScheme is a helper function to construct functional options
+// that sets the padding scheme of RSA signatures.
+func WithSignatureScheme(scheme SignatureScheme) CryptographyOptionsFunc {
+	return func(o *CryptographyOptions) error {
+		if _, ok := signatureSchemeNames[scheme]; !ok {
+			return fmt.Errorf("Unsupported signature scheme %d", scheme)
+		}
+		o.SignatureScheme = scheme
+		return nil
+	}
+}
+
+// WithHash is a helper function to construct functional options
+// that sets the hash algorithm of signatures, RSA-OAEP, HMAC and
+// checksums, see ParseHash. A new hash is created for every call, so
+// the options can be shared.
+func WithHash(h crypto.Hash) CryptographyOptionsFunc {
+	return func(o *CryptographyOptions) error {
+		if !h.Available() {
+			return fmt.Errorf("Unavailable hash function %d", h)
+		}
+		o.Hash = h
+		return nil
+	}
+}
+
+// WithPSSSaltLength is a helper function to construct functional options
+// that sets the salt length of PSS signatures in bytes, or one of
+// PSSSaltLengthAuto and PSSSaltLengthEqualsHash.
+func WithPSSSaltLength(length int) CryptographyOptionsFunc {
+	return func(o *CryptographyOptions) error {
+		if length < PSSSaltLengthEqualsHash {
+			return fmt.Errorf("Invalid PSS salt length %d", length)
+		}
+		o.PSSSaltLength = length
+		return nil
+	}
+}
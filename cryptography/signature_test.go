@@ -0,0 +1,85 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cryptography
+
+import (
+	"crypto"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseSignatureScheme converts signature schemes from and to names
+func TestParseSignatureScheme(t *testing.T) {
+	for _, scheme := range []SignatureScheme{SignatureSchemePSS, SignatureSchemePKCS1v15} {
+		parsed, err := ParseSignatureScheme(scheme.String())
+		assert.NoError(t, err)
+		assert.Equal(t, scheme, parsed)
+	}
+
+	_, err := ParseSignatureScheme("foo")
+	assert.EqualError(t, err, `Unsupported signature scheme "foo"`)
+	assert.Equal(t, "SignatureScheme(9)", SignatureScheme(9).String())
+}
+
+// TestSignatureOptions checks the validation of the signature options
+func TestSignatureOptions(t *testing.T) {
+	cases := map[string]struct {
+		optFn             CryptographyOptionsFunc
+		expectErrorString string
+	}{
+		"pss": {
+			optFn: WithSignatureScheme(SignatureSchemePSS),
+		},
+		"unsupported scheme": {
+			optFn:             WithSignatureScheme(0),
+			expectErrorString: "Fail to read cryptography options: Unsupported signature scheme 0",
+		},
+		"sha512/256": {
+			optFn: WithHash(crypto.SHA512_256),
+		},
+		"unavailable hash": {
+			optFn:             WithHash(crypto.MD4),
+			expectErrorString: "Fail to read cryptography options: Unavailable hash function 1",
+		},
+		"salt length of hash": {
+			optFn: WithPSSSaltLength(PSSSaltLengthEqualsHash),
+		},
+		"negative salt length": {
+			optFn:             WithPSSSaltLength(-2),
+			expectErrorString: "Fail to read cryptography options: Invalid PSS salt length -2",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			var options CryptographyOptions
+			err := options.Merge(c.optFn)
+			if c.expectErrorString != "" {
+				assert.EqualError(t, err, c.expectErrorString)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
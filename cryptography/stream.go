@@ -0,0 +1,358 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cryptography
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Default size of the plaintext of a chunk
+const DefaultChunkSize = 64 * 1024
+
+// Upper bound of the chunk size accepted when decrypting
+const maxChunkSize = 16 * 1024 * 1024
+
+// The counter and the final flag at the end of every chunk nonce
+const streamNonceSuffixLength = 5
+
+// ErrDataTooShort is returned when data is too short to be encrypted data
+var ErrDataTooShort = errors.New("Encrypted data is too short")
+
+// ErrStreamClosed is returned when writing to a closed encrypt writer
+var ErrStreamClosed = errors.New("Write to closed encryption stream")
+
+// ErrTooManyChunks is returned when a stream has more chunks than the
+// counter of the nonce can hold.
+var ErrTooManyChunks = errors.New("Too many chunks in encryption stream")
+
+// WithChunkSize is a helper function to construct functional options
+// that sets the plaintext size of the chunks of an encryption stream.
+func WithChunkSize(size int) CryptographyOptionsFunc {
+	return func(o *CryptographyOptions) error {
+		if size < 1 || size > maxChunkSize {
+			return fmt.Errorf("Invalid chunk size %d", size)
+		}
+		o.ChunkSize = size
+		return nil
+	}
+}
+
+// The nonce of a chunk is the prefix from the header, the big-endian
+// chunk counter and a flag that is 1 for the final chunk only. The flag
+// prevents a stream from being truncated at a chunk boundary.
+type streamNonce struct {
+	nonce   []byte
+	counter uint32
+}
+
+// Make the nonce of the next chunk
+func (s *streamNonce) next(final bool) ([]byte, error) {
+	if s.counter == ^uint32(0) {
+		return nil, ErrTooManyChunks
+	}
+
+	suffix := s.nonce[len(s.nonce)-streamNonceSuffixLength:]
+	binary.BigEndian.PutUint32(suffix, s.counter)
+	suffix[4] = 0
+	if final {
+		suffix[4] = 1
+	}
+	s.counter++
+
+	return s.nonce, nil
+}
+
+// Make the chunk nonces of an envelope
+func newStreamNonce(header *envelopeHeader, aead cipher.AEAD) (*streamNonce, error) {
+	if len(header.Nonce)+streamNonceSuffixLength != aead.NonceSize() {
+		return nil, fmt.Errorf("%w: invalid nonce length %d", ErrInvalidHeader, len(header.Nonce))
+	}
+	nonce := make([]byte, aead.NonceSize())
+	copy(nonce, header.Nonce)
+
+	return &streamNonce{nonce: nonce}, nil
+}
+
+// An io.WriteCloser that encrypts what is written to it
+type encryptWriter struct {
+	w              io.Writer
+	aead           cipher.AEAD
+	nonce          *streamNonce
+	additionalData []byte
+	buffer         []byte
+	chunk          []byte
+	err            error
+}
+
+// Create a writer that encrypts data with a passphrase and writes it to w.
+// The data is split into chunks that are sealed separately, so that
+// data of any size can be encrypted in constant memory. Close must be
+// called to write the final chunk; it does not close w.
+func NewEncryptWriter(w io.Writer, passphrase string, optFns ...CryptographyOptionsFunc) (io.WriteCloser, error) {
+	return _newEncryptWriter(w, secret{passphrase: passphrase}, optFns...)
+}
+
+// Create a writer that encrypts data with a data key and writes it to w,
+// see NewEncryptWriter.
+func NewEncryptWriterWithKey(w io.Writer, key []byte, optFns ...CryptographyOptionsFunc) (io.WriteCloser, error) {
+	if len(key) != DataKeyLength {
+		return nil, ErrInvalidKeyLength
+	}
+
+	return _newEncryptWriter(w, secret{key: key}, optFns...)
+}
+
+// Create a writer that encrypts data with the secret
+func _newEncryptWriter(w io.Writer, s secret, optFns ...CryptographyOptionsFunc) (io.WriteCloser, error) {
+	options := CryptographyOptions{ChunkSize: DefaultChunkSize}
+	if err := options.Merge(optFns...); err != nil {
+		return nil, err
+	}
+
+	header, aead, err := _newEnvelope(s, &options, streamNonceSuffixLength)
+	if err != nil {
+		return nil, err
+	}
+	header.ChunkSize = uint32(options.ChunkSize)
+	nonce, err := newStreamNonce(header, aead)
+	if err != nil {
+		return nil, err
+	}
+
+	headerBytes := header.marshal()
+	if _, err = w.Write(headerBytes); err != nil {
+		return nil, err
+	}
+
+	return &encryptWriter{
+		w:              w,
+		aead:           aead,
+		nonce:          nonce,
+		additionalData: _additionalData(headerBytes, options.AssociatedData),
+		buffer:         make([]byte, 0, options.ChunkSize),
+		chunk:          make([]byte, 0, options.ChunkSize+aead.Overhead()),
+	}, nil
+}
+
+// Seal the buffered data and write it as a chunk
+func (e *encryptWriter) flush(final bool) error {
+	nonce, err := e.nonce.next(final)
+	if err != nil {
+		return err
+	}
+	e.chunk = e.aead.Seal(e.chunk[:0], nonce, e.buffer, e.additionalData)
+	e.buffer = e.buffer[:0]
+	_, err = e.w.Write(e.chunk)
+
+	return err
+}
+
+// Write data to the stream. A chunk is only written once more data
+// follows it, so that the final chunk is known when the stream is closed.
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+
+	written := 0
+	for len(p) > 0 {
+		if len(e.buffer) == cap(e.buffer) {
+			if e.err = e.flush(false); e.err != nil {
+				return written, e.err
+			}
+		}
+		n := copy(e.buffer[len(e.buffer):cap(e.buffer)], p)
+		e.buffer = e.buffer[:len(e.buffer)+n]
+		p = p[n:]
+		written += n
+	}
+
+	return written, nil
+}
+
+// Write the final chunk. The underlying writer is not closed.
+func (e *encryptWriter) Close() error {
+	if e.err != nil {
+		if e.err == ErrStreamClosed {
+			return nil
+		}
+		return e.err
+	}
+
+	if e.err = e.flush(true); e.err != nil {
+		return e.err
+	}
+	e.err = ErrStreamClosed
+
+	return nil
+}
+
+// An io.Reader that decrypts a stream written by an encrypt writer
+type decryptReader struct {
+	r              *bufio.Reader
+	aead           cipher.AEAD
+	nonce          *streamNonce
+	additionalData []byte
+	chunk          []byte
+	buffer         []byte
+	plaintext      []byte
+	final          bool
+	err            error
+}
+
+// Create a reader that decrypts data read from r with a passphrase.
+// Streams written by NewEncryptWriter are decrypted chunk by chunk.
+// Data written by Encrypt is read in full and decrypted with Decrypt.
+func NewDecryptReader(r io.Reader, passphrase string, optFns ...CryptographyOptionsFunc) (io.Reader, error) {
+	return _openDecryptReader(r, secret{passphrase: passphrase}, func(data []byte) ([]byte, error) {
+		return Decrypt(data, passphrase, optFns...)
+	}, optFns...)
+}
+
+// Create a reader that decrypts data read from r with a data key,
+// see NewDecryptReader.
+func NewDecryptReaderWithKey(r io.Reader, key []byte, optFns ...CryptographyOptionsFunc) (io.Reader, error) {
+	if len(key) != DataKeyLength {
+		return nil, ErrInvalidKeyLength
+	}
+
+	return _openDecryptReader(r, secret{key: key}, func(data []byte) ([]byte, error) {
+		return DecryptWithKey(data, key, optFns...)
+	}, optFns...)
+}
+
+// Create a reader that decrypts a stream with the secret, or decrypts
+// data that is not a stream in full with decrypt.
+func _openDecryptReader(
+	r io.Reader,
+	s secret,
+	decrypt func([]byte) ([]byte, error),
+	optFns ...CryptographyOptionsFunc) (io.Reader, error) {
+	var options CryptographyOptions
+	if err := options.Merge(optFns...); err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(r)
+	peeked, err := br.Peek(maxEnvelopeHeaderLength)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	// The peeked bytes are only valid until the next read
+	header, headerBytes, _, err := unmarshalEnvelope(append([]byte{}, peeked...))
+	if err == nil && header.streamed() {
+		additionalData := _additionalData(headerBytes, options.AssociatedData)
+		if _, err = br.Discard(len(headerBytes)); err != nil {
+			return nil, err
+		}
+		return _newDecryptReader(br, header, additionalData, s)
+	}
+
+	data, err := io.ReadAll(br)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := decrypt(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(plaintext), nil
+}
+
+// Create a reader that decrypts the chunks following the header
+func _newDecryptReader(r *bufio.Reader, header *envelopeHeader, additionalData []byte, s secret) (*decryptReader, error) {
+	aead, err := header.newAEAD(s)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := newStreamNonce(header, aead)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptReader{
+		r:              r,
+		aead:           aead,
+		nonce:          nonce,
+		additionalData: additionalData,
+		chunk:          make([]byte, int(header.ChunkSize)+aead.Overhead()),
+	}, nil
+}
+
+// Read and open the next chunk. A chunk is final if it is shorter than
+// a full chunk or nothing follows it.
+func (d *decryptReader) readChunk() error {
+	n, err := io.ReadFull(d.r, d.chunk)
+	final := false
+	switch err {
+	case nil:
+		if _, err = d.r.Peek(1); err == io.EOF {
+			final = true
+		} else if err != nil {
+			return err
+		}
+	case io.EOF, io.ErrUnexpectedEOF:
+		final = true
+	default:
+		return err
+	}
+
+	nonce, err := d.nonce.next(final)
+	if err != nil {
+		return err
+	}
+	if d.buffer, err = d.aead.Open(d.buffer[:0], nonce, d.chunk[:n], d.additionalData); err != nil {
+		return err
+	}
+	d.plaintext = d.buffer
+	d.final = final
+
+	return nil
+}
+
+// Read decrypted data. Data is only returned once its chunk has been
+// authenticated, and a stream without its final chunk is an error.
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.plaintext) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		if d.final {
+			return 0, io.EOF
+		}
+		d.err = d.readChunk()
+	}
+
+	n := copy(p, d.plaintext)
+	d.plaintext = d.plaintext[n:]
+
+	return n, nil
+}
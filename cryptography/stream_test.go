@@ -0,0 +1,251 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cryptography
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Small chunks so that the tests cover many chunks
+const testChunkSize = 16
+
+// Encrypt data as a stream with cheap parameters
+func _encryptTestStream(t *testing.T, data []byte) []byte {
+	var buffer bytes.Buffer
+
+	w, err := NewEncryptWriter(&buffer, "iamthebest", WithChunkSize(testChunkSize), WithPBKDF2Params(testPBKDF2Params))
+	assert.NoError(t, err)
+	// Write in uneven pieces to exercise the chunk buffering
+	for len(data) > 0 {
+		n := 7
+		if n > len(data) {
+			n = len(data)
+		}
+		_, err = w.Write(data[:n])
+		assert.NoError(t, err)
+		data = data[n:]
+	}
+	assert.NoError(t, w.Close())
+
+	return buffer.Bytes()
+}
+
+// TestEncryptDecryptStream encrypts and decrypts streams of various lengths
+func TestEncryptDecryptStream(t *testing.T) {
+	cases := map[string]int{
+		"empty":               0,
+		"one byte":            1,
+		"one byte short":      testChunkSize - 1,
+		"one chunk":           testChunkSize,
+		"one byte over":       testChunkSize + 1,
+		"three chunks":        3 * testChunkSize,
+		"many uneven chunks":  10*testChunkSize + 5,
+		"default chunk sized": DefaultChunkSize,
+	}
+
+	for name, length := range cases {
+		t.Run(name, func(t *testing.T) {
+			data := make([]byte, length)
+			_, err := rand.Read(data)
+			assert.NoError(t, err)
+
+			encrypted := _encryptTestStream(t, data)
+			assert.True(t, IsEncrypted(encrypted))
+
+			r, err := NewDecryptReader(bytes.NewReader(encrypted), "iamthebest")
+			assert.NoError(t, err)
+			decrypted, err := io.ReadAll(iotest.OneByteReader(r))
+			assert.NoError(t, err)
+			assert.True(t, bytes.Equal(data, decrypted))
+
+			// Decrypt reads streams too
+			decrypted, err = Decrypt(encrypted, "iamthebest")
+			assert.NoError(t, err)
+			assert.True(t, bytes.Equal(data, decrypted))
+		})
+	}
+}
+
+// TestDecryptStreamTampered checks that modified streams are rejected
+func TestDecryptStreamTampered(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 10)
+	encrypted := _encryptTestStream(t, data)
+	header, additionalData, _, err := unmarshalEnvelope(encrypted)
+	assert.NoError(t, err)
+	chunkLength := int(header.ChunkSize) + 16
+	body := encrypted[len(additionalData):]
+
+	withBody := func(body []byte) []byte {
+		return append(append([]byte{}, additionalData...), body...)
+	}
+	swapped := withBody(body)
+	copy(swapped[len(additionalData):], body[chunkLength:2*chunkLength])
+	copy(swapped[len(additionalData)+chunkLength:], body[:chunkLength])
+	flipped := withBody(body)
+	flipped[len(flipped)-1] ^= 1
+
+	cases := map[string][]byte{
+		"truncated at chunk boundary": withBody(body[:2*chunkLength]),
+		"truncated in chunk":          withBody(body[:2*chunkLength+3]),
+		"final chunk removed":         withBody(body[:len(body)-len(body)%chunkLength]),
+		"chunks swapped":              swapped,
+		"bit flipped":                 flipped,
+		"chunk appended":              withBody(append(append([]byte{}, body...), body[:chunkLength]...)),
+	}
+
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			r, err := NewDecryptReader(bytes.NewReader(data), "iamthebest")
+			assert.NoError(t, err)
+			_, err = io.ReadAll(r)
+			assert.Error(t, err)
+		})
+	}
+
+	r, err := NewDecryptReader(bytes.NewReader(encrypted), "notthebest")
+	assert.NoError(t, err)
+	_, err = io.ReadAll(r)
+	assert.Error(t, err)
+}
+
+// TestDecryptReaderNonStream reads data that is not a stream
+func TestDecryptReaderNonStream(t *testing.T) {
+	data := []byte("The quick brown fox jumps over the lazy dog")
+
+	encrypted, err := Encrypt(data, "iamthebest", WithPBKDF2Params(testPBKDF2Params))
+	assert.NoError(t, err)
+	legacy, err := Encrypt(data, "iamthebest", WithHashFunc(CreateHash))
+	assert.NoError(t, err)
+
+	_, err = NewDecryptReader(bytes.NewReader(nil), "iamthebest")
+	assert.Equal(t, ErrDataTooShort, err)
+
+	for name, input := range map[string][]byte{"envelope": encrypted, "legacy": legacy} {
+		t.Run(name, func(t *testing.T) {
+			r, err := NewDecryptReader(bytes.NewReader(input), "iamthebest")
+			assert.NoError(t, err)
+			decrypted, err := io.ReadAll(r)
+			assert.NoError(t, err)
+			assert.Equal(t, data, decrypted)
+		})
+	}
+}
+
+// TestEncryptWriterClosed checks that a closed writer cannot be written to
+func TestEncryptWriterClosed(t *testing.T) {
+	var buffer bytes.Buffer
+
+	w, err := NewEncryptWriter(&buffer, "iamthebest", WithPBKDF2Params(testPBKDF2Params))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	assert.NoError(t, w.Close())
+	_, err = w.Write([]byte("data"))
+	assert.Equal(t, ErrStreamClosed, err)
+
+	_, err = NewEncryptWriter(&buffer, "iamthebest", WithChunkSize(0))
+	assert.Error(t, err)
+	_, err = NewEncryptWriter(&buffer, "iamthebest", WithChunkSize(maxChunkSize+1))
+	assert.Error(t, err)
+}
+
+// TestEncryptFileStream checks that EncryptFileStream writes a stream
+// and that DecryptFileStream does not leave output behind on failure.
+func TestEncryptFileStream(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join("testdata", "story.txt")
+	encFilename := filepath.Join(dir, "story.txt.enc")
+	decFilename := filepath.Join(dir, "story.txt.dec")
+
+	err := EncryptFileStream(filename, "iamthebest", WithOutputFilename(encFilename), WithPBKDF2Params(testPBKDF2Params), WithChunkSize(testChunkSize))
+	assert.NoError(t, err)
+	encrypted, err := os.ReadFile(encFilename)
+	assert.NoError(t, err)
+	header, _, _, err := unmarshalEnvelope(encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, byte(envelopeVersion4), header.Version)
+	assert.Equal(t, uint32(testChunkSize), header.ChunkSize)
+
+	err = DecryptFileStream(encFilename, "iamthebest", WithOutputFilename(decFilename))
+	assert.NoError(t, err)
+	expect, _ := os.ReadFile(filename)
+	actual, _ := os.ReadFile(decFilename)
+	assert.Equal(t, expect, actual)
+
+	err = DecryptFileStream(encFilename, "notthebest", WithOutputFilename(decFilename))
+	assert.Error(t, err)
+	_, err = os.Stat(decFilename)
+	assert.True(t, os.IsNotExist(err))
+
+	err = EncryptFileStream(filename, "iamthebest", WithOutputFilename(filename))
+	assert.EqualError(t, err, "Output file testdata/story.txt is the input file")
+	err = DecryptFileStream(encFilename, "iamthebest")
+	assert.EqualError(t, err, "Streaming a file requires an output filename")
+}
+
+// TestEncryptDecryptStreamWithKey encrypts and decrypts a stream with a
+// data key
+func TestEncryptDecryptStreamWithKey(t *testing.T) {
+	data := make([]byte, 100)
+	_, err := rand.Read(data)
+	assert.NoError(t, err)
+	key, err := GenerateDataKey()
+	assert.NoError(t, err)
+
+	var buffer bytes.Buffer
+	w, err := NewEncryptWriterWithKey(&buffer, key, WithChunkSize(testChunkSize))
+	assert.NoError(t, err)
+	_, err = w.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	encrypted := buffer.Bytes()
+
+	r, err := NewDecryptReaderWithKey(bytes.NewReader(encrypted), key)
+	assert.NoError(t, err)
+	decrypted, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, data, decrypted)
+
+	// Data that is not a stream is decrypted in full
+	sealed, err := EncryptWithKey(data, key)
+	assert.NoError(t, err)
+	r, err = NewDecryptReaderWithKey(bytes.NewReader(sealed), key)
+	assert.NoError(t, err)
+	decrypted, err = io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, data, decrypted)
+
+	_, err = NewDecryptReader(bytes.NewReader(encrypted), "iamthebest")
+	assert.Equal(t, ErrKeyRequired, err)
+	_, err = NewEncryptWriterWithKey(&buffer, key[:16])
+	assert.Equal(t, ErrInvalidKeyLength, err)
+	_, err = NewDecryptReaderWithKey(bytes.NewReader(encrypted), key[:16])
+	assert.Equal(t, ErrInvalidKeyLength, err)
+}
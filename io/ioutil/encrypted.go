@@ -0,0 +1,106 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package ioutil
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/tchiunam/axolgo-lib/cryptography"
+)
+
+// Prefix and suffix of encrypted config values
+const (
+	encryptedValuePrefix = "ENC["
+	encryptedValueSuffix = "]"
+)
+
+// Length of the nonce and the tag, which every encrypted value holds
+const minEncryptedValueLength = 12 + 16
+
+// Encrypt a config value with a passphrase. The value is the base64
+// encoded output of cryptography.Encrypt in the form of ENC[...], which
+// ReadConfigFile decrypts with WithCFOPassphrase.
+func EncryptConfigValue(value string, passphrase string, optFns ...cryptography.CryptographyOptionsFunc) (string, error) {
+	encrypted, err := cryptography.Encrypt([]byte(value), passphrase, optFns...)
+	if err != nil {
+		return "", err
+	}
+
+	return encryptedValuePrefix + base64.StdEncoding.EncodeToString(encrypted) + encryptedValueSuffix, nil
+}
+
+// Encrypt a config value with a data key, see EncryptConfigValue.
+// ReadConfigFile decrypts it with WithCFOKey.
+func EncryptConfigValueWithKey(value string, key []byte, optFns ...cryptography.CryptographyOptionsFunc) (string, error) {
+	encrypted, err := cryptography.EncryptWithKey([]byte(value), key, optFns...)
+	if err != nil {
+		return "", err
+	}
+
+	return encryptedValuePrefix + base64.StdEncoding.EncodeToString(encrypted) + encryptedValueSuffix, nil
+}
+
+// Tell whether a config value is encrypted
+func IsEncryptedConfigValue(value string) bool {
+	return strings.HasPrefix(value, encryptedValuePrefix) && strings.HasSuffix(value, encryptedValueSuffix)
+}
+
+// Decrypt an encrypted config value with the secret of the options
+func _decryptConfigValue(value string, options *ConfigFileOptions) (string, error) {
+	encoded := strings.TrimSuffix(strings.TrimPrefix(value, encryptedValuePrefix), encryptedValueSuffix)
+	encrypted, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("Fail to decode encrypted config value: %v", err)
+	}
+	if len(encrypted) < minEncryptedValueLength {
+		return "", fmt.Errorf("Fail to decrypt config value: %w", cryptography.ErrDataTooShort)
+	}
+
+	var decrypted []byte
+	if options.Key != nil {
+		decrypted, err = cryptography.DecryptWithKey(encrypted, options.Key, options.CryptographyOptions...)
+	} else {
+		decrypted, err = cryptography.Decrypt(encrypted, options.Passphrase, options.CryptographyOptions...)
+	}
+	if err != nil {
+		return "", fmt.Errorf("Fail to decrypt config value: %v", err)
+	}
+
+	return string(decrypted), nil
+}
+
+// Get the decode hook that decrypts encrypted string values
+func _decryptConfigValueHook(options *ConfigFileOptions) mapstructure.DecodeHookFuncType {
+	return func(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+		value, ok := data.(string)
+		if !ok || !IsEncryptedConfigValue(value) {
+			return data, nil
+		}
+
+		return _decryptConfigValue(value, options)
+	}
+}
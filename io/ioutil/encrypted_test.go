@@ -0,0 +1,166 @@
+/*
+Copyright © 2022 tchiunam
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package ioutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tchiunam/axolgo-lib/cryptography"
+)
+
+// Cheap key derivation for tests
+var testCryptographyOptions = []cryptography.CryptographyOptionsFunc{
+	cryptography.WithPBKDF2Params(cryptography.PBKDF2Params{Iterations: 1000}),
+}
+
+// Structure of database settings
+type TestEncryptedDatabase struct {
+	User     string        `mapstructure:"user"`
+	Password string        `mapstructure:"password"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+}
+
+// Structure of a config with secrets
+type TestEncryptedConfig struct {
+	Database TestEncryptedDatabase `mapstructure:"database"`
+	Tokens   []string              `mapstructure:"tokens"`
+}
+
+// Write a YAML config with encrypted values
+func _writeEncryptedConfig(t *testing.T, password string, token string) string {
+	filename := filepath.Join(t.TempDir(), "config.yaml")
+	content := fmt.Sprintf("database:\n  user: axolotl\n  password: %s\n  timeout: 5s\ntokens:\n  - plain\n  - %s\n", password, token)
+	assert.NoError(t, os.WriteFile(filename, []byte(content), 0600))
+
+	return filename
+}
+
+// TestReadConfigFileEncrypted reads a config with values encrypted
+// with a passphrase and with a key
+func TestReadConfigFileEncrypted(t *testing.T) {
+	key, err := cryptography.GenerateDataKey()
+	assert.NoError(t, err)
+
+	cases := map[string]struct {
+		encrypt func(value string) (string, error)
+		option  ConfigFileOptionsFunc
+	}{
+		"passphrase": {
+			encrypt: func(value string) (string, error) {
+				return EncryptConfigValue(value, "iamthebest", testCryptographyOptions...)
+			},
+			option: WithCFOPassphrase("iamthebest"),
+		},
+		"key": {
+			encrypt: func(value string) (string, error) {
+				return EncryptConfigValueWithKey(value, key)
+			},
+			option: WithCFOKey(key),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			password, err := tc.encrypt("s3cret")
+			assert.NoError(t, err)
+			assert.True(t, IsEncryptedConfigValue(password))
+			token, err := tc.encrypt("t0ken")
+			assert.NoError(t, err)
+			filename := _writeEncryptedConfig(t, password, token)
+
+			var config TestEncryptedConfig
+			_, err = ReadYamlFile(filename, WithCFOClass(&config), tc.option)
+			assert.NoError(t, err)
+			assert.Equal(t, TestEncryptedConfig{
+				Database: TestEncryptedDatabase{User: "axolotl", Password: "s3cret", Timeout: 5 * time.Second},
+				Tokens:   []string{"plain", "t0ken"},
+			}, config)
+
+			// Without a secret the values are kept as they are
+			config = TestEncryptedConfig{}
+			_, err = ReadYamlFile(filename, WithCFOClass(&config))
+			assert.NoError(t, err)
+			assert.Equal(t, password, config.Database.Password)
+		})
+	}
+}
+
+// TestReadConfigFileEncryptedInvalid checks wrong secrets and
+// malformed values
+func TestReadConfigFileEncryptedInvalid(t *testing.T) {
+	password, err := EncryptConfigValue("s3cret", "iamthebest", testCryptographyOptions...)
+	assert.NoError(t, err)
+	key, err := cryptography.GenerateDataKey()
+	assert.NoError(t, err)
+
+	cases := map[string]struct {
+		password    string
+		option      ConfigFileOptionsFunc
+		expectError string
+	}{
+		"wrong passphrase": {
+			password:    password,
+			option:      WithCFOPassphrase("iamnotthebest"),
+			expectError: "Fail to decrypt config value",
+		},
+		"key instead of passphrase": {
+			password:    password,
+			option:      WithCFOKey(key),
+			expectError: "Fail to decrypt config value",
+		},
+		"invalid base64": {
+			password:    "ENC[not base64!]",
+			option:      WithCFOPassphrase("iamthebest"),
+			expectError: "Fail to decode encrypted config value",
+		},
+		"empty value": {
+			password:    "ENC[]",
+			option:      WithCFOPassphrase("iamthebest"),
+			expectError: "Encrypted data is too short",
+		},
+		"short value": {
+			password:    "ENC[AAAA]",
+			option:      WithCFOKey(key),
+			expectError: "Encrypted data is too short",
+		},
+		"invalid key": {
+			password:    password,
+			option:      WithCFOKey(key[:16]),
+			expectError: "Fail to configure ReadConfigFile options",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			filename := _writeEncryptedConfig(t, tc.password, "plain")
+			var config TestEncryptedConfig
+			_, err := ReadYamlFile(filename, WithCFOClass(&config), tc.option)
+			assert.ErrorContains(t, err, tc.expectError)
+		})
+	}
+}
@@ -25,7 +25,9 @@ package ioutil
 import (
 	"fmt"
 
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
+	"github.com/tchiunam/axolgo-lib/cryptography"
 	"gopkg.in/ini.v1"
 )
 
@@ -38,6 +40,10 @@ func ReadIniFile(filepath string) (*ini.File, error) {
 type ConfigFileOptions struct {
 	Type  string
 	Class interface{}
+	// Secret of encrypted values, see WithCFOPassphrase and WithCFOKey
+	Passphrase          string
+	Key                 []byte
+	CryptographyOptions []cryptography.CryptographyOptionsFunc
 }
 
 // ConfigFileOptionsFunc is a type alias for ConfigFileOptions functional option
@@ -61,12 +67,39 @@ func WithCFOClass(class interface{}) ConfigFileOptionsFunc {
 	}
 }
 
+// WithCFOPassphrase is a helper function to construct functional options
+// that sets the passphrase to decrypt encrypted values on config's
+// ConfigFileOptions. The cryptography options are given to Decrypt.
+func WithCFOPassphrase(passphrase string, optFns ...cryptography.CryptographyOptionsFunc) ConfigFileOptionsFunc {
+	return func(f *ConfigFileOptions) error {
+		f.Passphrase = passphrase
+		f.CryptographyOptions = optFns
+		return nil
+	}
+}
+
+// WithCFOKey is a helper function to construct functional options
+// that sets the data key to decrypt encrypted values on config's
+// ConfigFileOptions. The cryptography options are given to DecryptWithKey.
+func WithCFOKey(key []byte, optFns ...cryptography.CryptographyOptionsFunc) ConfigFileOptionsFunc {
+	return func(f *ConfigFileOptions) error {
+		if len(key) != cryptography.DataKeyLength {
+			return cryptography.ErrInvalidKeyLength
+		}
+		f.Key = key
+		f.CryptographyOptions = optFns
+		return nil
+	}
+}
+
 // Read the file given by filepath. Viper instance is returned
 // if optClass is not provided. Otherwise Unmarshalling will be
 // performed.
 // If optClass is provided, Unmarshalling will be performed and
 // the class will be updated. No object will be returned.
 // optClass is expected to be a pointer to a struct.
+// If a passphrase or a key is provided, string values in the form of
+// ENC[...] are decrypted while unmarshalling, see EncryptConfigValue.
 //
 // For example:
 // 	type Config struct {
@@ -100,6 +133,13 @@ func ReadConfigFile(filepath string, optFns ...func(*ConfigFileOptions) error) (
 		return v, nil
 	} else {
 		// Unmarshal the config into the class
+		if options.Passphrase != "" || options.Key != nil {
+			return nil, v.Unmarshal(&options.Class, viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+				_decryptConfigValueHook(&options),
+				mapstructure.StringToTimeDurationHookFunc(),
+				mapstructure.StringToSliceHookFunc(","),
+			)))
+		}
 		return nil, v.Unmarshal(&options.Class)
 	}
 }
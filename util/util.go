@@ -26,6 +26,8 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"io"
+	"os"
 	"os/user"
 	"path/filepath"
 	"strings"
@@ -137,3 +139,39 @@ func Base58Decode(input []byte) ([]byte, error) {
 
 	return decode, nil
 }
+
+// WriteFileAtomic writes data to a temporary file in the directory of
+// filename and renames it to filename, so that readers see either the
+// old or the new content.
+func WriteFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	return WriteFileAtomicFunc(filename, perm, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// WriteFileAtomicFunc is like WriteFileAtomic, but the content is
+// written by fn. The file is left untouched if fn fails.
+func WriteFileAtomicFunc(filename string, perm os.FileMode, fn func(io.Writer) error) error {
+	file, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tempFilename := file.Name()
+	defer os.Remove(tempFilename)
+
+	if err = fn(file); err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tempFilename, perm)
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tempFilename, filename)
+}
@@ -25,7 +25,10 @@ package util
 import (
 	"errors"
 	"fmt"
+	"io"
+	"os"
 	"os/user"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -341,3 +344,47 @@ func TestBase58Decode(t *testing.T) {
 		})
 	}
 }
+
+// TestWriteFileAtomic calls WriteFileAtomic to create and replace
+// a file, checking the content and permission.
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "axolotl.txt")
+
+	for _, content := range []string{"hello world", "hello axolotl"} {
+		assert.NoError(t, WriteFileAtomic(filename, []byte(content), 0600))
+		data, err := os.ReadFile(filename)
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(data))
+		info, err := os.Stat(filename)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+
+	// No temporary file is left behind
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	assert.Error(t, WriteFileAtomic(filepath.Join(dir, "missing", "axolotl.txt"), []byte("hello world"), 0600))
+}
+
+// TestWriteFileAtomicFunc calls WriteFileAtomicFunc with a failing
+// writer, checking that the existing file is kept.
+func TestWriteFileAtomicFunc(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "axolotl.txt")
+	assert.NoError(t, WriteFileAtomic(filename, []byte("hello world"), 0600))
+
+	err := WriteFileAtomicFunc(filename, 0600, func(w io.Writer) error {
+		w.Write([]byte("hello"))
+		return errors.New("Write failed")
+	})
+	assert.EqualError(t, err, "Write failed")
+	data, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello world", string(data))
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}